@@ -0,0 +1,322 @@
+package ari
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/messagebus"
+	nats "github.com/nats-io/nats.go"
+	"github.com/rotisserie/eris"
+)
+
+// ErrNotListening is returned when pausing or resuming the intake of a client
+// which is not listening
+var ErrNotListening = eris.New("client is not listening")
+
+// RejectAction is what happens to a call refused by admission control
+type RejectAction int
+
+const (
+	// RejectRequeue leaves the call for another member of the queue group.
+	// Once it was requeued MaxRequeue times, the call is refused with
+	// Congestion.  A member which gets back a call it refused already passes
+	// it on without spending MaxRequeue, up to MaxPass times.
+	RejectRequeue RejectAction = iota
+
+	// RejectBusy refuses the call with Busy
+	RejectBusy
+
+	// RejectCongestion refuses the call with Congestion
+	RejectCongestion
+
+	// RejectContinue returns the call to the dialplan
+	RejectContinue
+)
+
+// DefaultMaxRequeue is the default number of times a refused call is put back
+// in the queue group
+const DefaultMaxRequeue = 3
+
+// DefaultMaxPass is the default number of times a requeued call is passed on
+// by members which refused it already
+const DefaultMaxPass = 10
+
+// AdmissionOptions configures the admission control of incoming calls.  A zero
+// limit is no limit.
+type AdmissionOptions struct {
+	// MaxCalls is the maximum number of concurrent calls of the worker
+	MaxCalls int
+
+	// MaxCallsPerNode is the maximum number of concurrent calls the worker
+	// handles for each Asterisk node
+	MaxCallsPerNode int
+
+	// MaxCallsPerTenant is the maximum number of concurrent calls the worker
+	// handles for each tenant, as returned by Tenant
+	MaxCallsPerTenant int
+
+	// Tenant returns the tenant of a call.  Calls without a tenant are not
+	// subject to MaxCallsPerTenant.
+	Tenant func(e *arievent.StasisEvent) string
+
+	// Reject is what happens to the refused calls
+	Reject RejectAction
+
+	// Context, Extension and Priority locate where RejectContinue returns the
+	// call in the dialplan.  Left empty, the call continues at the next
+	// priority.
+	Context   string
+	Extension string
+	Priority  int
+
+	// MaxRequeue bounds how many times RejectRequeue puts a call back in the
+	// queue group.  Defaults to DefaultMaxRequeue.
+	MaxRequeue int
+
+	// MaxPass bounds how many times a requeued call landing on members which
+	// refused it already is passed on.  Defaults to DefaultMaxPass.
+	MaxPass int
+}
+
+// TenantFromVar returns a Tenant function reading the tenant from the given
+// channel variable
+func TenantFromVar(name string) func(e *arievent.StasisEvent) string {
+	return func(e *arievent.StasisEvent) string {
+		return e.Channel.ChannelVars[name]
+	}
+}
+
+// admission counts the calls in progress and decides whether to accept new ones
+type admission struct {
+	opts *AdmissionOptions
+
+	paused  bool
+	calls   int
+	nodes   map[string]int
+	tenants map[string]int
+
+	// idle is closed and replaced whenever the last call ends
+	idle chan struct{}
+
+	mu sync.Mutex
+}
+
+func newAdmission(opts *AdmissionOptions) *admission {
+	if opts == nil {
+		opts = &AdmissionOptions{}
+	}
+
+	return &admission{
+		opts:    opts,
+		nodes:   make(map[string]int),
+		tenants: make(map[string]int),
+		idle:    make(chan struct{}),
+	}
+}
+
+// admit accepts the call if the worker is not paused and no limit is reached.
+// The returned function must be called once the call ends.
+func (ad *admission) admit(e *arievent.StasisEvent) (func(), bool) {
+	tenant := ""
+	if ad.opts.Tenant != nil {
+		tenant = ad.opts.Tenant(e)
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	if ad.paused {
+		return nil, false
+	}
+
+	if ad.opts.MaxCalls > 0 && ad.calls >= ad.opts.MaxCalls {
+		return nil, false
+	}
+
+	if ad.opts.MaxCallsPerNode > 0 && ad.nodes[e.Node] >= ad.opts.MaxCallsPerNode {
+		return nil, false
+	}
+
+	if tenant != "" && ad.opts.MaxCallsPerTenant > 0 && ad.tenants[tenant] >= ad.opts.MaxCallsPerTenant {
+		return nil, false
+	}
+
+	ad.calls++
+	ad.nodes[e.Node]++
+	if tenant != "" {
+		ad.tenants[tenant]++
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			ad.release(e.Node, tenant)
+		})
+	}, true
+}
+
+func (ad *admission) release(node, tenant string) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.calls--
+
+	if ad.nodes[node]--; ad.nodes[node] <= 0 {
+		delete(ad.nodes, node)
+	}
+
+	if tenant != "" {
+		if ad.tenants[tenant]--; ad.tenants[tenant] <= 0 {
+			delete(ad.tenants, tenant)
+		}
+	}
+
+	if ad.calls == 0 {
+		close(ad.idle)
+		ad.idle = make(chan struct{})
+	}
+}
+
+// reject applies the configured reject action to a refused call
+func (a *ARIClient) reject(o *arievent.StasisEvent, msg *nats.Msg) {
+	opts := a.admission.opts
+
+	action := opts.Reject
+	if action == RejectRequeue {
+		max := opts.MaxRequeue
+		if max <= 0 {
+			max = DefaultMaxRequeue
+		}
+
+		retry := messagebus.RequeueCount(msg) < max
+
+		// The call came back to a member which refused it already: it is
+		// passed on to the others without spending the requeue budget
+		if messagebus.RefusedBy(msg, a.instanceID) {
+			maxPass := opts.MaxPass
+			if maxPass <= 0 {
+				maxPass = DefaultMaxPass
+			}
+
+			retry = messagebus.PassCount(msg) < maxPass
+		}
+
+		if retry {
+			count, err := a.sbus.Requeue(msg, a.subjects.Requeue(o.Application, a.queueGroup()), a.instanceID)
+			if err == nil {
+				logs.TLogger.Debug().Msgf("requeued channel %s (%d)", o.Channel.ID, count)
+				return
+			}
+
+			logs.TLogger.Error().Msgf("failed to requeue channel %s: %s", o.Channel.ID, err)
+		}
+
+		action = RejectCongestion
+	}
+
+	k := key.NewKey(key.ChannelKey, o.Channel.GetID(), key.WithApp(o.Application), key.WithNode(o.Node))
+	ch := &ichannel{c: a}
+
+	var err error
+	switch action {
+	case RejectBusy:
+		err = ch.Busy(k)
+	case RejectCongestion:
+		err = ch.Congestion(k)
+	case RejectContinue:
+		err = ch.Continue(k, opts.Context, opts.Extension, opts.Priority)
+	}
+
+	if err != nil {
+		logs.TLogger.Error().Msgf("failed to reject channel %s: %s", o.Channel.ID, err)
+	}
+}
+
+// ActiveCalls returns the number of calls the client is handling
+func (a *ARIClient) ActiveCalls() int {
+	if a.admission == nil {
+		return 0
+	}
+
+	a.admission.mu.Lock()
+	defer a.admission.mu.Unlock()
+
+	return a.admission.calls
+}
+
+// PauseIntake stops taking new calls from the queue groups of every
+// application; they go to the other members.  The calls in progress are not
+// affected.
+func (a *ARIClient) PauseIntake() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.admission == nil || a.listenCtx == nil {
+		return ErrNotListening
+	}
+
+	a.admission.mu.Lock()
+	a.admission.paused = true
+	a.admission.mu.Unlock()
+
+	var errs []error
+	for _, in := range a.intakes {
+		if err := in.drain(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ResumeIntake takes new calls from the queue groups again
+func (a *ARIClient) ResumeIntake() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.admission == nil || a.listenCtx == nil {
+		return ErrNotListening
+	}
+
+	a.admission.mu.Lock()
+	a.admission.paused = false
+	a.admission.mu.Unlock()
+
+	for _, in := range a.intakes {
+		if err := a.subscribeIntake(in); err != nil {
+			return eris.Wrap(err, "failed to resume intake")
+		}
+	}
+
+	return nil
+}
+
+// Drain pauses the intake and waits until the calls in progress have ended, or
+// the context is done
+func (a *ARIClient) Drain(ctx context.Context) error {
+	if err := a.PauseIntake(); err != nil {
+		return err
+	}
+
+	for {
+		a.admission.mu.Lock()
+		calls := a.admission.calls
+		idle := a.admission.idle
+		a.admission.mu.Unlock()
+
+		if calls == 0 {
+			return nil
+		}
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
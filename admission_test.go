@@ -0,0 +1,209 @@
+package ari
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/messagebus"
+	nats "github.com/nats-io/nats.go"
+)
+
+func incoming(node, tenant string) *arievent.StasisEvent {
+	return &arievent.StasisEvent{
+		Type:        arievent.StasisStart,
+		Application: "app",
+		Node:        node,
+		Channel:     arievent.ChannelData{ID: "c-" + node + "-" + tenant, ChannelVars: map[string]string{"TENANT": tenant}},
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   *AdmissionOptions
+		paused bool
+		calls  []*arievent.StasisEvent
+		call   *arievent.StasisEvent
+		want   bool
+	}{
+		{"no limits", nil, false, []*arievent.StasisEvent{incoming("ast1", "a"), incoming("ast1", "a")}, incoming("ast1", "a"), true},
+		{"paused", nil, true, nil, incoming("ast1", "a"), false},
+		{"under MaxCalls", &AdmissionOptions{MaxCalls: 2}, false, []*arievent.StasisEvent{incoming("ast1", "a")}, incoming("ast2", "b"), true},
+		{"MaxCalls", &AdmissionOptions{MaxCalls: 2}, false, []*arievent.StasisEvent{incoming("ast1", "a"), incoming("ast2", "b")}, incoming("ast3", "c"), false},
+		{"MaxCallsPerNode", &AdmissionOptions{MaxCallsPerNode: 1}, false, []*arievent.StasisEvent{incoming("ast1", "a")}, incoming("ast1", "b"), false},
+		{"MaxCallsPerNode, other node", &AdmissionOptions{MaxCallsPerNode: 1}, false, []*arievent.StasisEvent{incoming("ast1", "a")}, incoming("ast2", "a"), true},
+		{"MaxCallsPerTenant", &AdmissionOptions{MaxCallsPerTenant: 1, Tenant: TenantFromVar("TENANT")}, false, []*arievent.StasisEvent{incoming("ast1", "a")}, incoming("ast2", "a"), false},
+		{"MaxCallsPerTenant, other tenant", &AdmissionOptions{MaxCallsPerTenant: 1, Tenant: TenantFromVar("TENANT")}, false, []*arievent.StasisEvent{incoming("ast1", "a")}, incoming("ast1", "b"), true},
+		{"MaxCallsPerTenant, no tenant", &AdmissionOptions{MaxCallsPerTenant: 1, Tenant: TenantFromVar("TENANT")}, false, []*arievent.StasisEvent{incoming("ast1", "")}, incoming("ast1", ""), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := newAdmission(tt.opts)
+			ad.paused = tt.paused
+
+			for _, e := range tt.calls {
+				if _, ok := ad.admit(e); !ok {
+					t.Fatalf("call on %s refused", e.Node)
+				}
+			}
+
+			if _, ok := ad.admit(tt.call); ok != tt.want {
+				t.Errorf("admitted = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdmitRelease(t *testing.T) {
+	ad := newAdmission(&AdmissionOptions{MaxCalls: 1, MaxCallsPerTenant: 1, Tenant: TenantFromVar("TENANT")})
+
+	release, ok := ad.admit(incoming("ast1", "a"))
+	if !ok {
+		t.Fatal("first call refused")
+	}
+
+	idle := ad.idle
+
+	release()
+	release()
+
+	if ad.calls != 0 || len(ad.nodes) != 0 || len(ad.tenants) != 0 {
+		t.Errorf("counters after release: %d calls, nodes %v, tenants %v", ad.calls, ad.nodes, ad.tenants)
+	}
+
+	select {
+	case <-idle:
+	default:
+		t.Error("idle not signalled once the last call ended")
+	}
+
+	if _, ok := ad.admit(incoming("ast1", "a")); !ok {
+		t.Error("call refused after the release")
+	}
+}
+
+func TestReject(t *testing.T) {
+	requeued := func(count int) *nats.Msg {
+		msg := nats.NewMsg("ari.app.ast1.c1.stasisstart.x")
+		msg.Reply = "reply.to"
+		if count > 0 {
+			msg.Header.Set(messagebus.RequeueHeader, strconv.Itoa(count))
+		}
+
+		return msg
+	}
+
+	// refused returns a message this worker refused already, and passed on
+	// the given number of times
+	refused := func(count, passes int) *nats.Msg {
+		msg := requeued(count)
+		msg.Header.Add(messagebus.RefusedByHeader, "worker1")
+		if passes > 0 {
+			msg.Header.Set(messagebus.PassHeader, strconv.Itoa(passes))
+		}
+
+		return msg
+	}
+
+	tests := []struct {
+		name    string
+		opts    *AdmissionOptions
+		msg     *nats.Msg
+		requeue bool
+		want    []string
+		count   int
+		passes  int
+	}{
+		{"requeue", &AdmissionOptions{Reject: RejectRequeue}, requeued(0), true, nil, 1, 0},
+		{"requeued too often", &AdmissionOptions{Reject: RejectRequeue}, requeued(3), false, []string{"ChannelCongestion"}, 0, 0},
+		{"requeued under MaxRequeue", &AdmissionOptions{Reject: RejectRequeue, MaxRequeue: 5}, requeued(3), true, nil, 4, 0},
+		{"passed on", &AdmissionOptions{Reject: RejectRequeue}, refused(3, 0), true, nil, 3, 1},
+		{"passed on too often", &AdmissionOptions{Reject: RejectRequeue}, refused(1, DefaultMaxPass), false, []string{"ChannelCongestion"}, 0, 0},
+		{"passed on under MaxPass", &AdmissionOptions{Reject: RejectRequeue, MaxPass: 20}, refused(1, DefaultMaxPass), true, nil, 1, DefaultMaxPass + 1},
+		{"busy", &AdmissionOptions{Reject: RejectBusy}, requeued(0), false, []string{"ChannelBusy"}, 0, 0},
+		{"congestion", &AdmissionOptions{Reject: RejectCongestion}, requeued(0), false, []string{"ChannelCongestion"}, 0, 0},
+		{"continue", &AdmissionOptions{Reject: RejectContinue, Context: "fallback"}, requeued(0), false, []string{"ChannelContinue"}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+			a.admission = newAdmission(tt.opts)
+
+			a.reject(incoming("ast1", ""), tt.msg)
+
+			if got := len(bus.requeued) == 1; got != tt.requeue {
+				t.Fatalf("requeued = %v, want %v", got, tt.requeue)
+			}
+
+			if tt.requeue {
+				out := bus.requeued[0]
+				if want := a.subjects.Requeue("app", messagebus.ListenQueue); out.Subject != want {
+					t.Errorf("requeued on %s, want %s", out.Subject, want)
+				}
+				if out.Reply != "reply.to" {
+					t.Errorf("reply = %q, want reply.to", out.Reply)
+				}
+				if !messagebus.RefusedBy(out, "worker1") || len(out.Header.Values(messagebus.RefusedByHeader)) != 1 {
+					t.Errorf("refusing workers %v, want [worker1]", out.Header.Values(messagebus.RefusedByHeader))
+				}
+				if count, passes := messagebus.RequeueCount(out), messagebus.PassCount(out); count != tt.count || passes != tt.passes {
+					t.Errorf("requeued %d times and passed on %d times, want %d and %d", count, passes, tt.count, tt.passes)
+				}
+			}
+
+			sent := bus.sent()
+			if len(sent) != len(tt.want) || (len(sent) > 0 && sent[0] != tt.want[0]) {
+				t.Errorf("sent %v, want %v", sent, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntakeSkipsRefusedCalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		refusedBy string
+		admitted  bool
+	}{
+		{"refused by another worker", "worker2", true},
+		{"refused by this worker", "worker1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+			a.admission = newAdmission(nil)
+
+			a.registerApplication("app", nil, true)
+			if err := a.startIntake(context.Background()); err != nil {
+				t.Fatalf("startIntake: %s", err)
+			}
+
+			msg := nats.NewMsg(a.subjects.Requeue("app", messagebus.ListenQueue))
+			msg.Header.Set(messagebus.RequeueHeader, "1")
+			msg.Header.Add(messagebus.RefusedByHeader, tt.refusedBy)
+
+			if !bus.deliver(msg.Subject, incoming("ast1", ""), msg) {
+				t.Fatal("requeue subject not subscribed")
+			}
+
+			if got := a.ActiveCalls() == 1; got != tt.admitted {
+				t.Errorf("admitted = %v, want %v", got, tt.admitted)
+			}
+			if got := len(bus.requeued) == 1; got == tt.admitted {
+				t.Fatalf("requeued = %v, want %v", got, !tt.admitted)
+			}
+
+			// Passing the call on does not spend the requeue budget
+			if !tt.admitted && messagebus.RequeueCount(bus.requeued[0]) != 1 {
+				t.Errorf("requeue count %d, want 1", messagebus.RequeueCount(bus.requeued[0]))
+			}
+		})
+	}
+}
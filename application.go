@@ -0,0 +1,73 @@
+package ari
+
+import (
+	"github.com/callevo/ari/application"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/requests"
+)
+
+type iapplication struct {
+	c *ARIClient
+}
+
+func (a *iapplication) List(filter *key.Key) ([]*key.Key, error) {
+	return a.c.listRequest(&requests.Request{
+		Kind: "ApplicationList",
+		Key:  filter,
+	})
+}
+
+func (a *iapplication) Get(key *key.Key) *application.ApplicationHandle {
+	k, err := a.c.getRequest(&requests.Request{
+		Kind: "ApplicationGet",
+		Key:  key,
+	})
+	if err != nil {
+		logs.TLogger.Warn().Msgf("failed to get application for handle %s", err)
+		return application.NewApplicationHandle(key, a)
+	}
+	return application.NewApplicationHandle(k, a)
+}
+
+func (a *iapplication) Data(key *key.Key) (*application.ApplicationData, error) {
+	data, err := a.c.dataRequest(&requests.Request{
+		Kind: "ApplicationData",
+		Key:  key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data.Application, nil
+}
+
+func (a *iapplication) Subscribe(key *key.Key, eventSource string) error {
+	err := a.c.commandRequest(&requests.Request{
+		Kind: "ApplicationSubscribe",
+		Key:  key,
+		ApplicationSubscribe: &requests.ApplicationSubscribe{
+			EventSource: eventSource,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Asterisk now sends the events of the source to the application; listen
+	// to them so that they reach the dispatcher
+	return a.c.subscribeEventSource(key, eventSource)
+}
+
+func (a *iapplication) Unsubscribe(key *key.Key, eventSource string) error {
+	err := a.c.commandRequest(&requests.Request{
+		Kind: "ApplicationUnsubscribe",
+		Key:  key,
+		ApplicationSubscribe: &requests.ApplicationSubscribe{
+			EventSource: eventSource,
+		},
+	})
+
+	a.c.unsubscribeEventSource(key, eventSource)
+
+	return err
+}
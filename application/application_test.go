@@ -0,0 +1,30 @@
+package application
+
+import "testing"
+
+func TestParseEventSource(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   string
+		id     string
+		ok     bool
+	}{
+		{"channel:c1", "channel", "c1", true},
+		{"bridge:b1", "bridge", "b1", true},
+		{"endpoint:PJSIP/100", "endpoint", "PJSIP/100", true},
+		{"deviceState:Custom:lamp", "deviceState", "Custom:lamp", true},
+		{"channel:", "channel", "", false},
+		{":c1", "", "c1", false},
+		{"c1", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			kind, id, ok := ParseEventSource(tt.source)
+			if kind != tt.kind || id != tt.id || ok != tt.ok {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, %v)", kind, id, ok, tt.kind, tt.id, tt.ok)
+			}
+		})
+	}
+}
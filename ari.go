@@ -3,16 +3,18 @@ package ari
 import (
 	"context"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/callevo/ari/application"
 	"github.com/callevo/ari/arievent"
 	"github.com/callevo/ari/asterisk"
 	"github.com/callevo/ari/bridge"
+	"github.com/callevo/ari/capture"
 	"github.com/callevo/ari/channel"
 	"github.com/callevo/ari/cluster"
 	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/health"
 	"github.com/callevo/ari/key"
 	"github.com/callevo/ari/logs"
 	"github.com/callevo/ari/messagebus"
@@ -20,6 +22,9 @@ import (
 	"github.com/callevo/ari/recordings"
 	"github.com/callevo/ari/requests"
 	"github.com/callevo/ari/response"
+	"github.com/callevo/ari/rid"
+	"github.com/callevo/ari/state"
+	"github.com/callevo/ari/subject"
 	"github.com/lrita/cmap"
 	nats "github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
@@ -29,23 +34,93 @@ import (
 // ErrNil indicates that the request returned an empty response
 var ErrNil = eris.New("Nil")
 
+// RequestTimeout is the time to wait for the response of a proxy
+var RequestTimeout = 3 * time.Second
+
+// eventBus is the message bus between the client and the proxies.
+// *messagebus.NatsBus implements it.
+type eventBus interface {
+	Connect() error
+	Close()
+	Connection() *nats.Conn
+	JetStream() jetstream.JetStream
+	KeyValue() jetstream.KeyValue
+	SubscribeAnnounce(topic string, callback messagebus.AnnounceHandler) (*nats.Subscription, error)
+	QueueSubscribeEvent(topic, queue string, callback messagebus.MsgEventHandler) (*nats.Subscription, error)
+	DynSubscription(topic string, callback messagebus.EventHandler) (*nats.Subscription, error)
+	Requeue(msg *nats.Msg, topic, instance string) (int, error)
+	RequestContext(ctx context.Context, topic string, r *requests.Request) (*response.Response, error)
+}
+
 type ARIClient struct {
 	Application    string
 	ConnectionName string
 	NATSUrl        string
 
 	announceSubs *nats.Subscription
-	proxysubs    *nats.Subscription
 
-	sbus *messagebus.NatsBus
+	sbus eventBus
 
 	// cluster describes the cluster of ARI proxies
 	cluster *cluster.Cluster
 
-	_dynSubscriptions cmap.Cmap
+	// topics holds the subscriptions to resource topics, shared by their
+	// users; see acquireTopic
+	topics    map[string]*topicSub
+	nextLease uint64
+	topicsMu  sync.Mutex
+
+	// sources holds the event source subscriptions, by topic
+	sources map[string]*topicLease
 
 	_dispatcher *dispatcher.EventDispatcher
 
+	// instanceID uniquely identifies this client among the workers
+	instanceID string
+
+	// _originates holds the channels originated by this client into its own
+	// application, by channel ID, until they leave the application
+	_originates cmap.Cmap
+
+	// claims is the bucket of the originate claim protocol, when enabled
+	claims jetstream.KeyValue
+
+	// claimsWarning warns once about originating without claims while taking
+	// calls from a queue group
+	claimsWarning sync.Once
+
+	// panicHandler receives the panics recovered from call handlers
+	panicHandler PanicHandler
+
+	// queue, nodes, filter and canary select the share of the incoming calls
+	// the client takes
+	queue  string
+	nodes  []string
+	filter StasisStartFilter
+	canary *Canary
+
+	// health holds the circuit breakers of the nodes, when enabled
+	health *health.Registry
+
+	// subjects is the layout of the subjects exchanged with the proxies
+	subjects *subject.Scheme
+
+	// admission decides which incoming calls the client takes
+	admission *admission
+
+	// intakes holds the applications the client takes calls for, by name
+	intakes map[string]*intake
+
+	// listenCtx is the context of Listen, once the client is listening
+	listenCtx context.Context
+
+	// recorder captures the dispatched events, when enabled
+	recorder *capture.Recorder
+
+	// state is the local entity state cache, when enabled
+	state       *state.Store
+	stateMaxAge time.Duration
+
 	mu sync.Mutex
 }
 
@@ -59,14 +134,7 @@ func NatsReconnect(nc *nats.Conn) error {
 
 // Subject returns the communication subject for the given parameters
 func Subject(prefix, appName, class, asterisk string) (ret string) {
-	ret = prefix + "."
-	if appName != "" {
-		ret += (appName + "." + class)
-		if asterisk != "" {
-			ret += "." + asterisk
-		}
-	}
-	return
+	return subject.New(prefix).Request(appName, class, asterisk)
 }
 
 type StasisHandler func(*ARIClient, *channel.ChannelHandle, *arievent.StasisEvent)
@@ -79,6 +147,8 @@ func (a *ARIClient) Create(ctx context.Context, opts *Options) error {
 	a.NATSUrl = opts.NatsUrl
 	a.ConnectionName = opts.ConnectionName
 	a.Application = opts.Application
+	a.setupSubjects(opts)
+	a.setupHealth(opts)
 
 	cfg := messagebus.Config{
 		URL:            a.NATSUrl,
@@ -97,17 +167,50 @@ func (a *ARIClient) Create(ctx context.Context, opts *Options) error {
 		return err
 	}
 
-	a._dispatcher = dispatcher.NewDispatcher()
+	a._dispatcher = dispatcher.NewDispatcher(dispatcher.WithMode(opts.DispatchMode))
+	a.attachCapture(opts)
+	a.attachState(opts)
 
-	return nil
+	if a.instanceID == "" {
+		a.instanceID = rid.New("")
+	}
+
+	return a.setupClaims(ctx, opts)
 }
 
+// Listen connects the client and hands every channel entering the application
+// to the exechandler, until the context is done.  The handler runs in its own
+// goroutine and may return before the call ends.
 func (a *ARIClient) Listen(ctx context.Context, opts *Options, exechandler StasisHandler) error {
+	var handler CallHandler
+	if exechandler != nil {
+		handler = func(_ context.Context, a *ARIClient, h *channel.ChannelHandle, o *arievent.StasisEvent) {
+			exechandler(a, h, o)
+		}
+	}
+
+	return a.listen(ctx, opts, handler, false)
+}
+
+// ListenContext connects the client and hands every channel entering the
+// application to the handler, until the context is done.  The context given to
+// the handler is cancelled when the channel hangs up or leaves the
+// application, and the events of the channel stop being dispatched once the
+// handler returns.
+func (a *ARIClient) ListenContext(ctx context.Context, opts *Options, handler CallHandler) error {
+	return a.listen(ctx, opts, handler, true)
+}
+
+func (a *ARIClient) listen(ctx context.Context, opts *Options, handler CallHandler, cleanup bool) error {
 	logs.TLogger.Debug().Msg("Entering in listening mode")
 
 	a.NATSUrl = opts.NatsUrl
 	a.ConnectionName = opts.ConnectionName
 	a.Application = opts.Application
+	a.panicHandler = opts.PanicHandler
+	a.setupSubjects(opts)
+	a.setupHealth(opts)
+	a.setupSharding(opts)
 
 	cfg := messagebus.Config{
 		URL:            a.NATSUrl,
@@ -119,7 +222,9 @@ func (a *ARIClient) Listen(ctx context.Context, opts *Options, exechandler Stasi
 		MaxPing:        3,
 	}
 
-	a._dispatcher = dispatcher.NewDispatcher()
+	a._dispatcher = dispatcher.NewDispatcher(dispatcher.WithMode(opts.DispatchMode))
+	a.attachCapture(opts)
+	a.attachState(opts)
 
 	a.sbus = messagebus.NewNatsBus(cfg)
 
@@ -128,11 +233,19 @@ func (a *ARIClient) Listen(ctx context.Context, opts *Options, exechandler Stasi
 		return err
 	}
 
+	if a.instanceID == "" {
+		a.instanceID = rid.New("")
+	}
+
+	if err := a.setupClaims(ctx, opts); err != nil {
+		return err
+	}
+
 	a.cluster = cluster.New()
 
 	logs.TLogger.Debug().Msg("subscribing to announce")
-	a.announceSubs, err = a.sbus.SubscribeAnnounce(a.ConnectionName+".announce.*", func(o *cluster.Announcement) {
-		a.cluster.Update(o.Node, o.Application)
+	a.announceSubs, err = a.sbus.SubscribeAnnounce(a.subjects.Announce(""), func(o *cluster.Announcement) {
+		a.cluster.Announce(o)
 	})
 	if err != nil {
 		logs.TLogger.Debug().Msgf("error!! %+v", eris.Wrap(err, "failed to listen to proxy announcements"))
@@ -140,103 +253,129 @@ func (a *ARIClient) Listen(ctx context.Context, opts *Options, exechandler Stasi
 		return eris.Wrap(err, "failed to listen to proxy announcements")
 	}
 
-	logs.TLogger.Debug().Msgf("Queue subscribing to stasisstart events %s", a.ConnectionName+"."+a.Application+".*.*.stasisstart.>")
-	a.proxysubs, err = a.sbus.SubscribeEvent(a.ConnectionName+"."+a.Application+".*.*.stasisstart.>", func(o *arievent.StasisEvent) {
-		logs.TLogger.Debug().Msgf("O: %+v", o)
+	a.admission = newAdmission(opts.Admission)
 
-		// We need to dispatch Event
+	if opts.Application != "" {
+		a.mu.Lock()
+		a.registerApplication(opts.Application, handler, cleanup)
+		a.mu.Unlock()
+	}
 
-		a._dispatcher.Dispatch(o)
+	if err := a.startIntake(ctx); err != nil {
+		logs.TLogger.Debug().Msgf("error!! %+v", err)
 
-		k := key.NewKey(key.ChannelKey, o.Channel.GetID(), key.WithApp(o.Application), key.WithNode(o.Node))
+		return eris.Wrap(err, "error creating dynamic subscription for topic")
+	}
 
-		h := channel.NewChannelHandle(k, &ichannel{c: a}, nil)
+	<-ctx.Done()
 
-		if exechandler != nil {
-			go exechandler(a, h, o)
-		}
+	return ctx.Err()
+}
 
-		channelTopic := a.ConnectionName + "." + a.Application + "." + o.Node + "." + strings.ReplaceAll(o.Channel.ID, ".", "#")
-		logs.TLogger.Debug().Msgf("subscribing client to %s", channelTopic)
-		dynSub, err := a.sbus.DynSubscription(channelTopic, func(o *arievent.StasisEvent) {
-
-			//logs.TLogger.Debug().Msgf("O: %+v", o)
-
-			//dispatching the event to the listeners
-			a._dispatcher.Dispatch(o)
-
-			switch o.GetType() {
-			//case arievent.ApplicationMoveFailed:
-			//case arievent.ApplicationReplaced:
-			//case arievent.BridgeAttendedTransfer:
-			//case arievent.BridgeBlindTransfer:
-			//case arievent.BridgeCreated:
-			//case arievent.BridgeDestroyed:
-			//case arievent.BridgeMerged:
-			//case arievent.BridgeVideoSourceChanged:
-			//case arievent.ChannelCallerId:
-			//case arievent.ChannelConnectedLine:
-			//case arievent.ChannelCreated:
-			//case arievent.ChannelDestroyed:
-			//case arievent.ChannelDialplan:
-			//case arievent.ChannelDtmfReceived:
-			//case arievent.ChannelEnteredBridge:
-			//case arievent.ChannelHangupRequest:
-			//case arievent.ChannelHold:
-			//case arievent.ChannelLeftBridge:
-			//case arievent.ChannelStateChange:
-			//case arievent.ChannelTalkingFinished:
-			//case arievent.ChannelTalkingStarted:
-			//case arievent.ChannelUnhold:
-			//case arievent.ChannelUserevent:
-			//case arievent.ChannelVarset:
-			//case arievent.ContactInfo:
-			//case arievent.ContactStatusChange:
-			//case arievent.DeviceStateChanged:
-			//case arievent.Dial:
-			//case arievent.EndpointStateChange:
-			//case arievent.Event:
-			//case arievent.Message:
-			//case arievent.MissingParams:
-			//case arievent.PeerStatusChange:
-			//case arievent.PlaybackContinuing:
-			//case arievent.PlaybackFinished:
-			//case arievent.PlaybackStarted:
-			//case arievent.RecordingFailed:
-			//case arievent.RecordingFinished:
-			//case arievent.RecordingStarted:
-			//case arievent.StasisStart:
-			//case arievent.TextMessageReceived:
-			case arievent.StasisEnd:
-
-				channelTopic := a.ConnectionName + "." + a.Application + "." + o.Node + "." + strings.ReplaceAll(o.Channel.ID, ".", "#")
-				if myDynSub, ok := a._dynSubscriptions.Load(channelTopic); ok {
-					logs.TLogger.Debug().Msgf("call finished we need to drain and unscrubscribe")
-					myDynSub.(*nats.Subscription).Drain()
-
-					a._dynSubscriptions.Delete(channelTopic)
-				}
-			default:
+// resourceTopic returns the subject prefix on which the proxy publishes the
+// events of the given resource
+func (a *ARIClient) resourceTopic(app, node, id string) string {
+	return a.subjects.Resource(app, node, id)
+}
 
-			}
-		})
-		if err != nil {
-			logs.TLogger.Debug().Msgf("error!! %+v", err)
+// subscribeEventSource subscribes the client to the events of an ARI event
+// source (channel:, bridge:, endpoint: or deviceState:) and dispatches them.
+// The subscription is shared with the calls and originators following the
+// same resource, so that each event is dispatched once.
+func (a *ARIClient) subscribeEventSource(k *key.Key, eventSource string) error {
+	kind, id, ok := application.ParseEventSource(eventSource)
+	if !ok {
+		return eris.Errorf("invalid event source %q", eventSource)
+	}
 
-			return
-		}
+	app := k.GetApp()
+	if app == "" {
+		app = a.Application
+	}
+
+	topic := a.resourceTopic(app, k.GetNode(), id)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-		a._dynSubscriptions.Store(channelTopic, dynSub)
+	if _, ok := a.sources[topic]; ok {
+		return nil
+	}
+
+	lease, err := a.acquireTopic(topic, func(o *arievent.StasisEvent) {
+		if kind == "bridge" && o.GetType() == arievent.BridgeDestroyed {
+			a.releaseEventSource(topic)
+		}
 	})
 	if err != nil {
-		logs.TLogger.Debug().Msgf("error!! %+v", err)
+		return eris.Wrapf(err, "failed to subscribe to event source %s", eventSource)
+	}
 
-		return eris.Wrap(err, "error creating dynamic subscription for topic")
+	if a.sources == nil {
+		a.sources = make(map[string]*topicLease)
 	}
 
-	<-ctx.Done()
+	a.sources[topic] = lease
 
-	return ctx.Err()
+	return nil
+}
+
+// unsubscribeEventSource drops the subscription created by subscribeEventSource.
+// The calls and originators following the same resource keep receiving its
+// events.
+func (a *ARIClient) unsubscribeEventSource(k *key.Key, eventSource string) {
+	_, id, ok := application.ParseEventSource(eventSource)
+	if !ok {
+		return
+	}
+
+	app := k.GetApp()
+	if app == "" {
+		app = a.Application
+	}
+
+	a.releaseEventSource(a.resourceTopic(app, k.GetNode(), id))
+}
+
+func (a *ARIClient) releaseEventSource(topic string) {
+	a.mu.Lock()
+	lease := a.sources[topic]
+	delete(a.sources, topic)
+	a.mu.Unlock()
+
+	lease.Release()
+}
+
+// Cluster returns the proxies known to the client, along with their announced
+// metadata
+func (a *ARIClient) Cluster() *cluster.Cluster {
+	return a.cluster
+}
+
+// Health returns the circuit breakers and health scores of the nodes, or nil if
+// the circuit breaker is not enabled
+func (a *ARIClient) Health() *health.Registry {
+	return a.health
+}
+
+func (a *ARIClient) setupHealth(opts *Options) {
+	if opts.CircuitBreaker == nil {
+		return
+	}
+
+	a.health = health.NewRegistry(opts.CircuitBreaker)
+}
+
+// Subjects returns the subject scheme of the client
+func (a *ARIClient) Subjects() *subject.Scheme {
+	return a.subjects
+}
+
+func (a *ARIClient) setupSubjects(opts *Options) {
+	a.subjects = opts.SubjectScheme
+	if a.subjects == nil {
+		a.subjects = subject.New(a.ConnectionName)
+	}
 }
 
 func (a *ARIClient) Messagebus() *nats.Conn {
@@ -255,6 +394,11 @@ func (a *ARIClient) Channel() channel.Channel {
 	return &ichannel{a}
 }
 
+// Applications is the application accessor
+func (a *ARIClient) Applications() application.Application {
+	return &iapplication{a}
+}
+
 func (a *ARIClient) Asterisk() asterisk.Asterisk {
 	return &iasterisk{a}
 }
@@ -267,8 +411,95 @@ func (a *ARIClient) Dispatcher() *dispatcher.EventDispatcher {
 	return a._dispatcher
 }
 
+// subscribeKey registers the listener for the events of the given types
+// relating to the entity identified by the key, or for all of its events when
+// no type is given.  Typed subscriptions are listeners and honour
+// StopPropagation in ordered mode; untyped ones are taps and see every event.
+func (a *ARIClient) subscribeKey(k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	match := func(e *arievent.StasisEvent) {
+		if e.Matches(k) {
+			l(e)
+		}
+	}
+
+	if len(types) == 0 {
+		return a._dispatcher.Tap(match)
+	}
+
+	return a._dispatcher.Subscribe(match, dispatcher.DefaultPriority, types...)
+}
+
+// OnUserEvent registers the listener for the user events with the given name,
+// or for all of them when the name is empty, among the events the client
+// receives: those of the calls it handles, of the channels it originated and
+// of the event sources it subscribed to.  The user events of other channels
+// do not reach it.  Services sharing the application coordinate through these
+// events.
+func (a *ARIClient) OnUserEvent(name string, l dispatcher.Listener) *dispatcher.Subscription {
+	return a._dispatcher.Subscribe(func(e *arievent.StasisEvent) {
+		if name == "" || e.EventName == name {
+			l(e)
+		}
+	}, dispatcher.DefaultPriority, arievent.ChannelUserevent)
+}
+
+// WaitFor waits for an event of one of the given types relating to the entity
+// identified by the key.  See dispatcher.EventDispatcher.WaitFor.
+func (a *ARIClient) WaitFor(ctx context.Context, k *key.Key, types ...arievent.EventType) (*arievent.StasisEvent, error) {
+	return a._dispatcher.WaitFor(ctx, k, types...)
+}
+
+// WaitForMatch waits for an event satisfying the predicate.  See
+// dispatcher.EventDispatcher.WaitForMatch.
+func (a *ARIClient) WaitForMatch(ctx context.Context, match func(*arievent.StasisEvent) bool) (*arievent.StasisEvent, error) {
+	return a._dispatcher.WaitForMatch(ctx, match)
+}
+
+// State returns the local entity state cache, or nil if it is not enabled
+func (a *ARIClient) State() *state.Store {
+	return a.state
+}
+
+func (a *ARIClient) attachState(opts *Options) {
+	if !opts.StateCache {
+		return
+	}
+
+	a.state = state.New()
+	a.stateMaxAge = opts.StateMaxAge
+	a.state.Attach(a._dispatcher)
+}
+
+// Recorder returns the capture of the dispatched events, or nil if it is not
+// enabled
+func (a *ARIClient) Recorder() *capture.Recorder {
+	return a.recorder
+}
+
+func (a *ARIClient) attachCapture(opts *Options) {
+	// A client set up again, as by Create then Listen, keeps its recorder
+	// and moves it to the new dispatcher
+	if a.recorder != nil {
+		a.recorder.Attach(a._dispatcher)
+		return
+	}
+
+	if opts.Capture == nil {
+		return
+	}
+
+	a.recorder = capture.NewRecorder(opts.Capture)
+	a.recorder.Attach(a._dispatcher)
+}
+
 func (a *ARIClient) Close() {
 	a.sbus.Close()
+
+	if a.recorder != nil {
+		if err := a.recorder.Close(); err != nil {
+			logs.TLogger.Debug().Msgf("failed to close event capture: %s", err)
+		}
+	}
 }
 
 type Options struct {
@@ -278,6 +509,81 @@ type Options struct {
 	ConnectionName string
 
 	NatsUrl string
+
+	// DispatchMode selects how events are delivered to the dispatcher
+	// listeners.  The default, dispatcher.Parallel, runs every listener
+	// concurrently; dispatcher.Ordered runs them by priority and honours
+	// StopPropagation.
+	DispatchMode dispatcher.Mode
+
+	// Capture, when set, records every event the client dispatches to the
+	// Writer, such as capture.NewJSONLWriter or capture.NewJetStreamWriter.
+	// The Writer is closed along with the client.  Only the Capture given to
+	// the first of Create, Listen or ListenContext is used: setting the
+	// client up again keeps recording to it.
+	Capture capture.Writer
+
+	// StateCache enables the local entity state cache.  Data requests for
+	// channels, bridges, playbacks and live recordings are then answered from
+	// the cache when it holds fresh enough state.
+	StateCache bool
+
+	// StateMaxAge is the staleness bound for data served from the state
+	// cache.  Zero accepts cached state of any age.
+	StateMaxAge time.Duration
+
+	// OriginateClaims enables the originate claim protocol: a worker which
+	// originates a channel into the application claims it in a shared
+	// key-value bucket, and the other workers of the queue group ignore its
+	// StasisStart.  Every worker of the queue group must enable it.  Without
+	// it, the StasisStart of an originated channel is also taken by the other
+	// workers of the queue group, so that the call is handled twice: a
+	// worker which originates into the application it listens on must enable
+	// it unless it is the only member of its queue group.
+	OriginateClaims bool
+
+	// PanicHandler is called with the value recovered from a panicking call
+	// handler.  Panics are logged when it is not set; they never take down
+	// the worker.
+	PanicHandler PanicHandler
+
+	// SubjectScheme is the layout of the subjects exchanged with the
+	// proxies.  Defaults to subject.New(ConnectionName).
+	SubjectScheme *subject.Scheme
+
+	// QueueGroup is the queue group the client joins to share the incoming
+	// calls with the other workers.  Defaults to messagebus.ListenQueue.
+	// Every queue group receives every StasisStart, so each call reaches one
+	// member of each group.
+	QueueGroup string
+
+	// Nodes restricts the incoming calls to those entering the application
+	// on the given Asterisk nodes.  Every node is served when it is empty.
+	Nodes []string
+
+	// Filter restricts the incoming calls to those it accepts.  A worker
+	// pool using a filter should have its own QueueGroup, so that the calls
+	// it ignores reach the pools which take them.  Filters are not exclusive:
+	//   - a pool without a filter takes every call, including those a
+	//     filtered pool takes, which are then handled twice; the filters of
+	//     the pools sharing calls must not overlap, and an unfiltered pool
+	//     must not run beside filtered ones;
+	//   - every member of a queue group must use the same filter, as a call
+	//     rejected by the member it reached is lost to the rest of the group.
+	Filter StasisStartFilter
+
+	// Canary sends a fraction of the incoming calls to a canary pool
+	Canary *Canary
+
+	// CircuitBreaker enables a circuit breaker per node in the request path:
+	// after consecutive failed requests, the requests to a node fail
+	// immediately with health.ErrOpen until a probe request succeeds.
+	CircuitBreaker *health.Options
+
+	// Admission limits the number of concurrent calls the worker takes, and
+	// tells what happens to the calls it refuses.  No limit applies when it
+	// is not set.
+	Admission *AdmissionOptions
 }
 
 func (c *ARIClient) commandRequest(req *requests.Request) error {
@@ -298,6 +604,15 @@ func (c *ARIClient) completeCoordinates(req *requests.Request) bool {
 }
 
 func (c *ARIClient) makeRequest(class string, req *requests.Request) (*response.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	return c.makeRequestContext(ctx, class, req)
+}
+
+// makeRequestContext sends the request and waits for the response until the
+// context is done
+func (c *ARIClient) makeRequestContext(ctx context.Context, class string, req *requests.Request) (*response.Response, error) {
 	//var resp response.Response
 	//var err error
 
@@ -305,15 +620,30 @@ func (c *ARIClient) makeRequest(class string, req *requests.Request) (*response.
 		return nil, eris.New("Uncomplete request")
 	}
 
+	if c.health == nil {
+		logs.TLogger.Debug().Msgf("Sending request to %s for %s", c.subject(class, req), req.Kind)
+		return c.sbus.RequestContext(ctx, c.subject(class, req), req)
+	}
+
+	node := req.Key.Node
+	ticket, err := c.health.Allow(node)
+	if err != nil {
+		return nil, err
+	}
+
 	logs.TLogger.Debug().Msgf("Sending request to %s for %s", c.subject(class, req), req.Kind)
-	return c.sbus.Request(c.subject(class, req), req)
+	started := time.Now()
+	resp, err := c.sbus.RequestContext(ctx, c.subject(class, req), req)
+	c.health.Record(node, ticket, time.Since(started), err)
+
+	return resp, err
 }
 
 func (c *ARIClient) subject(class string, req *requests.Request) string {
 	if req == nil || req.Key == nil {
-		return Subject(c.ConnectionName, c.Application, class, "")
+		return c.subjects.Request(c.Application, class, "")
 	}
-	return Subject(c.ConnectionName, req.Key.App, class, req.Key.Node)
+	return c.subjects.Request(req.Key.App, class, req.Key.Node)
 }
 
 func (c *ARIClient) getRequest(req *requests.Request) (*key.Key, error) {
@@ -347,11 +677,13 @@ func (c *ARIClient) listRequest(req *requests.Request) ([]*key.Key, error) {
 	if resp.Err() != nil {
 		return nil, resp.Err()
 	}
-	if resp.Data == nil {
+	if resp.Keys == nil && resp.Data == nil {
 		return nil, ErrNil
 	}
 	logs.TLogger.Debug().Msgf("we got %+v", resp)
 
+	list = append(list, resp.Keys...)
+
 	/*
 		for _, r := range responses {
 			err = r.Err()
@@ -382,7 +714,14 @@ func (c *ARIClient) dataRequest(req *requests.Request) (*response.EntityData, er
 }
 
 func (c *ARIClient) createRequest(req *requests.Request) (*key.Key, error) {
-	resp, err := c.makeRequest("create", req)
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	return c.createRequestContext(ctx, req)
+}
+
+func (c *ARIClient) createRequestContext(ctx context.Context, req *requests.Request) (*key.Key, error) {
+	resp, err := c.makeRequestContext(ctx, "create", req)
 	if err != nil {
 		return nil, err
 	}
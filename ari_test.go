@@ -0,0 +1,155 @@
+package ari
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/capture"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+)
+
+func TestListRequest(t *testing.T) {
+	filter := key.NewKey(key.ChannelKey, "", key.WithApp("app"), key.WithNode("ast1"))
+
+	tests := []struct {
+		name    string
+		resp    *response.Response
+		want    int
+		wantErr bool
+	}{
+		{"keys", &response.Response{Keys: []*key.Key{key.NewKey(key.ChannelKey, "c1"), key.NewKey(key.ChannelKey, "c2")}}, 2, false},
+		{"no keys", &response.Response{Data: &response.EntityData{}}, 0, false},
+		{"empty response", &response.Response{}, 0, true},
+		{"error", errorResponse("boom"), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			bus.respond = func(string, *requests.Request) (*response.Response, error) {
+				return tt.resp, nil
+			}
+
+			list, err := newTestClient(bus).Channel().List(filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if len(list) != tt.want {
+				t.Errorf("got %d keys, want %d", len(list), tt.want)
+			}
+		})
+	}
+}
+
+func TestOnUserEvent(t *testing.T) {
+	events := []*arievent.StasisEvent{
+		{Type: arievent.ChannelUserevent, EventName: "ping", Channel: arievent.ChannelData{ID: "c1"}},
+		{Type: arievent.ChannelUserevent, EventName: "pong", Channel: arievent.ChannelData{ID: "c1"}},
+		{Type: arievent.ChannelUserevent, EventName: "ping", Channel: arievent.ChannelData{ID: "c2"}},
+		{Type: arievent.ChannelUserevent, EventName: "ping"},
+		{Type: arievent.ChannelDtmfReceived, Digit: "1", Channel: arievent.ChannelData{ID: "c1"}},
+	}
+
+	tests := []struct {
+		name    string
+		channel bool
+		event   string
+		want    int
+	}{
+		{name: "client, by name", event: "ping", want: 3},
+		{name: "client, any name", want: 4},
+		{name: "channel, by name", channel: true, event: "ping", want: 1},
+		{name: "channel, any name", channel: true, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestClient(newFakeBus())
+
+			// The ordered dispatcher runs the listeners on the dispatching
+			// goroutine
+			got := 0
+			count := func(e *arievent.StasisEvent) {
+				got++
+			}
+
+			var sub *dispatcher.Subscription
+			if tt.channel {
+				h := channel.NewChannelHandle(key.NewKey(key.ChannelKey, "c1", key.WithApp("app")), a.Channel(), nil)
+				sub = h.OnUserEvent(tt.event, count)
+			} else {
+				sub = a.OnUserEvent(tt.event, count)
+			}
+
+			for _, e := range events {
+				a._dispatcher.Dispatch(e)
+			}
+
+			sub.Cancel()
+
+			if got != tt.want {
+				t.Errorf("received %d events, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// captureWriter keeps the types of the captured events
+type captureWriter struct {
+	mu     sync.Mutex
+	types  []arievent.EventType
+	closed bool
+}
+
+func (w *captureWriter) Write(e *capture.Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.types = append(w.types, e.Event.GetType())
+
+	return nil
+}
+
+func (w *captureWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+
+	return nil
+}
+
+func TestAttachCaptureAgain(t *testing.T) {
+	w := &captureWriter{}
+	a := newTestClient(newFakeBus())
+	opts := &Options{Capture: w}
+
+	a.attachCapture(opts)
+	first, old := a.recorder, a._dispatcher
+
+	// Setting the client up again, as Listen after Create does, replaces the
+	// dispatcher
+	a._dispatcher = dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered))
+	a.attachCapture(opts)
+
+	if a.recorder != first {
+		t.Fatal("a second recorder was created")
+	}
+
+	old.Dispatch(&arievent.StasisEvent{Type: arievent.StasisStart})
+	a._dispatcher.Dispatch(&arievent.StasisEvent{Type: arievent.StasisEnd})
+
+	if err := a.recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []arievent.EventType{arievent.StasisEnd}; !reflect.DeepEqual(w.types, want) || !w.closed {
+		t.Errorf("captured %v (closed %v), want %v", w.types, w.closed, want)
+	}
+}
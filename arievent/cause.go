@@ -0,0 +1,279 @@
+package arievent
+
+import "strconv"
+
+// Cause is a Q.850 hangup cause, as carried by the ChannelDestroyed and
+// ChannelHangupRequest events
+type Cause int
+
+const (
+	CauseNotDefined               Cause = 0
+	CauseUnallocated              Cause = 1
+	CauseNoRouteTransitNet        Cause = 2
+	CauseNoRouteDestination       Cause = 3
+	CauseMisdialledTrunkPrefix    Cause = 5
+	CauseChannelUnacceptable      Cause = 6
+	CauseCallAwardedDelivered     Cause = 7
+	CausePreEmpted                Cause = 8
+	CauseNumberPortedNotHere      Cause = 14
+	CauseNormalClearing           Cause = 16
+	CauseUserBusy                 Cause = 17
+	CauseNoUserResponse           Cause = 18
+	CauseNoAnswer                 Cause = 19
+	CauseSubscriberAbsent         Cause = 20
+	CauseCallRejected             Cause = 21
+	CauseNumberChanged            Cause = 22
+	CauseRedirectedToNewDest      Cause = 23
+	CauseAnsweredElsewhere        Cause = 26
+	CauseDestinationOutOfOrder    Cause = 27
+	CauseInvalidNumberFormat      Cause = 28
+	CauseFacilityRejected         Cause = 29
+	CauseResponseToStatusEnquiry  Cause = 30
+	CauseNormalUnspecified        Cause = 31
+	CauseNormalCircuitCongestion  Cause = 34
+	CauseNetworkOutOfOrder        Cause = 38
+	CauseNormalTemporaryFailure   Cause = 41
+	CauseSwitchCongestion         Cause = 42
+	CauseAccessInfoDiscarded      Cause = 43
+	CauseRequestedChanUnavail     Cause = 44
+	CauseFacilityNotSubscribed    Cause = 50
+	CauseOutgoingCallBarred       Cause = 52
+	CauseIncomingCallBarred       Cause = 54
+	CauseBearerCapabilityNotAuth  Cause = 57
+	CauseBearerCapabilityNotAvail Cause = 58
+	CauseBearerCapabilityNotImpl  Cause = 65
+	CauseChanNotImplemented       Cause = 66
+	CauseFacilityNotImplemented   Cause = 69
+	CauseInvalidCallReference     Cause = 81
+	CauseIncompatibleDestination  Cause = 88
+	CauseInvalidMsgUnspecified    Cause = 95
+	CauseMandatoryIEMissing       Cause = 96
+	CauseMessageTypeNonexist      Cause = 97
+	CauseWrongMessage             Cause = 98
+	CauseIENonexist               Cause = 99
+	CauseInvalidIEContents        Cause = 100
+	CauseWrongCallState           Cause = 101
+	CauseRecoveryOnTimerExpire    Cause = 102
+	CauseMandatoryIELengthError   Cause = 103
+	CauseProtocolError            Cause = 111
+	CauseInterworking             Cause = 127
+)
+
+var causeDescriptions = map[Cause]string{
+	CauseNotDefined:               "Not defined",
+	CauseUnallocated:              "Unallocated (unassigned) number",
+	CauseNoRouteTransitNet:        "No route to specified transit network",
+	CauseNoRouteDestination:       "No route to destination",
+	CauseMisdialledTrunkPrefix:    "Misdialled trunk prefix",
+	CauseChannelUnacceptable:      "Channel unacceptable",
+	CauseCallAwardedDelivered:     "Call awarded and being delivered in an established channel",
+	CausePreEmpted:                "Pre-emption",
+	CauseNumberPortedNotHere:      "Number ported but not found here",
+	CauseNormalClearing:           "Normal clearing",
+	CauseUserBusy:                 "User busy",
+	CauseNoUserResponse:           "No user responding",
+	CauseNoAnswer:                 "User alerting, no answer",
+	CauseSubscriberAbsent:         "Subscriber absent",
+	CauseCallRejected:             "Call rejected",
+	CauseNumberChanged:            "Number changed",
+	CauseRedirectedToNewDest:      "Redirected to new destination",
+	CauseAnsweredElsewhere:        "Answered elsewhere",
+	CauseDestinationOutOfOrder:    "Destination out of order",
+	CauseInvalidNumberFormat:      "Invalid number format",
+	CauseFacilityRejected:         "Facility rejected",
+	CauseResponseToStatusEnquiry:  "Response to STATUS ENQUIRY",
+	CauseNormalUnspecified:        "Normal, unspecified",
+	CauseNormalCircuitCongestion:  "Circuit/channel congestion",
+	CauseNetworkOutOfOrder:        "Network out of order",
+	CauseNormalTemporaryFailure:   "Temporary failure",
+	CauseSwitchCongestion:         "Switching equipment congestion",
+	CauseAccessInfoDiscarded:      "Access information discarded",
+	CauseRequestedChanUnavail:     "Requested channel not available",
+	CauseFacilityNotSubscribed:    "Facility not subscribed",
+	CauseOutgoingCallBarred:       "Outgoing call barred",
+	CauseIncomingCallBarred:       "Incoming call barred",
+	CauseBearerCapabilityNotAuth:  "Bearer capability not authorized",
+	CauseBearerCapabilityNotAvail: "Bearer capability not available",
+	CauseBearerCapabilityNotImpl:  "Bearer capability not implemented",
+	CauseChanNotImplemented:       "Channel type not implemented",
+	CauseFacilityNotImplemented:   "Facility not implemented",
+	CauseInvalidCallReference:     "Invalid call reference value",
+	CauseIncompatibleDestination:  "Incompatible destination",
+	CauseInvalidMsgUnspecified:    "Invalid message, unspecified",
+	CauseMandatoryIEMissing:       "Mandatory information element is missing",
+	CauseMessageTypeNonexist:      "Message type nonexistent or not implemented",
+	CauseWrongMessage:             "Message not compatible with call state or message type nonexistent",
+	CauseIENonexist:               "Information element nonexistent or not implemented",
+	CauseInvalidIEContents:        "Invalid information element contents",
+	CauseWrongCallState:           "Message not compatible with call state",
+	CauseRecoveryOnTimerExpire:    "Recovery on timer expiry",
+	CauseMandatoryIELengthError:   "Mandatory information element length error",
+	CauseProtocolError:            "Protocol error, unspecified",
+	CauseInterworking:             "Interworking, unspecified",
+}
+
+// Description returns the Q.850 description of the cause
+func (c Cause) Description() string {
+	if d, ok := causeDescriptions[c]; ok {
+		return d
+	}
+
+	return "Unknown cause " + strconv.Itoa(int(c))
+}
+
+func (c Cause) String() string {
+	return strconv.Itoa(int(c)) + " (" + c.Description() + ")"
+}
+
+// Reason returns the hangup reason closest to the cause
+// nolint: gocyclo
+func (c Cause) Reason() HangupReason {
+	switch c {
+	case CauseNormalClearing:
+		return HangupNormal
+	case CauseUserBusy:
+		return HangupBusy
+	case CauseNormalCircuitCongestion, CauseSwitchCongestion:
+		return HangupCongestion
+	case CauseNoAnswer:
+		return HangupNoAnswer
+	case CauseNoUserResponse:
+		return HangupTimeout
+	case CauseCallRejected:
+		return HangupRejected
+	case CauseUnallocated:
+		return HangupUnallocated
+	case CauseNormalUnspecified:
+		return HangupNormalUnspecified
+	case CauseInvalidNumberFormat:
+		return HangupNumberIncomplete
+	case CauseBearerCapabilityNotAvail:
+		return HangupCodecMismatch
+	case CauseInterworking:
+		return HangupInterworking
+	case CauseAnsweredElsewhere:
+		return HangupAnsweredElsewhere
+	}
+
+	return HangupFailure
+}
+
+// DialStatus returns the status of a dial whose channel was hung up with the
+// cause before answering.  As with the Dial application of Asterisk, only
+// User busy is BUSY; a rejected call is CHANUNAVAIL.
+func (c Cause) DialStatus() DialStatus {
+	switch c {
+	case CauseUserBusy:
+		return DialBusy
+	case CauseNoUserResponse, CauseNoAnswer:
+		return DialNoAnswer
+	case CauseNormalCircuitCongestion, CauseNetworkOutOfOrder, CauseNormalTemporaryFailure,
+		CauseSwitchCongestion, CauseRequestedChanUnavail:
+		return DialCongestion
+	case CauseNormalClearing, CauseNormalUnspecified, CauseAnsweredElsewhere:
+		return DialCancel
+	}
+
+	return DialChanUnavail
+}
+
+// IsBusy reports whether the cause indicates a busy party
+func (c Cause) IsBusy() bool {
+	return c == CauseUserBusy
+}
+
+// IsNormal reports whether the cause indicates a normal hangup
+func (c Cause) IsNormal() bool {
+	return c == CauseNormalClearing || c == CauseNormalUnspecified
+}
+
+// HangupReason is the reason given when hanging up a channel through ARI
+type HangupReason string
+
+const (
+	HangupNormal            HangupReason = "normal"
+	HangupBusy              HangupReason = "busy"
+	HangupCongestion        HangupReason = "congestion"
+	HangupNoAnswer          HangupReason = "no_answer"
+	HangupTimeout           HangupReason = "timeout"
+	HangupRejected          HangupReason = "rejected"
+	HangupUnallocated       HangupReason = "unallocated"
+	HangupNormalUnspecified HangupReason = "normal_unspecified"
+	HangupNumberIncomplete  HangupReason = "number_incomplete"
+	HangupCodecMismatch     HangupReason = "codec_mismatch"
+	HangupInterworking      HangupReason = "interworking"
+	HangupFailure           HangupReason = "failure"
+	HangupAnsweredElsewhere HangupReason = "answered_elsewhere"
+)
+
+var reasonCauses = map[HangupReason]Cause{
+	HangupNormal:            CauseNormalClearing,
+	HangupBusy:              CauseUserBusy,
+	HangupCongestion:        CauseNormalCircuitCongestion,
+	HangupNoAnswer:          CauseNoAnswer,
+	HangupTimeout:           CauseNoUserResponse,
+	HangupRejected:          CauseCallRejected,
+	HangupUnallocated:       CauseUnallocated,
+	HangupNormalUnspecified: CauseNormalUnspecified,
+	HangupNumberIncomplete:  CauseInvalidNumberFormat,
+	HangupCodecMismatch:     CauseBearerCapabilityNotAvail,
+	HangupInterworking:      CauseInterworking,
+	HangupFailure:           CauseNetworkOutOfOrder,
+	HangupAnsweredElsewhere: CauseAnsweredElsewhere,
+}
+
+// Cause returns the Q.850 cause Asterisk uses for the reason, following
+// res_ari: congestion is AST_CAUSE_CONGESTION, which causes.h defines as
+// AST_CAUSE_NORMAL_CIRCUIT_CONGESTION (34), and failure AST_CAUSE_FAILURE (38)
+func (r HangupReason) Cause() Cause {
+	if c, ok := reasonCauses[r]; ok {
+		return c
+	}
+
+	return CauseNormalClearing
+}
+
+// Valid reports whether ARI accepts the reason.  The empty reason is valid and
+// selects normal.
+func (r HangupReason) Valid() bool {
+	_, ok := reasonCauses[r]
+	return ok || r == ""
+}
+
+// ChannelState is the state of a channel
+type ChannelState string
+
+const (
+	StateDown           ChannelState = "Down"
+	StateReserved       ChannelState = "Rsrvd"
+	StateOffHook        ChannelState = "OffHook"
+	StateDialing        ChannelState = "Dialing"
+	StateRing           ChannelState = "Ring"
+	StateRinging        ChannelState = "Ringing"
+	StateUp             ChannelState = "Up"
+	StateBusy           ChannelState = "Busy"
+	StateDialingOffhook ChannelState = "Dialing Offhook"
+	StatePreRing        ChannelState = "Pre-ring"
+	StateUnknown        ChannelState = "Unknown"
+)
+
+// IsUp reports whether the channel is answered
+func (s ChannelState) IsUp() bool {
+	return s == StateUp
+}
+
+// IsRinging reports whether the channel rings, either the remote end
+// (Ringing) or the local one (Ring)
+func (s ChannelState) IsRinging() bool {
+	return s == StateRinging || s == StateRing || s == StatePreRing
+}
+
+// IsDown reports whether the channel is not connected
+func (s ChannelState) IsDown() bool {
+	return s == StateDown || s == StateReserved
+}
+
+// IsBusy reports whether the channel is busy
+func (s ChannelState) IsBusy() bool {
+	return s == StateBusy
+}
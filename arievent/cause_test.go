@@ -0,0 +1,122 @@
+package arievent
+
+import "testing"
+
+func TestHangupReasonCause(t *testing.T) {
+	tests := []struct {
+		reason HangupReason
+		cause  Cause
+		valid  bool
+	}{
+		{HangupNormal, CauseNormalClearing, true},
+		{HangupBusy, CauseUserBusy, true},
+		{HangupCongestion, CauseNormalCircuitCongestion, true},
+		{HangupNoAnswer, CauseNoAnswer, true},
+		{HangupTimeout, CauseNoUserResponse, true},
+		{HangupRejected, CauseCallRejected, true},
+		{HangupUnallocated, CauseUnallocated, true},
+		{HangupNormalUnspecified, CauseNormalUnspecified, true},
+		{HangupNumberIncomplete, CauseInvalidNumberFormat, true},
+		{HangupCodecMismatch, CauseBearerCapabilityNotAvail, true},
+		{HangupInterworking, CauseInterworking, true},
+		{HangupFailure, CauseNetworkOutOfOrder, true},
+		{HangupAnsweredElsewhere, CauseAnsweredElsewhere, true},
+		{"", CauseNormalClearing, true},
+		{"hungry", CauseNormalClearing, false},
+	}
+
+	for _, tt := range tests {
+		if c := tt.reason.Cause(); c != tt.cause {
+			t.Errorf("%q.Cause() = %s, want %s", tt.reason, c, tt.cause)
+		}
+		if v := tt.reason.Valid(); v != tt.valid {
+			t.Errorf("%q.Valid() = %v, want %v", tt.reason, v, tt.valid)
+		}
+
+		// Every reason survives the round trip through its cause
+		if tt.reason != "" && tt.valid {
+			if r := tt.cause.Reason(); r != tt.reason {
+				t.Errorf("%s.Reason() = %q, want %q", tt.cause, r, tt.reason)
+			}
+		}
+	}
+}
+
+func TestCause(t *testing.T) {
+	tests := []struct {
+		cause  Cause
+		reason HangupReason
+		status DialStatus
+		busy   bool
+		normal bool
+	}{
+		{CauseNormalClearing, HangupNormal, DialCancel, false, true},
+		{CauseNormalUnspecified, HangupNormalUnspecified, DialCancel, false, true},
+		{CauseAnsweredElsewhere, HangupAnsweredElsewhere, DialCancel, false, false},
+		{CauseUserBusy, HangupBusy, DialBusy, true, false},
+		{CauseCallRejected, HangupRejected, DialChanUnavail, false, false},
+		{CauseNoAnswer, HangupNoAnswer, DialNoAnswer, false, false},
+		{CauseNoUserResponse, HangupTimeout, DialNoAnswer, false, false},
+		{CauseNormalCircuitCongestion, HangupCongestion, DialCongestion, false, false},
+		{CauseSwitchCongestion, HangupCongestion, DialCongestion, false, false},
+		{CauseNetworkOutOfOrder, HangupFailure, DialCongestion, false, false},
+		{CauseNormalTemporaryFailure, HangupFailure, DialCongestion, false, false},
+		{CauseRequestedChanUnavail, HangupFailure, DialCongestion, false, false},
+		{CauseUnallocated, HangupUnallocated, DialChanUnavail, false, false},
+		{CauseNoRouteDestination, HangupFailure, DialChanUnavail, false, false},
+		{CauseDestinationOutOfOrder, HangupFailure, DialChanUnavail, false, false},
+		{CauseInterworking, HangupInterworking, DialChanUnavail, false, false},
+		{Cause(200), HangupFailure, DialChanUnavail, false, false},
+	}
+
+	for _, tt := range tests {
+		if r := tt.cause.Reason(); r != tt.reason {
+			t.Errorf("%s.Reason() = %q, want %q", tt.cause, r, tt.reason)
+		}
+		if s := tt.cause.DialStatus(); s != tt.status {
+			t.Errorf("%s.DialStatus() = %s, want %s", tt.cause, s, tt.status)
+		}
+		if tt.cause.IsBusy() != tt.busy || tt.cause.IsNormal() != tt.normal {
+			t.Errorf("%s busy %v normal %v, want busy %v normal %v", tt.cause, tt.cause.IsBusy(), tt.cause.IsNormal(), tt.busy, tt.normal)
+		}
+	}
+}
+
+func TestCauseString(t *testing.T) {
+	tests := []struct {
+		cause Cause
+		want  string
+	}{
+		{CauseNormalClearing, "16 (Normal clearing)"},
+		{CauseSwitchCongestion, "42 (Switching equipment congestion)"},
+		{Cause(4), "4 (Unknown cause 4)"},
+	}
+
+	for _, tt := range tests {
+		if s := tt.cause.String(); s != tt.want {
+			t.Errorf("String() = %q, want %q", s, tt.want)
+		}
+	}
+}
+
+func TestChannelState(t *testing.T) {
+	tests := []struct {
+		state                   ChannelState
+		up, ringing, down, busy bool
+	}{
+		{StateUp, true, false, false, false},
+		{StateRinging, false, true, false, false},
+		{StateRing, false, true, false, false},
+		{StatePreRing, false, true, false, false},
+		{StateDown, false, false, true, false},
+		{StateReserved, false, false, true, false},
+		{StateBusy, false, false, false, true},
+		{StateDialing, false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		if tt.state.IsUp() != tt.up || tt.state.IsRinging() != tt.ringing || tt.state.IsDown() != tt.down || tt.state.IsBusy() != tt.busy {
+			t.Errorf("%s: up %v ringing %v down %v busy %v", tt.state, tt.state.IsUp(), tt.state.IsRinging(), tt.state.IsDown(), tt.state.IsBusy())
+		}
+	}
+}
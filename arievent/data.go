@@ -0,0 +1,140 @@
+package arievent
+
+import (
+	"time"
+
+	"github.com/callevo/ari/key"
+)
+
+// The payloads of the events live here, rather than in the packages of the
+// entities, so that those packages may subscribe to events.  The entity
+// packages declare aliases for them.
+
+type CallerInfo struct {
+	Name   string `json:"name"`
+	Number string `json:"number"`
+}
+
+type ChannelData struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	State        ChannelState `json:"state"`
+	Protocol     string       `json:"protocol"`
+	Caller       CallerInfo
+	Connected    CallerInfo
+	Accountcode  string `json:"accountcode"`
+	Dialplan     DialplanInfo
+	Creationtime string            `json:"creationtime"`
+	Language     string            `json:"language"`
+	ChannelVars  map[string]string `json:"channelvars"`
+}
+
+func (c *ChannelData) GetID() string {
+	return c.ID
+}
+
+type DialplanInfo struct {
+	Context  string `json:"context"`
+	Exten    string `json:"exten"`
+	Priority int    `json:"priority"`
+	AppName  string `json:"app_name"`
+	AppData  string `json:"app_data"`
+}
+
+// BridgeData describes an Asterisk Bridge, the entity which merges media from
+// one or more channels into a common audio output
+type BridgeData struct {
+	// Key is the cluster-unique identifier for this bridge
+	Key *key.Key `json:"key"`
+
+	ID         string   `json:"id"`           // Unique Id for this bridge
+	Class      string   `json:"bridge_class"` // Class of the bridge
+	Type       string   `json:"bridge_type"`  // Type of bridge (mixing, holding, dtmf_events, proxy_media)
+	ChannelIDs []string `json:"channels"`     // List of pariticipating channel ids
+	Creator    string   `json:"creator"`      // Creating entity of the bridge
+	Name       string   `json:"name"`         // The name of the bridge
+	Technology string   `json:"technology"`   // Name of the bridging technology
+}
+
+// Channels returns the list of channels found in the bridge
+func (b *BridgeData) Channels() (list []*key.Key) {
+	for _, id := range b.ChannelIDs {
+		list = append(list, b.Key.New(key.ChannelKey, id))
+	}
+
+	return
+}
+
+// PlaybackData represents the state of a playback
+type PlaybackData struct {
+	// Key is the cluster-unique identifier for this playback
+	Key *key.Key `json:"key"`
+
+	ID        string `json:"id"` // Unique ID for this playback session
+	Language  string `json:"language,omitempty"`
+	MediaURI  string `json:"media_uri"`  // URI for the media which is to be played
+	State     string `json:"state"`      // State of the playback operation
+	TargetURI string `json:"target_uri"` // URI of the channel or bridge on which the media should be played (follows format of 'type':'name')
+}
+
+// LiveRecordingData is the data for a live recording
+type LiveRecordingData struct {
+	// Key is the cluster-unique identifier for this live recording
+	Key *key.Key `json:"key"`
+
+	Cause     string        `json:"cause,omitempty"`            // If failed, the cause of the failure
+	Duration  time.Duration `json:"duration,omitempty"`         // Length of recording in seconds
+	Format    string        `json:"format"`                     // Format of recording (wav, gsm, etc)
+	Name      string        `json:"name"`                       // (base) name for the recording
+	Silence   time.Duration `json:"silence_duration,omitempty"` // If silence was detected in the recording, the duration in seconds of that silence (requires that maxSilenceSeconds be non-zero)
+	State     string        `json:"state"`                      // Current state of the recording
+	Talking   time.Duration `json:"talking_duration,omitempty"` // Duration of talking, in seconds, that has been detected in the recording (requires that maxSilenceSeconds be non-zero)
+	TargetURI string        `json:"target_uri"`                 // URI for the channel or bridge which is being recorded (TODO: figure out format for this)
+}
+
+// ID returns the identifier of the live recording
+func (s *LiveRecordingData) ID() string {
+	return s.Name
+}
+
+// DialStatus is the status of a dial, as carried by the Dial events
+type DialStatus string
+
+const (
+	// DialRinging indicates that the dialed channel is ringing
+	DialRinging DialStatus = "RINGING"
+
+	// DialProgress indicates that the dialed channel reported progress
+	DialProgress DialStatus = "PROGRESS"
+
+	// DialProceeding indicates that the dialed channel is proceeding
+	DialProceeding DialStatus = "PROCEEDING"
+
+	// DialAnswer indicates that the dialed channel answered
+	DialAnswer DialStatus = "ANSWER"
+
+	// DialBusy indicates that the dialed channel was busy
+	DialBusy DialStatus = "BUSY"
+
+	// DialNoAnswer indicates that the dialed channel did not answer in time
+	DialNoAnswer DialStatus = "NOANSWER"
+
+	// DialChanUnavail indicates that the dialed channel could not be reached
+	DialChanUnavail DialStatus = "CHANUNAVAIL"
+
+	// DialCongestion indicates that the network was congested
+	DialCongestion DialStatus = "CONGESTION"
+
+	// DialCancel indicates that the dial was cancelled
+	DialCancel DialStatus = "CANCEL"
+)
+
+// Final reports whether the dial is over
+func (s DialStatus) Final() bool {
+	switch s {
+	case DialAnswer, DialBusy, DialNoAnswer, DialChanUnavail, DialCongestion, DialCancel:
+		return true
+	}
+
+	return false
+}
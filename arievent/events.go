@@ -1,6 +1,10 @@
 package arievent
 
-import "github.com/callevo/ari/channel"
+import (
+	"encoding/json"
+
+	"github.com/callevo/ari/key"
+)
 
 type Events interface {
 	GetType() string
@@ -23,11 +27,49 @@ type StasisEvent struct {
 	Application     string    `json:"application"`
 	TimeStamp       string    `json:"timestamp"`
 	Args            []string  `json:"args"`
-	Cause           int       `json:"cause,omitempty"`
-	Channel         channel.ChannelData
+	Cause           Cause     `json:"cause,omitempty"`
+	CauseText       string    `json:"cause_txt,omitempty"`
+	Channel         ChannelData
+	Bridge          *BridgeData        `json:"bridge,omitempty"`
+	Playback        *PlaybackData      `json:"playback,omitempty"`
+	Recording       *LiveRecordingData `json:"recording,omitempty"`
+	Endpoint        *EndpointData      `json:"endpoint,omitempty"`
+	DeviceState     *DeviceStateData   `json:"device_state,omitempty"`
+	Variable        string             `json:"variable,omitempty"`
+	Value           string             `json:"value,omitempty"`
+	Digit           string             `json:"digit,omitempty"`
+	DurationMs      int                `json:"duration_ms,omitempty"`
+	Peer            *ChannelData       `json:"peer,omitempty"`
+	Caller          *ChannelData       `json:"caller,omitempty"`
+	Forwarded       *ChannelData       `json:"forwarded,omitempty"`
+	Forward         string             `json:"forward,omitempty"`
+	DialString      string             `json:"dialstring,omitempty"`
+	DialStatus      DialStatus         `json:"dialstatus,omitempty"`
+	Destination     string             `json:"destination,omitempty"`
+	EventName       string             `json:"eventname,omitempty"`
+	Userevent       json.RawMessage    `json:"userevent,omitempty"`
 	stopPropagation bool
 }
 
+// EndpointData describes the endpoint carried by endpoint events
+type EndpointData struct {
+	Technology string   `json:"technology"`
+	Resource   string   `json:"resource"`
+	State      string   `json:"state,omitempty"`
+	ChannelIDs []string `json:"channel_ids"`
+}
+
+// ID returns the tech/resource identifier of the endpoint
+func (e *EndpointData) ID() string {
+	return e.Technology + "/" + e.Resource
+}
+
+// DeviceStateData describes the device carried by device state events
+type DeviceStateData struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
 func (evt *StasisEvent) GetType() EventType {
 	return evt.Type
 }
@@ -40,7 +82,65 @@ func (evt *StasisEvent) GetNode() string {
 	return evt.Node
 }
 
-func (evt *StasisEvent) StopPropagation(p bool) {
+// Matches reports whether the event relates to the entity identified by the
+// key.  A key without a Kind matches any entity carrying its ID, and a key
+// without a Node matches events from any node.
+func (evt *StasisEvent) Matches(k *key.Key) bool {
+	if k == nil {
+		return false
+	}
+
+	if k.Node != "" && evt.Node != "" && k.Node != evt.Node {
+		return false
+	}
+
+	switch k.Kind {
+	case key.ChannelKey:
+		return evt.Channel.ID == k.ID || (evt.Peer != nil && evt.Peer.ID == k.ID)
+	case key.BridgeKey:
+		return evt.Bridge != nil && evt.Bridge.ID == k.ID
+	case key.PlaybackKey:
+		return evt.Playback != nil && evt.Playback.ID == k.ID
+	case key.LiveRecordingKey:
+		return evt.Recording != nil && evt.Recording.Name == k.ID
+	case key.EndpointKey:
+		return evt.Endpoint != nil && evt.Endpoint.ID() == k.ID
+	case key.DeviceStateKey:
+		return evt.DeviceState != nil && evt.DeviceState.Name == k.ID
+	case "":
+		return (evt.Channel.ID != "" && evt.Channel.ID == k.ID) ||
+			(evt.Peer != nil && evt.Peer.ID == k.ID) ||
+			(evt.Bridge != nil && evt.Bridge.ID == k.ID) ||
+			(evt.Playback != nil && evt.Playback.ID == k.ID) ||
+			(evt.Recording != nil && evt.Recording.Name == k.ID)
+	}
+
+	return false
+}
+
+// DecodeUserevent decodes the user-defined fields of a ChannelUserevent into
+// v, such as a pointer to a struct or to a map
+func (evt *StasisEvent) DecodeUserevent(v interface{}) error {
+	if len(evt.Userevent) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(evt.Userevent, v)
+}
+
+// UsereventFields returns the user-defined fields of a ChannelUserevent
+func (evt *StasisEvent) UsereventFields() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if err := evt.DecodeUserevent(&fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// StopPropagation prevents the event from reaching the listeners with a lower
+// priority.  It is only honoured by a dispatcher in Ordered mode.
+func (evt *StasisEvent) StopPropagation() {
 	evt.stopPropagation = true
 }
 
@@ -90,6 +190,6 @@ var (
 	RecordingFinished        EventType = "RecordingFinished"
 	RecordingStarted         EventType = "RecordingStarted"
 	StasisEnd                EventType = "StasisEnd"
-	StasisStart              EventType = "StasisStar1t"
+	StasisStart              EventType = "StasisStart"
 	TextMessageReceived      EventType = "TextMessageReceived"
 )
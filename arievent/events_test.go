@@ -0,0 +1,41 @@
+package arievent
+
+import (
+	"testing"
+
+	"github.com/callevo/ari/key"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *StasisEvent
+		key   *key.Key
+		want  bool
+	}{
+		{"nil key", &StasisEvent{Channel: ChannelData{ID: "c1"}}, nil, false},
+		{"channel", &StasisEvent{Channel: ChannelData{ID: "c1"}}, key.NewKey(key.ChannelKey, "c1"), true},
+		{"other channel", &StasisEvent{Channel: ChannelData{ID: "c2"}}, key.NewKey(key.ChannelKey, "c1"), false},
+		{"peer channel", &StasisEvent{Channel: ChannelData{ID: "c2"}, Peer: &ChannelData{ID: "c1"}}, key.NewKey(key.ChannelKey, "c1"), true},
+		{"same node", &StasisEvent{Node: "ast1", Channel: ChannelData{ID: "c1"}}, key.NewKey(key.ChannelKey, "c1", key.WithNode("ast1")), true},
+		{"other node", &StasisEvent{Node: "ast2", Channel: ChannelData{ID: "c1"}}, key.NewKey(key.ChannelKey, "c1", key.WithNode("ast1")), false},
+		{"key without node", &StasisEvent{Node: "ast2", Channel: ChannelData{ID: "c1"}}, key.NewKey(key.ChannelKey, "c1"), true},
+		{"bridge", &StasisEvent{Bridge: &BridgeData{ID: "b1"}}, key.NewKey(key.BridgeKey, "b1"), true},
+		{"no bridge", &StasisEvent{Channel: ChannelData{ID: "b1"}}, key.NewKey(key.BridgeKey, "b1"), false},
+		{"playback", &StasisEvent{Playback: &PlaybackData{ID: "p1"}}, key.NewKey(key.PlaybackKey, "p1"), true},
+		{"live recording", &StasisEvent{Recording: &LiveRecordingData{Name: "r1"}}, key.NewKey(key.LiveRecordingKey, "r1"), true},
+		{"endpoint", &StasisEvent{Endpoint: &EndpointData{Technology: "PJSIP", Resource: "100"}}, key.NewKey(key.EndpointKey, "PJSIP/100"), true},
+		{"device state", &StasisEvent{DeviceState: &DeviceStateData{Name: "Custom:x"}}, key.NewKey(key.DeviceStateKey, "Custom:x"), true},
+		{"any kind channel", &StasisEvent{Channel: ChannelData{ID: "x"}}, key.NewKey("", "x"), true},
+		{"any kind bridge", &StasisEvent{Bridge: &BridgeData{ID: "x"}}, key.NewKey("", "x"), true},
+		{"any kind empty id", &StasisEvent{}, key.NewKey("", ""), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.Matches(tt.key); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package ari
 
 import (
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/arioptions"
 	"github.com/callevo/ari/bridge"
+	"github.com/callevo/ari/dispatcher"
 	"github.com/callevo/ari/key"
 	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/play"
+	"github.com/callevo/ari/recordings"
 	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/rid"
 )
 
 type ibridge struct {
@@ -101,6 +107,12 @@ func (b *ibridge) StopMOH(key *key.Key) error {
 }
 
 func (b *ibridge) Data(key *key.Key) (*bridge.BridgeData, error) {
+	if b.c.state != nil {
+		if d, ok := b.c.state.Bridge(key, b.c.stateMaxAge); ok {
+			return d, nil
+		}
+	}
+
 	resp, err := b.c.dataRequest(&requests.Request{
 		Kind: "BridgeData",
 		Key:  key,
@@ -140,3 +152,81 @@ func (b *ibridge) VideoSourceDelete(key *key.Key) error {
 		Key:  key,
 	})
 }
+
+func (b *ibridge) Play(ikey *key.Key, playbackID string, mediaURI string) (*play.PlaybackHandle, error) {
+	if playbackID == "" {
+		playbackID = rid.New(rid.Playback)
+	}
+
+	k, err := b.c.createRequest(&requests.Request{
+		Kind: "BridgePlay",
+		Key:  ikey,
+		BridgePlay: &requests.BridgePlay{
+			PlaybackID: playbackID,
+			MediaURI:   mediaURI,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return play.NewPlaybackHandle(k.New(key.PlaybackKey, playbackID), b.c.Playback(), nil), nil
+}
+
+func (b *ibridge) StagePlay(ikey *key.Key, playbackID string, mediaURI string) (*play.PlaybackHandle, error) {
+	if playbackID == "" {
+		playbackID = rid.New(rid.Playback)
+	}
+
+	k, err := b.c.createRequest(&requests.Request{
+		Kind: "BridgeStagePlay",
+		Key:  ikey,
+		BridgePlay: &requests.BridgePlay{
+			PlaybackID: playbackID,
+			MediaURI:   mediaURI,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return play.NewPlaybackHandle(k.New(key.PlaybackKey, playbackID), b.c.Playback(), func(h *play.PlaybackHandle) error {
+		_, err := b.Play(k.New(ikey.Kind, ikey.ID), playbackID, mediaURI)
+		return err
+	}), nil
+}
+
+func (b *ibridge) Record(ikey *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
+	rb, err := b.c.createRequest(&requests.Request{
+		Kind: "BridgeRecord",
+		Key:  ikey,
+		BridgeRecord: &requests.BridgeRecord{
+			Name:    name,
+			Options: opts,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recordings.NewLiveRecordingHandle(rb.New(key.LiveRecordingKey, name), b.c.LiveRecording(), nil), nil
+}
+
+func (b *ibridge) StageRecord(ikey *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
+	rb, err := b.c.createRequest(&requests.Request{
+		Kind: "BridgeStageRecord",
+		Key:  ikey,
+		BridgeRecord: &requests.BridgeRecord{
+			Name:    name,
+			Options: opts,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recordings.NewLiveRecordingHandle(rb.New(key.LiveRecordingKey, name), b.c.LiveRecording(), func(h *recordings.LiveRecordingHandle) error {
+		_, err := b.Record(rb.New(ikey.Kind, ikey.ID), name, opts)
+		return err
+	}), nil
+}
+
+func (b *ibridge) Subscribe(ikey *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return b.c.subscribeKey(ikey, l, types...)
+}
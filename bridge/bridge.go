@@ -1,6 +1,13 @@
 package bridge
 
-import "github.com/callevo/ari/key"
+import (
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/arioptions"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/play"
+	"github.com/callevo/ari/recordings"
+)
 
 // Bridge represents a communication path to an
 // Asterisk server for working with bridge resources
@@ -40,40 +47,33 @@ type Bridge interface {
 	StopMOH(key *key.Key) error
 
 	// Play plays the media URI to the bridge
-	//Play(key *key.Key, playbackID string, mediaURI string) (*PlaybackHandle, error)
+	Play(key *key.Key, playbackID string, mediaURI string) (*play.PlaybackHandle, error)
 
 	// StagePlay stages a `Play` operation and returns the `PlaybackHandle`
 	// for invoking it.
-	//StagePlay(key *key.Key, playbackID string, mediaURI string) (*PlaybackHandle, error)
+	StagePlay(key *key.Key, playbackID string, mediaURI string) (*play.PlaybackHandle, error)
 
 	// Record records the bridge
-	//Record(key *key.Key, name string, opts *RecordingOptions) (*LiveRecordingHandle, error)
+	Record(key *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error)
 
-	// StageRecord stages a `Record` operation and returns the `PlaybackHandle`
+	// StageRecord stages a `Record` operation and returns the `LiveRecordingHandle`
 	// for invoking it.
-	//StageRecord(key *key.Key, name string, opts *RecordingOptions) (*LiveRecordingHandle, error)
+	StageRecord(key *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error)
 
 	// VideoSource add Channel as Video-Source-ID at bridge
 	VideoSource(key *key.Key, channelID string) error
 
 	// VideoSourceDelete delete Video-Source-ID from bridge
 	VideoSourceDelete(key *key.Key) error
+
+	// Subscribe registers the listener for the events of the given types
+	// relating to the bridge, or for all of its events when no type is given
+	Subscribe(key *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription
 }
 
 // BridgeData describes an Asterisk Bridge, the entity which merges media from
 // one or more channels into a common audio output
-type BridgeData struct {
-	// Key is the cluster-unique identifier for this bridge
-	Key *key.Key `json:"key"`
-
-	ID         string   `json:"id"`           // Unique Id for this bridge
-	Class      string   `json:"bridge_class"` // Class of the bridge
-	Type       string   `json:"bridge_type"`  // Type of bridge (mixing, holding, dtmf_events, proxy_media)
-	ChannelIDs []string `json:"channels"`     // List of pariticipating channel ids
-	Creator    string   `json:"creator"`      // Creating entity of the bridge
-	Name       string   `json:"name"`         // The name of the bridge
-	Technology string   `json:"technology"`   // Name of the bridging technology
-}
+type BridgeData = arievent.BridgeData
 
 // BridgeAddChannelOptions describes additional options to be applied to a channel when it is joined to a bridge
 type BridgeAddChannelOptions struct {
@@ -121,15 +121,6 @@ func (bh *BridgeHandle) Exec() error {
 	return nil
 }
 
-// Channels returns the list of channels found in the bridge
-func (b *BridgeData) Channels() (list []*key.Key) {
-	for _, id := range b.ChannelIDs {
-		list = append(list, b.Key.New(key.ChannelKey, id))
-	}
-
-	return
-}
-
 // NewBridgeHandle creates a new bridge handle
 func NewBridgeHandle(key *key.Key, b Bridge, exec func(bh *BridgeHandle) error) *BridgeHandle {
 	return &BridgeHandle{
@@ -164,3 +155,33 @@ func (bh *BridgeHandle) Delete() (err error) {
 func (bh *BridgeHandle) Data() (*BridgeData, error) {
 	return bh.b.Data(bh.key)
 }
+
+// Subscribe registers the listener for the events of the given types relating
+// to the bridge, or for all of its events when no type is given.  Only
+// typed subscriptions honour StopPropagation in ordered mode.
+func (bh *BridgeHandle) Subscribe(l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return bh.b.Subscribe(bh.key, l, types...)
+}
+
+// Play initiates playback of the specified media uri
+// to the bridge, returning the Playback handle
+func (bh *BridgeHandle) Play(id string, mediaURI string) (*play.PlaybackHandle, error) {
+	return bh.b.Play(bh.key, id, mediaURI)
+}
+
+// StagePlay stages a `Play` operation; the playback starts when Exec is called
+// on the returned handle
+func (bh *BridgeHandle) StagePlay(id string, mediaURI string) (*play.PlaybackHandle, error) {
+	return bh.b.StagePlay(bh.key, id, mediaURI)
+}
+
+// Record records the bridge to the given filename
+func (bh *BridgeHandle) Record(name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
+	return bh.b.Record(bh.key, name, opts)
+}
+
+// StageRecord stages a `Record` operation; the recording starts when Exec is
+// called on the returned handle
+func (bh *BridgeHandle) StageRecord(name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
+	return bh.b.StageRecord(bh.key, name, opts)
+}
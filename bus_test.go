@@ -0,0 +1,200 @@
+package ari
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/messagebus"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+	"github.com/callevo/ari/subject"
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rotisserie/eris"
+)
+
+// fakeBus is an in-memory eventBus.  Requests are answered by respond, and
+// events are delivered with publish to the matching subscriptions.
+type fakeBus struct {
+	mu sync.Mutex
+
+	// subs are the dynamic subscriptions, by topic
+	subs map[string][]*fakeSub
+
+	// requests are the requests sent, in order
+	requests []*requests.Request
+
+	// respond answers the requests; requests succeed when it is nil
+	respond func(topic string, r *requests.Request) (*response.Response, error)
+
+	// requeued are the messages requeued
+	requeued []*nats.Msg
+
+	// queueSubs are the queue subscriptions, by topic
+	queueSubs map[string][]*fakeQueueSub
+}
+
+type fakeQueueSub struct {
+	queue    string
+	callback messagebus.MsgEventHandler
+}
+
+type fakeSub struct {
+	topic    string
+	callback messagebus.EventHandler
+}
+
+func newFakeBus() *fakeBus {
+	return &fakeBus{
+		subs:      make(map[string][]*fakeSub),
+		queueSubs: make(map[string][]*fakeQueueSub),
+	}
+}
+
+// newTestClient returns a client using a fake bus and an ordered dispatcher
+func newTestClient(bus *fakeBus) *ARIClient {
+	return &ARIClient{
+		Application:    "app",
+		ConnectionName: "ari",
+		sbus:           bus,
+		subjects:       subject.New("ari"),
+		instanceID:     "worker1",
+		_dispatcher:    dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered)),
+	}
+}
+
+func (b *fakeBus) Connect() error                 { return nil }
+func (b *fakeBus) Close()                         {}
+func (b *fakeBus) Connection() *nats.Conn         { return nil }
+func (b *fakeBus) JetStream() jetstream.JetStream { return nil }
+func (b *fakeBus) KeyValue() jetstream.KeyValue   { return nil }
+
+func (b *fakeBus) SubscribeAnnounce(topic string, callback messagebus.AnnounceHandler) (*nats.Subscription, error) {
+	return &nats.Subscription{}, nil
+}
+
+func (b *fakeBus) QueueSubscribeEvent(topic, queue string, callback messagebus.MsgEventHandler) (*nats.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queueSubs[topic] = append(b.queueSubs[topic], &fakeQueueSub{queue: queue, callback: callback})
+
+	return &nats.Subscription{}, nil
+}
+
+func (b *fakeBus) DynSubscription(topic string, callback messagebus.EventHandler) (*nats.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[topic] = append(b.subs[topic], &fakeSub{topic: topic, callback: callback})
+
+	return &nats.Subscription{Subject: topic + ".>"}, nil
+}
+
+func (b *fakeBus) Requeue(msg *nats.Msg, topic, instance string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out, count := messagebus.RequeueMsg(msg, topic, instance)
+
+	b.requeued = append(b.requeued, out)
+
+	return count, nil
+}
+
+func (b *fakeBus) RequestContext(ctx context.Context, topic string, r *requests.Request) (*response.Response, error) {
+	b.mu.Lock()
+	b.requests = append(b.requests, r)
+	respond := b.respond
+	b.mu.Unlock()
+
+	if respond == nil {
+		return &response.Response{}, nil
+	}
+
+	return respond(topic, r)
+}
+
+// publish delivers the event to the subscriptions whose topic matches the
+// resource topic the event is published on.  A "*" node in a subscription
+// topic matches any node.
+func (b *fakeBus) publish(resourceTopic string, e *arievent.StasisEvent) {
+	b.mu.Lock()
+	var matched []*fakeSub
+	for topic, subs := range b.subs {
+		if !topicMatches(topic, resourceTopic) {
+			continue
+		}
+
+		matched = append(matched, subs...)
+	}
+	b.mu.Unlock()
+
+	for _, s := range matched {
+		s.callback(e)
+	}
+}
+
+// deliver hands the message carrying the event to the first queue
+// subscription of the topic.  It reports whether the topic has one.
+func (b *fakeBus) deliver(topic string, e *arievent.StasisEvent, msg *nats.Msg) bool {
+	b.mu.Lock()
+	subs := b.queueSubs[topic]
+	b.mu.Unlock()
+
+	if len(subs) == 0 {
+		return false
+	}
+
+	subs[0].callback(e, msg)
+
+	return true
+}
+
+// subscriptions returns the number of subscriptions to the topic
+func (b *fakeBus) subscriptions(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subs[topic])
+}
+
+// sent returns the kinds of the requests sent, in order
+func (b *fakeBus) sent() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var kinds []string
+	for _, r := range b.requests {
+		kinds = append(kinds, r.Kind)
+	}
+
+	return kinds
+}
+
+func topicMatches(pattern, topic string) bool {
+	p := strings.Split(pattern, ".")
+	t := strings.Split(topic, ".")
+
+	if len(p) != len(t) {
+		return false
+	}
+
+	for i := range p {
+		if p[i] != "*" && p[i] != t[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// errorResponse returns a response carrying the error
+func errorResponse(msg string) *response.Response {
+	return &response.Response{Error: msg}
+}
+
+var errBus = eris.New("bus failure")
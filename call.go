@@ -0,0 +1,143 @@
+package ari
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/rotisserie/eris"
+)
+
+// ErrCallEnded is the cause of the cancellation of a call context when the
+// channel leaves the application
+var ErrCallEnded = eris.New("call left the application")
+
+// ErrCallHangup is the cause of the cancellation of a call context when the
+// channel is hung up
+var ErrCallHangup = eris.New("call hung up")
+
+// CallHandler handles a channel which entered the application.  The context
+// is cancelled when the channel hangs up or leaves the application;
+// context.Cause returns ErrCallHangup or ErrCallEnded accordingly.
+type CallHandler func(ctx context.Context, a *ARIClient, h *channel.ChannelHandle, e *arievent.StasisEvent)
+
+// PanicHandler is called with the value recovered from a panicking call
+// handler, along with the StasisStart event of the call and the stack trace
+type PanicHandler func(e *arievent.StasisEvent, recovered interface{}, stack []byte)
+
+// call tracks the lifecycle of a channel handed to a CallHandler
+type call struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu sync.Mutex
+
+	// lease is the share of the call in the subscription to the channel
+	lease *topicLease
+
+	// ended is set once the call no longer needs its subscription
+	ended bool
+}
+
+// setLease stores the lease of the call, releasing it at once if the call
+// already ended
+func (c *call) setLease(l *topicLease) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ended {
+		l.Release()
+		return
+	}
+
+	c.lease = l
+}
+
+// end cancels the context of the call and releases its subscription.  Only the
+// lease of this call is released, so that the subscription of a later call of
+// the same channel, once it enters the application again, is left alone.
+func (c *call) end(cause error) {
+	c.cancel(cause)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ended = true
+	c.lease.Release()
+}
+
+// startCall subscribes to the events of the channel which just entered the
+// application, then runs the handler.  When cleanup is set, the subscription
+// is dropped as soon as the handler returns.  release is called once the call
+// ends.
+func (a *ARIClient) startCall(ctx context.Context, o *arievent.StasisEvent, handler CallHandler, cleanup bool, release func()) {
+	a._dispatcher.Dispatch(o)
+
+	k := key.NewKey(key.ChannelKey, o.Channel.GetID(), key.WithApp(o.Application), key.WithNode(o.Node))
+
+	h := channel.NewChannelHandle(k, &ichannel{c: a}, nil)
+
+	c := &call{}
+	c.ctx, c.cancel = context.WithCancelCause(ctx)
+
+	if release != nil {
+		context.AfterFunc(c.ctx, release)
+	}
+
+	// Subscribe before running the handler, so that it cannot miss the first
+	// events of the channel
+	lease, err := a.acquireTopic(a.resourceTopic(o.Application, o.Node, o.Channel.ID), func(o *arievent.StasisEvent) {
+		switch o.GetType() {
+		case arievent.ChannelHangupRequest:
+			c.cancel(ErrCallHangup)
+		case arievent.StasisEnd, arievent.ChannelDestroyed:
+			logs.TLogger.Debug().Msgf("call of channel %s finished, dropping its subscription", o.Channel.ID)
+			c.end(ErrCallEnded)
+		}
+	})
+	if err != nil {
+		logs.TLogger.Debug().Msgf("failed to subscribe to the call of channel %s: %s", o.Channel.ID, err)
+		c.cancel(err)
+
+		return
+	}
+
+	c.setLease(lease)
+
+	if handler == nil {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if cleanup {
+				c.end(ErrCallEnded)
+			}
+		}()
+
+		defer a.recoverCall(o)
+
+		handler(c.ctx, a, h, o)
+	}()
+}
+
+// recoverCall recovers a panicking call handler and reports the panic
+func (a *ARIClient) recoverCall(o *arievent.StasisEvent) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	stack := debug.Stack()
+
+	if a.panicHandler != nil {
+		a.panicHandler(o, r, stack)
+		return
+	}
+
+	logs.TLogger.Error().Msgf("call handler for channel %s panicked: %v\n%s", o.Channel.ID, r, stack)
+}
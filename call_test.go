@@ -0,0 +1,136 @@
+package ari
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+)
+
+func stasisStart(id string) *arievent.StasisEvent {
+	return &arievent.StasisEvent{Type: arievent.StasisStart, Application: "app", Node: "ast1", Channel: arievent.ChannelData{ID: id}}
+}
+
+func TestCallContextCause(t *testing.T) {
+	tests := []struct {
+		name  string
+		event arievent.EventType
+		want  error
+	}{
+		{"hangup request", arievent.ChannelHangupRequest, ErrCallHangup},
+		{"left the application", arievent.StasisEnd, ErrCallEnded},
+		{"destroyed", arievent.ChannelDestroyed, ErrCallEnded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+
+			causes := make(chan error, 1)
+			released := make(chan struct{})
+
+			a.startCall(context.Background(), stasisStart("c1"), func(ctx context.Context, _ *ARIClient, _ *channel.ChannelHandle, _ *arievent.StasisEvent) {
+				<-ctx.Done()
+				causes <- context.Cause(ctx)
+			}, true, func() { close(released) })
+
+			bus.publish(a.resourceTopic("app", "ast1", "c1"), &arievent.StasisEvent{Type: tt.event, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}})
+
+			select {
+			case err := <-causes:
+				if err != tt.want {
+					t.Errorf("cause = %v, want %v", err, tt.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("call context not cancelled")
+			}
+
+			select {
+			case <-released:
+			case <-time.After(time.Second):
+				t.Fatal("release not called")
+			}
+		})
+	}
+}
+
+func TestCallReentryKeepsSubscription(t *testing.T) {
+	bus := newFakeBus()
+	a := newTestClient(bus)
+	topic := a.resourceTopic("app", "ast1", "c1")
+
+	proceed := make(chan struct{})
+	finished := make(chan struct{})
+
+	a.startCall(context.Background(), stasisStart("c1"), func(ctx context.Context, _ *ARIClient, _ *channel.ChannelHandle, _ *arievent.StasisEvent) {
+		defer close(finished)
+		<-proceed
+	}, true, nil)
+
+	// The channel leaves the application, then enters it again while the
+	// handler of its first call is still running
+	bus.publish(topic, &arievent.StasisEvent{Type: arievent.StasisEnd, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}})
+
+	second := make(chan context.Context, 1)
+	a.startCall(context.Background(), stasisStart("c1"), func(ctx context.Context, _ *ARIClient, _ *channel.ChannelHandle, _ *arievent.StasisEvent) {
+		second <- ctx
+		<-ctx.Done()
+	}, true, nil)
+
+	ctx := <-second
+
+	close(proceed)
+	<-finished
+
+	if n := a.topicUsers(topic); n != 1 {
+		t.Fatalf("%d subscribers after the first call ended, want 1", n)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("second call cancelled by the first")
+	}
+
+	bus.publish(topic, &arievent.StasisEvent{Type: arievent.StasisEnd, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}})
+
+	<-ctx.Done()
+	if n := a.topicUsers(topic); n != 0 {
+		t.Errorf("%d subscribers after the second call ended, want 0", n)
+	}
+}
+
+func TestCallPanic(t *testing.T) {
+	bus := newFakeBus()
+	a := newTestClient(bus)
+
+	recovered := make(chan interface{}, 1)
+	a.panicHandler = func(e *arievent.StasisEvent, r interface{}, stack []byte) {
+		if e.Channel.ID != "c1" || len(stack) == 0 {
+			t.Errorf("event %s, %d bytes of stack", e.Channel.ID, len(stack))
+		}
+		recovered <- r
+	}
+
+	a.startCall(context.Background(), stasisStart("c1"), func(context.Context, *ARIClient, *channel.ChannelHandle, *arievent.StasisEvent) {
+		panic("boom")
+	}, true, nil)
+
+	select {
+	case r := <-recovered:
+		if r != "boom" {
+			t.Errorf("recovered %v, want boom", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic not reported")
+	}
+
+	// The subscription is dropped once the handler returns, even by panicking
+	deadline := time.Now().Add(time.Second)
+	for a.topicUsers(a.resourceTopic("app", "ast1", "c1")) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription kept after the panic")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
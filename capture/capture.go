@@ -0,0 +1,223 @@
+// Package capture records the events seen by a client and replays them
+package capture
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/logs"
+	"github.com/rotisserie/eris"
+)
+
+// Entry is a single captured event
+type Entry struct {
+	// Received is the time at which the event was received by the client
+	Received time.Time `json:"received"`
+
+	// Event is the captured event
+	Event *arievent.StasisEvent `json:"event"`
+}
+
+// Writer is the destination of a capture
+type Writer interface {
+	// Write stores the given entry
+	Write(e *Entry) error
+
+	// Close flushes and releases the destination
+	Close() error
+}
+
+// DefaultBuffer is the number of events a Recorder holds while its Writer is
+// busy
+var DefaultBuffer = 4096
+
+// Recorder writes every event dispatched by an EventDispatcher to a Writer
+type Recorder struct {
+	w Writer
+
+	sub *dispatcher.Subscription
+
+	// queue holds the entries of the attached dispatcher until the writer
+	// goroutine writes them
+	queue chan *Entry
+	done  chan struct{}
+
+	// dropped counts the entries lost because the queue was full
+	dropped atomic.Uint64
+
+	// err is the first error returned by the Writer
+	err error
+
+	closed bool
+
+	mu sync.Mutex
+
+	// wmu serializes the calls to the Writer
+	wmu sync.Mutex
+}
+
+// OptionFunc is a function which applies changes to a Recorder
+type OptionFunc func(r *Recorder)
+
+// Buffer sets the number of events the Recorder holds while its Writer is
+// busy.  Defaults to DefaultBuffer.
+func Buffer(n int) OptionFunc {
+	return func(r *Recorder) {
+		if n > 0 {
+			r.queue = make(chan *Entry, n)
+		}
+	}
+}
+
+// NewRecorder returns a Recorder writing to the given Writer
+func NewRecorder(w Writer, opts ...OptionFunc) *Recorder {
+	r := &Recorder{
+		w:    w,
+		done: make(chan struct{}),
+	}
+
+	for _, f := range opts {
+		f(r)
+	}
+
+	if r.queue == nil {
+		r.queue = make(chan *Entry, DefaultBuffer)
+	}
+
+	go r.run()
+
+	return r
+}
+
+// run writes the queued entries until the queue is closed
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	for entry := range r.queue {
+		if err := r.write(entry); err != nil {
+			logs.TLogger.Debug().Msgf("failed to capture event %s: %s", entry.Event.GetType(), err)
+		}
+	}
+}
+
+// Attach starts recording the events dispatched by the given dispatcher.  The
+// recorder is registered as a tap, so it sees every event in the order it was
+// received, before any listener can stop its propagation.  As taps must not
+// block, the events are queued and written by a goroutine of the recorder;
+// the events arriving while the queue is full are dropped and counted, see
+// Dropped.  Each event is deep copied before it is queued, as the listeners
+// are free to change it while it waits to be written.
+func (r *Recorder) Attach(d *dispatcher.EventDispatcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	r.sub.Cancel()
+	r.sub = d.Tap(func(e *arievent.StasisEvent) {
+		received := time.Now()
+
+		c, err := clone(e)
+		if err != nil {
+			logs.TLogger.Debug().Msgf("failed to capture event %s: %s", e.GetType(), err)
+			return
+		}
+
+		entry := &Entry{
+			Received: received,
+			Event:    c,
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.closed {
+			return
+		}
+
+		select {
+		case r.queue <- entry:
+		default:
+			r.dropped.Add(1)
+		}
+	})
+}
+
+// Record writes the given event to the capture, stamped with the current
+// time.  Unlike the events of the attached dispatcher, the event is written
+// before Record returns.
+func (r *Recorder) Record(e *arievent.StasisEvent) error {
+	r.mu.Lock()
+	closed := r.closed
+	r.mu.Unlock()
+
+	if closed {
+		return eris.New("recorder is closed")
+	}
+
+	return r.write(&Entry{
+		Received: time.Now(),
+		Event:    e,
+	})
+}
+
+func (r *Recorder) write(entry *Entry) error {
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+
+	if err := r.w.Write(entry); err != nil {
+		r.mu.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Err returns the first error encountered while writing the capture
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Dropped returns the number of events of the attached dispatcher which were
+// not captured because the Writer could not keep up
+func (r *Recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Close detaches the recorder from its dispatcher, writes the queued events
+// and closes the Writer
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+
+	r.closed = true
+
+	r.sub.Cancel()
+	close(r.queue)
+
+	r.mu.Unlock()
+
+	<-r.done
+
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+
+	return r.w.Close()
+}
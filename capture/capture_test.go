@@ -0,0 +1,274 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/rotisserie/eris"
+)
+
+// memWriter keeps the capture in memory
+type memWriter struct {
+	entries []*Entry
+	closed  bool
+
+	// block, when set, holds every Write until it is closed
+	block chan struct{}
+
+	mu sync.Mutex
+}
+
+func (m *memWriter) Write(e *Entry) error {
+	if m.block != nil {
+		<-m.block
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = append(m.entries, e)
+
+	return nil
+}
+
+func (m *memWriter) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+
+	return nil
+}
+
+func (m *memWriter) types() []arievent.EventType {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ret []arievent.EventType
+	for _, e := range m.entries {
+		ret = append(ret, e.Event.GetType())
+	}
+
+	return ret
+}
+
+func TestRecorderAttach(t *testing.T) {
+	w := &memWriter{}
+	r := NewRecorder(w)
+
+	d := dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered))
+	r.Attach(d)
+
+	want := []arievent.EventType{arievent.StasisStart, arievent.ChannelDtmfReceived, arievent.StasisEnd}
+	for _, typ := range want {
+		d.Dispatch(&arievent.StasisEvent{Type: typ})
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	// Events dispatched after Close are not captured
+	d.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelDestroyed})
+
+	if got := w.types(); !reflect.DeepEqual(got, want) {
+		t.Errorf("captured %v, want %v", got, want)
+	}
+	if !w.closed {
+		t.Error("writer not closed")
+	}
+	if err := r.Record(&arievent.StasisEvent{Type: arievent.StasisStart}); err == nil {
+		t.Error("Record after Close succeeded")
+	}
+}
+
+func TestRecorderDoesNotBlockDispatch(t *testing.T) {
+	w := &memWriter{block: make(chan struct{})}
+	r := NewRecorder(w, Buffer(2))
+
+	d := dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered))
+	r.Attach(d)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			d.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelVarset})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked on the writer")
+	}
+
+	close(w.block)
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	captured := uint64(len(w.types()))
+	if captured+r.Dropped() != 10 {
+		t.Errorf("captured %d and dropped %d events, want 10 in total", captured, r.Dropped())
+	}
+	if r.Dropped() == 0 {
+		t.Error("no event dropped with a blocked writer")
+	}
+}
+
+func TestRecorderCopiesEvents(t *testing.T) {
+	w := &memWriter{block: make(chan struct{})}
+	r := NewRecorder(w)
+
+	d := dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered))
+	r.Attach(d)
+
+	d.AddListener(arievent.ChannelDtmfReceived, func(e *arievent.StasisEvent) {
+		e.Digit = "#"
+		e.Channel.ID = "changed"
+		e.Args[0] = "changed"
+	})
+
+	d.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelDtmfReceived, Digit: "1", Channel: arievent.ChannelData{ID: "c1"}, Args: []string{"a"}})
+
+	close(w.block)
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	if len(w.entries) != 1 {
+		t.Fatalf("captured %d events, want 1", len(w.entries))
+	}
+	if e := w.entries[0].Event; e.Digit != "1" || e.Channel.ID != "c1" || e.Args[0] != "a" {
+		t.Errorf("captured the event as changed by a listener: %+v", e)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(e *Entry) error { return eris.New("disk full") }
+func (failingWriter) Close() error         { return nil }
+
+func TestRecorderErr(t *testing.T) {
+	r := NewRecorder(failingWriter{})
+	defer r.Close()
+
+	if err := r.Record(&arievent.StasisEvent{Type: arievent.StasisStart}); err == nil {
+		t.Fatal("Record succeeded")
+	}
+	if r.Err() == nil {
+		t.Error("Err is nil after a failed write")
+	}
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := NewRecorder(NewJSONLWriter(&buf))
+
+	events := []*arievent.StasisEvent{
+		{Type: arievent.StasisStart, Node: "ast1", Args: []string{"a", "b"}, Channel: arievent.ChannelData{ID: "c1"}},
+		{Type: arievent.BridgeCreated, Bridge: &arievent.BridgeData{ID: "b1", ChannelIDs: []string{"c1"}}},
+		{Type: arievent.ChannelDestroyed, Cause: arievent.CauseUserBusy, Channel: arievent.ChannelData{ID: "c1"}},
+	}
+
+	for _, e := range events {
+		if err := r.Record(e); err != nil {
+			t.Fatalf("Record: %s", err)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+
+	entries, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+
+	if len(entries) != len(events) {
+		t.Fatalf("decoded %d entries, want %d", len(entries), len(events))
+	}
+
+	for i, e := range entries {
+		if !reflect.DeepEqual(e.Event, events[i]) {
+			t.Errorf("entry %d: got %+v, want %+v", i, e.Event, events[i])
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Now()
+
+	entries := []*Entry{
+		{Received: start, Event: &arievent.StasisEvent{Type: arievent.StasisStart, Channel: arievent.ChannelData{ID: "c1"}}},
+		nil,
+		{Received: start.Add(20 * time.Millisecond), Event: &arievent.StasisEvent{Type: arievent.BridgeCreated, Bridge: &arievent.BridgeData{ID: "b1"}}},
+		{Received: start.Add(40 * time.Millisecond), Event: &arievent.StasisEvent{Type: arievent.StasisEnd, Channel: arievent.ChannelData{ID: "c1"}}},
+	}
+
+	tests := []struct {
+		name    string
+		opts    []ReplayOptionFunc
+		minTime time.Duration
+	}{
+		{"as fast as possible", nil, 0},
+		{"real time", []ReplayOptionFunc{RealTime()}, 40 * time.Millisecond},
+		{"twice as fast", []ReplayOptionFunc{Speed(2)}, 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []arievent.EventType
+
+			began := time.Now()
+			err := Replay(context.Background(), entries, TargetFunc(func(e *arievent.StasisEvent) {
+				got = append(got, e.GetType())
+
+				// Listeners altering the events must not alter the capture
+				e.Channel.ID = "altered"
+				if e.Bridge != nil {
+					e.Bridge.ID = "altered"
+				}
+			}), tt.opts...)
+			if err != nil {
+				t.Fatalf("Replay: %s", err)
+			}
+
+			want := []arievent.EventType{arievent.StasisStart, arievent.BridgeCreated, arievent.StasisEnd}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+			if elapsed := time.Since(began); elapsed < tt.minTime {
+				t.Errorf("replayed in %s, want at least %s", elapsed, tt.minTime)
+			}
+			if entries[0].Event.Channel.ID != "c1" || entries[2].Event.Bridge.ID != "b1" {
+				t.Error("replay altered the capture")
+			}
+		})
+	}
+}
+
+func TestReplayCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	entries := []*Entry{{Received: time.Now(), Event: &arievent.StasisEvent{Type: arievent.StasisStart}}}
+
+	err := Replay(ctx, entries, TargetFunc(func(e *arievent.StasisEvent) {
+		t.Error("event replayed after cancellation")
+	}))
+	if err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
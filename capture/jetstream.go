@@ -0,0 +1,114 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rotisserie/eris"
+)
+
+// PublishTimeout bounds the publication of a capture entry to JetStream
+var PublishTimeout = 5 * time.Second
+
+// FetchBatch is the number of entries fetched at once when reading a capture
+// from a JetStream stream
+var FetchBatch = 256
+
+// jetStreamWriter publishes a capture to a JetStream subject
+type jetStreamWriter struct {
+	js      jetstream.JetStream
+	subject string
+}
+
+// NewJetStreamWriter returns a Writer which publishes each Entry, as JSON, to
+// the given subject.  The subject must be bound to a stream; see CreateStream.
+func NewJetStreamWriter(js jetstream.JetStream, subject string) Writer {
+	return &jetStreamWriter{
+		js:      js,
+		subject: subject,
+	}
+}
+
+// CreateStream creates (or updates) a stream storing the captures published to
+// the given subjects
+func CreateStream(ctx context.Context, js jetstream.JetStream, name string, subjects ...string) (jetstream.Stream, error) {
+	s, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     name,
+		Subjects: subjects,
+	})
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to create capture stream %s", name)
+	}
+
+	return s, nil
+}
+
+func (j *jetStreamWriter) Write(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), PublishTimeout)
+	defer cancel()
+
+	_, err = j.js.Publish(ctx, j.subject, b)
+
+	return err
+}
+
+func (j *jetStreamWriter) Close() error {
+	return nil
+}
+
+// ReadStream reads every capture entry stored in the given stream, optionally
+// restricted to a subject
+func ReadStream(ctx context.Context, js jetstream.JetStream, stream, subject string) ([]*Entry, error) {
+	s, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to find capture stream %s", stream)
+	}
+
+	cfg := jetstream.OrderedConsumerConfig{}
+	if subject != "" {
+		cfg.FilterSubjects = []string{subject}
+	}
+
+	cons, err := s.OrderedConsumer(ctx, cfg)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to create capture consumer")
+	}
+
+	var list []*Entry
+
+	for {
+		info, err := cons.Info(ctx)
+		if err != nil {
+			return list, eris.Wrap(err, "failed to read capture consumer state")
+		}
+
+		if info.NumPending == 0 {
+			return list, nil
+		}
+
+		batch, err := cons.Fetch(FetchBatch, jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			return list, eris.Wrap(err, "failed to fetch capture entries")
+		}
+
+		for msg := range batch.Messages() {
+			e := new(Entry)
+			if err := json.Unmarshal(msg.Data(), e); err != nil {
+				return list, eris.Wrapf(err, "failed to decode capture entry %d", len(list)+1)
+			}
+
+			list = append(list, e)
+		}
+
+		if err := batch.Error(); err != nil {
+			return list, eris.Wrap(err, "failed to fetch capture entries")
+		}
+	}
+}
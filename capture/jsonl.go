@@ -0,0 +1,97 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/rotisserie/eris"
+)
+
+// jsonlWriter writes a capture as JSON lines, one Entry per line
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+	c   io.Closer
+}
+
+// NewJSONLWriter returns a Writer which encodes each Entry as a line of JSON.
+// If w is an io.Closer, it is closed along with the Writer.
+func NewJSONLWriter(w io.Writer) Writer {
+	bw := bufio.NewWriter(w)
+
+	jw := &jsonlWriter{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+
+	if c, ok := w.(io.Closer); ok {
+		jw.c = c
+	}
+
+	return jw
+}
+
+// CreateFile creates (or truncates) the named file and returns a JSONL Writer
+// for it
+func CreateFile(name string) (Writer, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to create capture file %s", name)
+	}
+
+	return NewJSONLWriter(f), nil
+}
+
+func (j *jsonlWriter) Write(e *Entry) error {
+	if err := j.enc.Encode(e); err != nil {
+		return err
+	}
+
+	// Flush every entry, so that a crash loses as little of the capture as possible
+	return j.w.Flush()
+}
+
+func (j *jsonlWriter) Close() error {
+	err := j.w.Flush()
+
+	if j.c != nil {
+		if cerr := j.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// Decode reads a JSONL capture
+func Decode(r io.Reader) ([]*Entry, error) {
+	var list []*Entry
+
+	dec := json.NewDecoder(r)
+	for {
+		e := new(Entry)
+
+		err := dec.Decode(e)
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return list, eris.Wrapf(err, "failed to decode capture entry %d", len(list)+1)
+		}
+
+		list = append(list, e)
+	}
+}
+
+// ReadFile reads the JSONL capture stored in the named file
+func ReadFile(name string) ([]*Entry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to open capture file %s", name)
+	}
+	defer f.Close()
+
+	return Decode(f)
+}
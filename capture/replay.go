@@ -0,0 +1,124 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/rotisserie/eris"
+)
+
+// Target is the receiver of replayed events.  *dispatcher.EventDispatcher is a
+// Target.
+type Target interface {
+	Dispatch(e *arievent.StasisEvent) *arievent.StasisEvent
+}
+
+// TargetFunc adapts a function, such as a fake transport, into a Target
+type TargetFunc func(e *arievent.StasisEvent)
+
+// Dispatch calls f(e)
+func (f TargetFunc) Dispatch(e *arievent.StasisEvent) *arievent.StasisEvent {
+	f(e)
+	return e
+}
+
+// ReplayOptions describes how a capture is replayed
+type ReplayOptions struct {
+	// speed is the replay speed relative to the capture; zero replays as fast
+	// as possible
+	speed float64
+}
+
+// ReplayOptionFunc is a function which applies changes to a ReplayOptions set
+type ReplayOptionFunc func(*ReplayOptions)
+
+// RealTime replays the events with the same spacing as they were received
+func RealTime() ReplayOptionFunc {
+	return Speed(1)
+}
+
+// Speed replays the events with their original spacing divided by the given
+// factor; Speed(2) replays twice as fast as real time.
+func Speed(factor float64) ReplayOptionFunc {
+	return func(o *ReplayOptions) {
+		o.speed = factor
+	}
+}
+
+// AsFastAsPossible replays the events back to back.  This is the default.
+func AsFastAsPossible() ReplayOptionFunc {
+	return Speed(0)
+}
+
+// Replay feeds the captured events to the target, in capture order, and
+// returns once every event has been dispatched or the context is cancelled.
+// Each event is deep copied before it is dispatched, so the same capture may be
+// replayed any number of times with identical results, whatever the listeners
+// do to the events.
+func Replay(ctx context.Context, entries []*Entry, target Target, opts ...ReplayOptionFunc) error {
+	o := new(ReplayOptions)
+	for _, f := range opts {
+		f(o)
+	}
+
+	start := time.Now()
+
+	var first time.Time
+	for _, entry := range entries {
+		if entry != nil && entry.Event != nil {
+			first = entry.Received
+			break
+		}
+	}
+
+	for _, entry := range entries {
+		if entry == nil || entry.Event == nil {
+			continue
+		}
+
+		if o.speed > 0 {
+			offset := entry.Received.Sub(first)
+			wait := time.Until(start.Add(time.Duration(float64(offset) / o.speed)))
+
+			if wait > 0 {
+				t := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return ctx.Err()
+				case <-t.C:
+				}
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		e, err := clone(entry.Event)
+		if err != nil {
+			return err
+		}
+
+		target.Dispatch(e)
+	}
+
+	return nil
+}
+
+// clone deep copies the event through its JSON encoding, as it was captured
+func clone(e *arievent.StasisEvent) (*arievent.StasisEvent, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to copy event %s", e.GetType())
+	}
+
+	ret := new(arievent.StasisEvent)
+	if err := json.Unmarshal(b, ret); err != nil {
+		return nil, eris.Wrapf(err, "failed to copy event %s", e.GetType())
+	}
+
+	return ret, nil
+}
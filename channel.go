@@ -1,18 +1,28 @@
 package ari
 
 import (
+	"context"
+	"strings"
 	"time"
 
+	"github.com/callevo/ari/arievent"
 	"github.com/callevo/ari/arioptions"
 	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/dispatcher"
 	"github.com/callevo/ari/key"
 	"github.com/callevo/ari/logs"
 	"github.com/callevo/ari/play"
 	"github.com/callevo/ari/recordings"
 	"github.com/callevo/ari/requests"
 	"github.com/callevo/ari/rid"
+	"github.com/rotisserie/eris"
 )
 
+// MoveConfirmTimeout is the amount of time Move waits for Asterisk to report
+// the outcome of the move.  Without report, Move returns
+// channel.ErrMoveUnconfirmed.
+var MoveConfirmTimeout = 3 * time.Second
+
 type ichannel struct {
 	c *ARIClient
 }
@@ -85,17 +95,27 @@ func (c *ichannel) Get(key *key.Key) *channel.ChannelHandle {
 	return channel.NewChannelHandle(k, c, nil)
 }
 
-func (c *ichannel) Hangup(key *key.Key, reason string) error {
+func (c *ichannel) Hangup(key *key.Key, reason channel.HangupReason) error {
+	if !reason.Valid() {
+		return eris.Errorf("invalid hangup reason %q", reason)
+	}
+
 	return c.c.commandRequest((&requests.Request{
 		Kind: "ChannelHangup",
 		Key:  key,
 		ChannelHangup: &requests.ChannelHangup{
-			Reason: reason,
+			Reason: string(reason),
 		},
 	}))
 }
 
 func (c *ichannel) Data(key *key.Key) (*channel.ChannelData, error) {
+	if c.c.state != nil {
+		if d, ok := c.c.state.Channel(key, c.c.stateMaxAge); ok {
+			return d, nil
+		}
+	}
+
 	data, err := c.c.dataRequest(&requests.Request{
 		Kind: "ChannelData",
 		Key:  key,
@@ -118,6 +138,85 @@ func (c *ichannel) Continue(key *key.Key, context string, extension string, prio
 	})
 }
 
+// Move moves the channel and waits for the outcome.  The channel leaving the
+// application (StasisEnd) confirms the move, unless it hung up first
+// (ChannelHangupRequest or ChannelDestroyed).  A hangup Asterisk reports only
+// after StasisEnd cannot be told from a move, and counts as one.  The client
+// follows the events of the channel during the move, so that it also confirms
+// the move of a channel it neither handles nor originated.
+func (c *ichannel) Move(k *key.Key, app string, args ...string) error {
+	result := make(chan error, 1)
+
+	source, node := k.GetApp(), k.GetNode()
+	if source == "" {
+		source = c.c.Application
+	}
+	if node == "" {
+		node = "*"
+	}
+
+	lease, err := c.c.acquireTopic(c.c.resourceTopic(source, node, k.GetID()), nil)
+	if err != nil {
+		return err
+	}
+	defer lease.Release()
+
+	// Subscribe before moving, so that the outcome is not missed.  The first
+	// event decides.
+	sub := c.c.subscribeKey(k, func(e *arievent.StasisEvent) {
+		var err error
+		switch e.GetType() {
+		case arievent.ApplicationMoveFailed:
+			err = &channel.MoveFailedError{
+				ChannelID:   k.ID,
+				Destination: e.Destination,
+				Args:        e.Args,
+			}
+		case arievent.ChannelHangupRequest, arievent.ChannelDestroyed:
+			err = channel.ErrMoveHangup
+		}
+
+		select {
+		case result <- err:
+		default:
+		}
+	}, arievent.ApplicationMoveFailed, arievent.ChannelHangupRequest, arievent.ChannelDestroyed, arievent.StasisEnd)
+	defer sub.Cancel()
+
+	err = c.c.commandRequest(&requests.Request{
+		Kind: "ChannelMove",
+		Key:  k,
+		ChannelMove: &requests.ChannelMove{
+			App:     app,
+			AppArgs: strings.Join(args, ","),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(MoveConfirmTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		logs.TLogger.Debug().Msgf("move of channel %s to %s not confirmed", k.ID, app)
+		return channel.ErrMoveUnconfirmed
+	}
+}
+
+func (c *ichannel) Redirect(key *key.Key, endpoint string) error {
+	return c.c.commandRequest(&requests.Request{
+		Kind: "ChannelRedirect",
+		Key:  key,
+		ChannelRedirect: &requests.ChannelRedirect{
+			Endpoint: endpoint,
+		},
+	})
+}
+
 func (c *ichannel) Dial(key *key.Key, caller string, timeout time.Duration) error {
 	return c.c.commandRequest(&requests.Request{
 		Kind: "ChannelDial",
@@ -168,6 +267,21 @@ func (c *ichannel) SendDTMF(key *key.Key, dtmf string, opts *arioptions.DTMFOpti
 	})
 }
 
+func (c *ichannel) UserEvent(key *key.Key, name string, fields map[string]interface{}) error {
+	if name == "" {
+		return eris.New("user event without name")
+	}
+
+	return c.c.commandRequest(&requests.Request{
+		Kind: "ChannelUserevent",
+		Key:  key,
+		ChannelUserevent: &requests.ChannelUserevent{
+			EventName: name,
+			Variables: fields,
+		},
+	})
+}
+
 func (c *ichannel) Snoop(ikey *key.Key, snoopID string, opts *arioptions.SnoopOptions) (*channel.ChannelHandle, error) {
 
 	if opts.App == "" {
@@ -253,7 +367,52 @@ func (c *ichannel) StopSilence(key *key.Key) error {
 }
 
 func (c *ichannel) Originate(referenceKey *key.Key, o requests.OriginateRequest) (*channel.ChannelHandle, error) {
-	k, err := c.c.createRequest(&requests.Request{
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	h, p, err := c.originate(ctx, referenceKey, o)
+	if err != nil && p != nil {
+		c.c.untrackOriginate(p)
+	}
+
+	return h, err
+}
+
+// originate originates the channel.  When it is originated into an
+// application of the client, the returned pendingOriginate tracks it, even if
+// the request failed: the caller decides whether to untrack it.
+func (c *ichannel) originate(ctx context.Context, referenceKey *key.Key, o requests.OriginateRequest) (*channel.ChannelHandle, *pendingOriginate, error) {
+	if o.App == "" || !c.c.HandlesApplication(o.App) {
+		k, err := c.c.createRequestContext(ctx, &requests.Request{
+			Kind: "ChannelOriginate",
+			Key:  referenceKey,
+			ChannelOriginate: &requests.ChannelOriginate{
+				OriginateRequest: o,
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return channel.NewChannelHandle(k, c, nil), nil, nil
+	}
+
+	// The channel is originated into our own application: make sure it has
+	// a known ID, so that its StasisStart can be routed back to us
+	if o.ChannelID == "" {
+		o.ChannelID = rid.New(rid.Channel)
+	}
+
+	ids := []string{o.ChannelID}
+	if o.OtherChannelID != "" {
+		ids = append(ids, o.OtherChannelID)
+	}
+
+	p, err := c.c.trackOriginate(o.App, referenceKey.GetNode(), ids...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k, err := c.c.createRequestContext(ctx, &requests.Request{
 		Kind: "ChannelOriginate",
 		Key:  referenceKey,
 		ChannelOriginate: &requests.ChannelOriginate{
@@ -261,9 +420,9 @@ func (c *ichannel) Originate(referenceKey *key.Key, o requests.OriginateRequest)
 		},
 	})
 	if err != nil {
-		return nil, err
+		return nil, p, err
 	}
-	return channel.NewChannelHandle(k, c, nil), nil
+	return channel.NewOriginatedChannelHandle(k, c, nil, p.started), p, nil
 }
 
 func (c *ichannel) Play(ikey *key.Key, playbackID string, mediaURI string) (*play.PlaybackHandle, error) {
@@ -285,6 +444,28 @@ func (c *ichannel) Play(ikey *key.Key, playbackID string, mediaURI string) (*pla
 	return play.NewPlaybackHandle(k.New(key.PlaybackKey, playbackID), c.c.Playback(), nil), nil
 }
 
+func (c *ichannel) StagePlay(ikey *key.Key, playbackID string, mediaURI string) (*play.PlaybackHandle, error) {
+	if playbackID == "" {
+		playbackID = rid.New(rid.Playback)
+	}
+
+	k, err := c.c.createRequest(&requests.Request{
+		Kind: "ChannelStagePlay",
+		Key:  ikey,
+		ChannelPlay: &requests.ChannelPlay{
+			PlaybackID: playbackID,
+			MediaURI:   mediaURI,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return play.NewPlaybackHandle(k.New(key.PlaybackKey, playbackID), c.c.Playback(), func(h *play.PlaybackHandle) error {
+		_, err := c.Play(k.New(ikey.Kind, ikey.ID), playbackID, mediaURI)
+		return err
+	}), nil
+}
+
 func (c *ichannel) Record(ikey *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
 	rb, err := c.c.createRequest(&requests.Request{
 		Kind: "ChannelRecord",
@@ -300,6 +481,24 @@ func (c *ichannel) Record(ikey *key.Key, name string, opts *arioptions.Recording
 	return recordings.NewLiveRecordingHandle(rb.New(key.LiveRecordingKey, name), c.c.LiveRecording(), nil), nil
 }
 
+func (c *ichannel) StageRecord(ikey *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
+	rb, err := c.c.createRequest(&requests.Request{
+		Kind: "ChannelStageRecord",
+		Key:  ikey,
+		ChannelRecord: &requests.ChannelRecord{
+			Name:    name,
+			Options: opts,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recordings.NewLiveRecordingHandle(rb.New(key.LiveRecordingKey, name), c.c.LiveRecording(), func(h *recordings.LiveRecordingHandle) error {
+		_, err := c.Record(rb.New(ikey.Kind, ikey.ID), name, opts)
+		return err
+	}), nil
+}
+
 func (c *ichannel) ExternalMedia(referenceKey *key.Key, opts arioptions.ExternalMediaOptions) (*channel.ChannelHandle, error) {
 	if opts.ChannelID == "" {
 		opts.ChannelID = rid.New(rid.Channel)
@@ -340,3 +539,7 @@ func (c *ichannel) StageExternalMedia(referenceKey *key.Key, opts arioptions.Ext
 		return err
 	}), nil
 }
+
+func (c *ichannel) Subscribe(ikey *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return c.c.subscribeKey(ikey, l, types...)
+}
@@ -1,16 +1,32 @@
 package channel
 
 import (
-	"strings"
+	"context"
 	"time"
 
+	"github.com/callevo/ari/arievent"
 	"github.com/callevo/ari/arioptions"
+	"github.com/callevo/ari/dispatcher"
 	"github.com/callevo/ari/key"
 	"github.com/callevo/ari/play"
 	"github.com/callevo/ari/recordings"
 	"github.com/callevo/ari/requests"
+	"github.com/rotisserie/eris"
 )
 
+// ErrNotTracked indicates that the handle does not track the entry of its
+// channel into the Stasis application
+var ErrNotTracked = eris.New("channel handle does not track Stasis entry")
+
+// ErrMoveUnconfirmed is returned by Move when Asterisk reports neither the
+// departure of the channel nor a failure in time.  The move may still have
+// happened.
+var ErrMoveUnconfirmed = eris.New("move of channel not confirmed")
+
+// ErrMoveHangup is returned by Move when the channel hung up instead of
+// moving
+var ErrMoveHangup = eris.New("channel hung up while moving")
+
 type Channel interface {
 	// Get returns a handle to a channel for further interaction
 	Get(key *key.Key) *ChannelHandle
@@ -25,6 +41,10 @@ type Channel interface {
 	// the creation failed.
 	// The Key should be that of the linked channel, if one exists, so that the
 	// Node can be matches to it.
+	// A channel originated into the application of a listening client is
+	// handed back to that client, but unless Options.OriginateClaims is set
+	// the other members of its queue group take it as well, and the call is
+	// handled twice.
 	Originate(*key.Key, requests.OriginateRequest) (*ChannelHandle, error)
 
 	// StageOriginate creates a new Originate, created when the `Exec` method
@@ -45,6 +65,15 @@ type Channel interface {
 	// Continue tells Asterisk to return a channel to the dialplan
 	Continue(key *key.Key, context, extension string, priority int) error
 
+	// Move moves the channel to another Stasis application.  It returns a
+	// *MoveFailedError when Asterisk reports that the move failed,
+	// ErrMoveHangup when the channel hangs up first and ErrMoveUnconfirmed
+	// when Asterisk reports nothing in time.
+	Move(key *key.Key, app string, args ...string) error
+
+	// Redirect redirects the channel to another endpoint
+	Redirect(key *key.Key, endpoint string) error
+
 	// Busy hangs up the channel with the "busy" cause code
 	Busy(key *key.Key) error
 
@@ -54,8 +83,9 @@ type Channel interface {
 	// Answer answers the channel
 	Answer(key *key.Key) error
 
-	// Hangup hangs up the given channel
-	Hangup(key *key.Key, reason string) error
+	// Hangup hangs up the given channel with the reason.  A reason ARI does
+	// not accept is an error; see HangupReason.Valid.
+	Hangup(key *key.Key, reason HangupReason) error
 
 	// Ring indicates ringing to the channel
 	Ring(key *key.Key) error
@@ -98,14 +128,14 @@ type Channel interface {
 
 	// StagePlay stages a `Play` operation and returns the `PlaybackHandle`
 	// for invoking it.
-	//StagePlay(key *key.Key, playbackID string, mediaURI string) (*PlaybackHandle, error)
+	StagePlay(key *key.Key, playbackID string, mediaURI string) (*play.PlaybackHandle, error)
 
 	// Record records the channel
 	Record(key *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error)
 
-	// StageRecord stages a `Record` operation and returns the `PlaybackHandle`
+	// StageRecord stages a `Record` operation and returns the `LiveRecordingHandle`
 	// for invoking it.
-	//StageRecord(key *Key, name string, opts *RecordingOptions) (*LiveRecordingHandle, error)
+	StageRecord(key *key.Key, name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error)
 
 	// Dial dials a created channel
 	Dial(key *key.Key, caller string, timeout time.Duration) error
@@ -126,41 +156,47 @@ type Channel interface {
 	// ExternalMedia creates a new non-telephony external media channel by which audio may be sent or received
 	ExternalMedia(key *key.Key, opts arioptions.ExternalMediaOptions) (*ChannelHandle, error)
 
-	// UserEvent Sends user-event to AMI channel subscribers
-	//UserEvent(key *Key, ue *ChannelUserevent) error
-}
+	// UserEvent generates a user event on the channel, with the user-defined
+	// fields.  It reaches the ARI and AMI subscribers of the channel as a
+	// ChannelUserevent.
+	UserEvent(key *key.Key, name string, fields map[string]interface{}) error
 
-type CallerInfo struct {
-	Name   string `json:"name"`
-	Number string `json:"number"`
+	// Subscribe registers the listener for the events of the given types
+	// relating to the channel, or for all of its events when no type is given
+	Subscribe(key *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription
 }
 
-type ChannelData struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	State        string `json:"state"`
-	Protocol     string `json:"protocol"`
-	Caller       CallerInfo
-	Connected    CallerInfo
-	Accountcode  string `json:"accountcode"`
-	Dialplan     DialplanInfo
-	Creationtime string            `json:"creationtime"`
-	Language     string            `json:"language"`
-	ChannelVars  map[string]string `json:"channelvars"`
-}
+// CallerInfo describes the caller or connected party of a channel
+type CallerInfo = arievent.CallerInfo
+
+// ChannelData describes the state of a channel
+type ChannelData = arievent.ChannelData
 
-func (c *ChannelData) GetID() string {
-	return c.ID
+// DialplanInfo describes the dialplan location of a channel
+type DialplanInfo = arievent.DialplanInfo
+
+// MoveFailedError is returned by Move when Asterisk could not move the channel
+// to the application, such as when the application is not registered.  The
+// channel stays in its current application.
+type MoveFailedError struct {
+	ChannelID   string
+	Destination string
+	Args        []string
 }
 
-type DialplanInfo struct {
-	Context  string `json:"context"`
-	Exten    string `json:"exten"`
-	Priority int    `json:"priority"`
-	AppName  string `json:"app_name"`
-	AppData  string `json:"app_data"`
+func (err *MoveFailedError) Error() string {
+	return "failed to move channel " + err.ChannelID + " to application " + err.Destination
 }
 
+// HangupReason is the reason given when hanging up a channel
+type HangupReason = arievent.HangupReason
+
+// Cause is the Q.850 hangup cause of a channel
+type Cause = arievent.Cause
+
+// ChannelState is the state of a channel
+type ChannelState = arievent.ChannelState
+
 // ChannelHandle provides a wrapper on the Channel interface for operations on a particular channel ID.
 type ChannelHandle struct {
 	key *key.Key
@@ -170,6 +206,9 @@ type ChannelHandle struct {
 	callback func(ch *ChannelHandle) error
 
 	executed bool
+
+	// stasis is closed once the channel has entered the Stasis application
+	stasis <-chan struct{}
 }
 
 // Exec executes any staged channel operations attached to this handle.
@@ -194,6 +233,37 @@ func NewChannelHandle(key *key.Key, c Channel, exec func(ch *ChannelHandle) erro
 	}
 }
 
+// NewOriginatedChannelHandle returns a handle to a channel originated into the
+// Stasis application of the client.  The stasis channel must be closed when the
+// channel enters the application.
+func NewOriginatedChannelHandle(key *key.Key, c Channel, exec func(ch *ChannelHandle) error, stasis <-chan struct{}) *ChannelHandle {
+	h := NewChannelHandle(key, c, exec)
+	h.stasis = stasis
+
+	return h
+}
+
+// StasisStarted returns a channel which is closed when the channel enters the
+// Stasis application.  It is nil if the handle does not track the entry.
+func (ch *ChannelHandle) StasisStarted() <-chan struct{} {
+	return ch.stasis
+}
+
+// WaitStasis blocks until the channel enters the Stasis application or the
+// context is done.
+func (ch *ChannelHandle) WaitStasis(ctx context.Context) error {
+	if ch.stasis == nil {
+		return ErrNotTracked
+	}
+
+	select {
+	case <-ch.stasis:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (ch *ChannelHandle) ID() string {
 	return ch.key.ID
 }
@@ -217,17 +287,49 @@ func (ch *ChannelHandle) Continue(context, extension string, priority int) error
 	return ch.c.Continue(ch.key, context, extension, priority)
 }
 
+// Move hands the channel to another Stasis application, with the arguments.
+// It returns a *MoveFailedError when Asterisk reports that the move failed,
+// ErrMoveHangup when the channel hangs up first and ErrMoveUnconfirmed when
+// Asterisk reports nothing in time.
+func (ch *ChannelHandle) Move(app string, args ...string) error {
+	return ch.c.Move(ch.key, app, args...)
+}
+
+// Redirect sends the channel to another endpoint
+func (ch *ChannelHandle) Redirect(endpoint string) error {
+	return ch.c.Redirect(ch.key, endpoint)
+}
+
 // Play initiates playback of the specified media uri
 // to the channel, returning the Playback handle
 func (ch *ChannelHandle) Play(id string, mediaURI string) (ph *play.PlaybackHandle, err error) {
 	return ch.c.Play(ch.key, id, mediaURI)
 }
 
+// Subscribe registers the listener for the events of the given types relating
+// to the channel, or for all of its events when no type is given.  Only
+// typed subscriptions honour StopPropagation in ordered mode.
+func (ch *ChannelHandle) Subscribe(l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return ch.c.Subscribe(ch.key, l, types...)
+}
+
+// StagePlay stages a `Play` operation; the playback starts when Exec is called
+// on the returned handle
+func (ch *ChannelHandle) StagePlay(id string, mediaURI string) (*play.PlaybackHandle, error) {
+	return ch.c.StagePlay(ch.key, id, mediaURI)
+}
+
 // Record records the channel to the given filename
 func (ch *ChannelHandle) Record(name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
 	return ch.c.Record(ch.key, name, opts)
 }
 
+// StageRecord stages a `Record` operation; the recording starts when Exec is
+// called on the returned handle
+func (ch *ChannelHandle) StageRecord(name string, opts *arioptions.RecordingOptions) (*recordings.LiveRecordingHandle, error) {
+	return ch.c.StageRecord(ch.key, name, opts)
+}
+
 //---
 // Hangup Operations
 //---
@@ -244,7 +346,13 @@ func (ch *ChannelHandle) Congestion() error {
 
 // Hangup hangs up the channel with the normal cause code
 func (ch *ChannelHandle) Hangup() error {
-	return ch.c.Hangup(ch.key, "normal")
+	return ch.c.Hangup(ch.key, arievent.HangupNormal)
+}
+
+// HangupWithReason hangs up the channel with the reason, such as
+// arievent.HangupBusy
+func (ch *ChannelHandle) HangupWithReason(reason HangupReason) error {
+	return ch.c.Hangup(ch.key, reason)
 }
 
 // Answer operations
@@ -262,7 +370,7 @@ func (ch *ChannelHandle) IsAnswered() (bool, error) {
 		return false, err
 	}
 
-	return strings.ToLower(updated.State) == "up", nil
+	return updated.State.IsUp(), nil
 }
 
 // Ring Operations
@@ -421,10 +529,22 @@ func (ch *ChannelHandle) SendDTMF(dtmf string, opts *arioptions.DTMFOptions) err
 	return ch.c.SendDTMF(ch.key, dtmf, opts)
 }
 
-// UserEvent sends user-event to AMI channel subscribers
-//func (ch *ChannelHandle) UserEvent(key *Key, ue *ChannelUserevent) error {
-//	return nil
-//}
+// UserEvent generates a user event on the channel, with the user-defined
+// fields
+func (ch *ChannelHandle) UserEvent(name string, fields map[string]interface{}) error {
+	return ch.c.UserEvent(ch.key, name, fields)
+}
+
+// OnUserEvent registers the listener for the user events of the channel with
+// the given name, or for all of them when the name is empty.  User events
+// raised without a channel never match.
+func (ch *ChannelHandle) OnUserEvent(name string, l dispatcher.Listener) *dispatcher.Subscription {
+	return ch.c.Subscribe(ch.key, func(e *arievent.StasisEvent) {
+		if name == "" || e.EventName == name {
+			l(e)
+		}
+	}, arievent.ChannelUserevent)
+}
 
 //Code taken from ari-proxy. NOt verbatim, but with enough similarities to make this work .
 //Key was a copy from ari-proxy
@@ -0,0 +1,131 @@
+package ari
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+)
+
+func TestHangupReason(t *testing.T) {
+	tests := []struct {
+		reason  arievent.HangupReason
+		sent    []string
+		wantErr bool
+	}{
+		{reason: arievent.HangupBusy, sent: []string{"busy"}},
+		{reason: "", sent: []string{""}},
+		{reason: "hungry", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		bus := newFakeBus()
+		a := newTestClient(bus)
+
+		err := a.Channel().Hangup(key.NewKey(key.ChannelKey, "c1", key.WithApp("app"), key.WithNode("ast1")), tt.reason)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Hangup(%q) error = %v, want error %v", tt.reason, err, tt.wantErr)
+		}
+
+		if sent := bus.hangups(); !reflect.DeepEqual(sent, tt.sent) {
+			t.Errorf("Hangup(%q) sent %q, want %q", tt.reason, sent, tt.sent)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	defer func(timeout time.Duration) { MoveConfirmTimeout = timeout }(MoveConfirmTimeout)
+	MoveConfirmTimeout = 20 * time.Millisecond
+
+	event := func(typ arievent.EventType, id string) *arievent.StasisEvent {
+		return &arievent.StasisEvent{Type: typ, Node: "ast1", Channel: arievent.ChannelData{ID: id}, Destination: "other", Args: []string{"a", "b"}}
+	}
+
+	tests := []struct {
+		name   string
+		events []*arievent.StasisEvent
+		err    error
+		want   error
+		failed bool
+	}{
+		{
+			name:   "moved",
+			events: []*arievent.StasisEvent{event(arievent.StasisEnd, "c1")},
+		},
+		{
+			name:   "failed",
+			events: []*arievent.StasisEvent{event(arievent.ApplicationMoveFailed, "c1")},
+			failed: true,
+		},
+		{
+			name:   "hung up",
+			events: []*arievent.StasisEvent{event(arievent.ChannelHangupRequest, "c1"), event(arievent.StasisEnd, "c1")},
+			want:   channel.ErrMoveHangup,
+		},
+		{
+			name:   "destroyed",
+			events: []*arievent.StasisEvent{event(arievent.ChannelDestroyed, "c1")},
+			want:   channel.ErrMoveHangup,
+		},
+		{
+			name:   "unconfirmed",
+			events: []*arievent.StasisEvent{event(arievent.StasisEnd, "c2")},
+			want:   channel.ErrMoveUnconfirmed,
+		},
+		{
+			name: "request failed",
+			err:  errBus,
+			want: errBus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+
+			bus.respond = func(_ string, r *requests.Request) (*response.Response, error) {
+				if r.ChannelMove.App != "other" || r.ChannelMove.AppArgs != "a,b" {
+					t.Errorf("moved to %s(%s)", r.ChannelMove.App, r.ChannelMove.AppArgs)
+				}
+
+				// The events come from the topic of the channel, which the
+				// client does not follow otherwise
+				for _, e := range tt.events {
+					bus.publish(a.resourceTopic("app", "ast1", "c1"), e)
+				}
+
+				return &response.Response{}, tt.err
+			}
+
+			h := channel.NewChannelHandle(key.NewKey(key.ChannelKey, "c1", key.WithApp("app"), key.WithNode("ast1")), a.Channel(), nil)
+
+			err := h.Move("other", "a", "b")
+
+			if n := a.topicUsers(a.resourceTopic("app", "ast1", "c1")); n != 0 {
+				t.Errorf("topic of the channel still followed by %d users", n)
+			}
+
+			var failed *channel.MoveFailedError
+			if errors.As(err, &failed) != tt.failed {
+				t.Fatalf("error = %v, want a *MoveFailedError: %v", err, tt.failed)
+			}
+			if tt.failed {
+				if failed.ChannelID != "c1" || failed.Destination != "other" || !reflect.DeepEqual(failed.Args, []string{"a", "b"}) {
+					t.Errorf("failure %+v", failed)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
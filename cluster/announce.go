@@ -1,5 +1,7 @@
 package cluster
 
+import "strings"
+
 // Announcement describes the structure of an ARI proxy's announcement of availability on the network.  These are sent periodically and upon request (by a Ping).
 type Announcement struct {
 	// EventName
@@ -10,4 +12,94 @@ type Announcement struct {
 
 	// Application indicates the ARI application as which the proxy is connected
 	Application string `json:"application"`
+
+	// Metadata describes the capabilities and load of the proxy
+	Metadata
+}
+
+// Metadata describes the capabilities and the load of a proxy and of its
+// Asterisk node.  Proxies which do not send it leave it empty.
+type Metadata struct {
+	// ProxyVersion is the version of the proxy software
+	ProxyVersion string `json:"proxy_version,omitempty"`
+
+	// AsteriskVersion is the version of the Asterisk node
+	AsteriskVersion string `json:"asterisk_version,omitempty"`
+
+	// Kinds lists the request kinds the proxy supports
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Codecs lists the codecs the Asterisk node supports
+	Codecs []string `json:"codecs,omitempty"`
+
+	// Channels is the number of live channels on the node
+	Channels int `json:"channels,omitempty"`
+
+	// Bridges is the number of live bridges on the node
+	Bridges int `json:"bridges,omitempty"`
+
+	// MaxChannels is the maximum number of channels of the node, zero when
+	// unlimited
+	MaxChannels int `json:"max_channels,omitempty"`
+
+	// Labels are free-form properties of the node, such as its region or
+	// trunk group
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SupportsKind reports whether the proxy supports the request kind.  A proxy
+// which does not announce its kinds is assumed to support every kind.
+func (m *Metadata) SupportsKind(kind string) bool {
+	if len(m.Kinds) == 0 {
+		return true
+	}
+
+	for _, k := range m.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SupportsCodec reports whether the node supports the codec
+func (m *Metadata) SupportsCodec(codec string) bool {
+	for _, c := range m.Codecs {
+		if strings.EqualFold(c, codec) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Label returns the value of the label, and whether it is set
+func (m *Metadata) Label(name string) (string, bool) {
+	v, ok := m.Labels[name]
+	return v, ok
+}
+
+// Free returns the number of channels the node can still take, or -1 when it
+// is unlimited
+func (m *Metadata) Free() int {
+	if m.MaxChannels <= 0 {
+		return -1
+	}
+
+	if free := m.MaxChannels - m.Channels; free > 0 {
+		return free
+	}
+
+	return 0
+}
+
+// Load returns the fraction of the maximum channels in use, or zero when it is
+// unlimited
+func (m *Metadata) Load() float64 {
+	if m.MaxChannels <= 0 {
+		return 0
+	}
+
+	return float64(m.Channels) / float64(m.MaxChannels)
 }
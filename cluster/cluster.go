@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -12,19 +13,25 @@ var AutoPurgeInterval = 24 * time.Hour
 // AutoPurgeAge is the maximum age allowed for members' last update when automatically purging.
 var AutoPurgeAge = 12 * time.Hour
 
-// Cluster describes the set of ari proxies in a system.  The list is indexed by a hash of the asterisk ID and the ARI application and indicates the time of last contact.
+// Cluster describes the set of ari proxies in a system.  The list is indexed by a hash of the asterisk ID and the ARI application and indicates the time of last contact and the last announced metadata.
 type Cluster struct {
 	lastPurge time.Time
 
-	members map[string]time.Time
+	members map[string]*memberState
 
 	mu sync.Mutex
 }
 
+// memberState is what the cluster knows of a member
+type memberState struct {
+	lastActive time.Time
+	meta       Metadata
+}
+
 // New returns a new Cluster
 func New() *Cluster {
 	return &Cluster{
-		members: make(map[string]time.Time),
+		members: make(map[string]*memberState),
 	}
 }
 
@@ -52,6 +59,20 @@ type Member struct {
 
 	// LastActive is the timestamp of the last occurrence of this node
 	LastActive time.Time
+
+	// Metadata is the last metadata announced by this node
+	Metadata
+}
+
+// member returns the Member described by the key and state
+func member(k string, v *memberState) Member {
+	id, app := dehash(k)
+	return Member{
+		ID:         id,
+		App:        app,
+		LastActive: v.lastActive,
+		Metadata:   v.meta,
+	}
 }
 
 // All returns a list of all cluster members whose LastActive time is no older thatn the given maxAge.
@@ -60,13 +81,8 @@ func (c *Cluster) All(maxAge time.Duration) (list []Member) {
 	defer c.mu.Unlock()
 
 	for k, v := range c.members {
-		if maxAge == 0 || time.Since(v) < maxAge {
-			id, app := dehash(k)
-			list = append(list, Member{
-				ID:         id,
-				App:        app,
-				LastActive: v,
-			})
+		if maxAge == 0 || time.Since(v.lastActive) < maxAge {
+			list = append(list, member(k, v))
 		}
 	}
 	return
@@ -78,25 +94,26 @@ func (c *Cluster) App(app string, maxAge time.Duration) (list []Member) {
 	defer c.mu.Unlock()
 
 	for k, v := range c.members {
-		i, a := dehash(k)
-		if app == a && (maxAge == 0 || time.Since(v) < maxAge) {
-			list = append(list, Member{
-				ID:         i,
-				App:        a,
-				LastActive: v,
-			})
+		_, a := dehash(k)
+		if app == a && (maxAge == 0 || time.Since(v.lastActive) < maxAge) {
+			list = append(list, member(k, v))
 		}
 	}
 	return
 }
 
-// Matching returns a list of all cluster members for whom the given proxy Metadata matches
-func (c *Cluster) Matching(id, app string, maxAge time.Duration) (list []Member) {
+// AnyAge is the maxAge accepting the members however long ago they were last
+// heard of
+const AnyAge = time.Duration(math.MaxInt64)
+
+// Matching returns a list of all cluster members for whom the given proxy Metadata matches, and which satisfy every filter.  Only the members heard of within maxAge are returned, so that a zero maxAge returns none; use AnyAge to accept every member.
+func (c *Cluster) Matching(id, app string, maxAge time.Duration, filters ...Filter) (list []Member) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+members:
 	for k, v := range c.members {
-		if time.Since(v) > maxAge {
+		if time.Since(v.lastActive) > maxAge {
 			continue
 		}
 
@@ -107,21 +124,61 @@ func (c *Cluster) Matching(id, app string, maxAge time.Duration) (list []Member)
 		if app != "" && app != a {
 			continue
 		}
-		list = append(list, Member{
-			ID:         i,
-			App:        a,
-			LastActive: v,
-		})
+
+		m := member(k, v)
+		for _, f := range filters {
+			if !f(&m) {
+				continue members
+			}
+		}
+
+		list = append(list, m)
 	}
 	return
 }
 
-// Update adds (or updates) a proxy to/in the cluster
+// Get returns the cluster member for the given Asterisk ID and ARI application
+func (c *Cluster) Get(id, app string) (Member, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := hash(id, app)
+	v, ok := c.members[k]
+	if !ok {
+		return Member{}, false
+	}
+
+	return member(k, v), true
+}
+
+// Update adds (or updates) a proxy to/in the cluster, keeping its last announced metadata
 func (c *Cluster) Update(id, app string) {
 	c.mu.Lock()
-	c.members[hash(id, app)] = time.Now()
+	k := hash(id, app)
+	if v, ok := c.members[k]; ok {
+		v.lastActive = time.Now()
+	} else {
+		c.members[k] = &memberState{lastActive: time.Now()}
+	}
 	c.mu.Unlock()
 
+	c.autoPurge()
+}
+
+// Announce adds (or updates) the proxy of the announcement to/in the cluster, along with its metadata
+func (c *Cluster) Announce(a *Announcement) {
+	c.mu.Lock()
+	c.members[hash(a.Node, a.Application)] = &memberState{
+		lastActive: time.Now(),
+		meta:       a.Metadata,
+	}
+	c.mu.Unlock()
+
+	c.autoPurge()
+}
+
+// autoPurge purges the stale members when it is time to
+func (c *Cluster) autoPurge() {
 	// See if it is time to auto-purge
 	if time.Since(c.lastPurge) > AutoPurgeInterval {
 		c.Purge(AutoPurgeAge)
@@ -138,7 +195,7 @@ func (c *Cluster) Purge(maxAge time.Duration) {
 	var removalKeys []string
 
 	for k, v := range c.members {
-		if maxAge == 0 || time.Since(v) > maxAge {
+		if maxAge == 0 || time.Since(v.lastActive) > maxAge {
 			removalKeys = append(removalKeys, k)
 		}
 	}
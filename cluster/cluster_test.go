@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func testCluster() *Cluster {
+	c := New()
+	c.lastPurge = time.Now()
+
+	c.members[hash("ast1", "app")] = &memberState{
+		lastActive: time.Now(),
+		meta:       Metadata{Codecs: []string{"ulaw"}, Labels: map[string]string{"region": "eu"}},
+	}
+	c.members[hash("ast2", "app")] = &memberState{
+		lastActive: time.Now().Add(-time.Hour),
+		meta:       Metadata{Codecs: []string{"opus"}, Labels: map[string]string{"region": "us"}},
+	}
+	c.members[hash("ast1", "other")] = &memberState{
+		lastActive: time.Now(),
+	}
+
+	return c
+}
+
+func TestMatching(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		app     string
+		maxAge  time.Duration
+		filters []Filter
+		want    []string
+	}{
+		{"zero age", "", "app", 0, nil, nil},
+		{"any age", "", "app", AnyAge, nil, []string{"ast1|app", "ast2|app"}},
+		{"recent", "", "app", time.Minute, nil, []string{"ast1|app"}},
+		{"every application", "", "", AnyAge, nil, []string{"ast1|app", "ast1|other", "ast2|app"}},
+		{"node", "ast1", "", AnyAge, nil, []string{"ast1|app", "ast1|other"}},
+		{"filter", "", "app", AnyAge, []Filter{WithLabel("region", "us")}, []string{"ast2|app"}},
+		{"filters", "", "app", AnyAge, []Filter{WithLabel("region", "us"), WithCodec("ulaw")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range testCluster().Matching(tt.id, tt.app, tt.maxAge, tt.filters...) {
+				got = append(got, hash(m.ID, m.App))
+			}
+			sort.Strings(got)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	c := New()
+	c.Announce(&Announcement{Node: "ast1", Application: "app", Metadata: Metadata{Channels: 3}})
+
+	m, ok := c.Get("ast1", "app")
+	if !ok {
+		t.Fatal("announced member unknown")
+	}
+	if m.ID != "ast1" || m.App != "app" || m.Channels != 3 {
+		t.Errorf("member = %+v", m)
+	}
+
+	if _, ok := c.Get("ast2", "app"); ok {
+		t.Error("unknown member found")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	c := testCluster()
+	c.Purge(time.Minute)
+
+	if n := len(c.All(AnyAge)); n != 2 {
+		t.Errorf("%d members after purge, want 2", n)
+	}
+}
@@ -0,0 +1,61 @@
+package cluster
+
+import "strings"
+
+// Filter selects cluster members by their metadata
+type Filter func(m *Member) bool
+
+// WithKind selects the members whose proxy supports the request kind
+func WithKind(kind string) Filter {
+	return func(m *Member) bool {
+		return m.SupportsKind(kind)
+	}
+}
+
+// WithCodec selects the members whose node supports the codec
+func WithCodec(codec string) Filter {
+	return func(m *Member) bool {
+		return m.SupportsCodec(codec)
+	}
+}
+
+// WithLabel selects the members with the given label value
+func WithLabel(name, value string) Filter {
+	return func(m *Member) bool {
+		v, ok := m.Label(name)
+		return ok && v == value
+	}
+}
+
+// WithCapacity selects the members whose node can take at least n more
+// channels
+func WithCapacity(n int) Filter {
+	return func(m *Member) bool {
+		free := m.Free()
+		return free < 0 || free >= n
+	}
+}
+
+// WithAsteriskVersion selects the members whose Asterisk version starts with
+// the prefix, such as "20." or "20.5"
+func WithAsteriskVersion(prefix string) Filter {
+	return func(m *Member) bool {
+		return strings.HasPrefix(m.AsteriskVersion, prefix)
+	}
+}
+
+// WithProxyVersion selects the members whose proxy version starts with the
+// prefix
+func WithProxyVersion(prefix string) Filter {
+	return func(m *Member) bool {
+		return strings.HasPrefix(m.ProxyVersion, prefix)
+	}
+}
+
+// WithMinScore selects the members whose node has at least the given health
+// score, as returned by score, such as health.Registry.Score
+func WithMinScore(score func(node string) float64, min float64) Filter {
+	return func(m *Member) bool {
+		return score(m.ID) >= min
+	}
+}
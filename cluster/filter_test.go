@@ -0,0 +1,80 @@
+package cluster
+
+import "testing"
+
+func TestFilters(t *testing.T) {
+	m := &Member{
+		ID: "ast1",
+		Metadata: Metadata{
+			ProxyVersion:    "1.4.2",
+			AsteriskVersion: "20.5.0",
+			Kinds:           []string{"ChannelOriginate"},
+			Codecs:          []string{"ULAW", "opus"},
+			Channels:        8,
+			MaxChannels:     10,
+			Labels:          map[string]string{"region": "eu"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"kind", WithKind("ChannelOriginate"), true},
+		{"unsupported kind", WithKind("BridgeCreate"), false},
+		{"codec", WithCodec("ulaw"), true},
+		{"unsupported codec", WithCodec("g722"), false},
+		{"label", WithLabel("region", "eu"), true},
+		{"other label value", WithLabel("region", "us"), false},
+		{"missing label", WithLabel("trunk", ""), false},
+		{"capacity", WithCapacity(2), true},
+		{"no capacity", WithCapacity(3), false},
+		{"asterisk version", WithAsteriskVersion("20."), true},
+		{"other asterisk version", WithAsteriskVersion("18"), false},
+		{"proxy version", WithProxyVersion("1.4"), true},
+		{"score", WithMinScore(func(string) float64 { return 0.5 }, 0.5), true},
+		{"low score", WithMinScore(func(string) float64 { return 0.4 }, 0.5), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(m); got != tt.want {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     Metadata
+		anyKind  bool
+		free     int
+		load     float64
+		capacity bool
+	}{
+		{"unannounced", Metadata{}, true, -1, 0, true},
+		{"kinds", Metadata{Kinds: []string{"ChannelGet"}}, false, -1, 0, true},
+		{"half full", Metadata{Channels: 5, MaxChannels: 10}, true, 5, 0.5, true},
+		{"overloaded", Metadata{Channels: 12, MaxChannels: 10}, true, 0, 1.2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.meta.SupportsKind("ChannelOriginate"); got != tt.anyKind {
+				t.Errorf("SupportsKind = %v, want %v", got, tt.anyKind)
+			}
+			if got := tt.meta.Free(); got != tt.free {
+				t.Errorf("Free = %d, want %d", got, tt.free)
+			}
+			if got := tt.meta.Load(); got != tt.load {
+				t.Errorf("Load = %v, want %v", got, tt.load)
+			}
+			if got := WithCapacity(1)(&Member{Metadata: tt.meta}); got != tt.capacity {
+				t.Errorf("WithCapacity(1) = %v, want %v", got, tt.capacity)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package ari
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/bridge"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/rid"
+	"github.com/rotisserie/eris"
+)
+
+// DefaultDialTimeout is the default amount of time to wait for the dialed
+// endpoint to answer
+var DefaultDialTimeout = 30 * time.Second
+
+// dialGrace is the time given to Asterisk beyond the dial timeout to report
+// the outcome of the dial
+var dialGrace = 5 * time.Second
+
+// CancelDial is given as the cause of the cancellation of the context of Dial,
+// through context.WithCancelCause, to hang up the dialed channel with a
+// reason such as "answered_elsewhere"
+type CancelDial struct {
+	Reason arievent.HangupReason
+}
+
+func (c *CancelDial) Error() string {
+	return "dial cancelled: " + string(c.Reason)
+}
+
+// DialOptions configures Dial
+type DialOptions struct {
+	// App is the Stasis application of the new channel.  Defaults to the
+	// application of the client.
+	App string
+
+	// AppArgs are the arguments of the application
+	AppArgs string
+
+	// Node is the Asterisk node of the new channel, when there is no caller.
+	// With a caller, the channel is created on the node of the caller.
+	Node string
+
+	// Formats are the codecs allowed on the new channel, when there is no
+	// caller
+	Formats string
+
+	// Timeout is the amount of time to wait for an answer.  Defaults to
+	// DefaultDialTimeout.
+	Timeout time.Duration
+
+	// Bridge joins the answered channel and the caller in a new mixing bridge
+	Bridge bool
+}
+
+// DialResult is the outcome of Dial
+type DialResult struct {
+	// Status is the final status of the dial
+	Status arievent.DialStatus
+
+	// Handle is the dialed channel
+	Handle *channel.ChannelHandle
+
+	// Bridge joins the caller and the dialed channel, when requested
+	Bridge *bridge.BridgeHandle
+
+	// Cause and CauseText are the hangup cause of the dialed channel, when it
+	// was destroyed before answering
+	Cause     arievent.Cause
+	CauseText string
+
+	// Started is when the channel was created
+	Started time.Time
+
+	// Ringing and Answered are the times from Started to the first ring and
+	// to the answer, zero if they did not happen
+	Ringing  time.Duration
+	Answered time.Duration
+
+	// Duration is the time from Started to the final status
+	Duration time.Duration
+}
+
+// Dial creates a channel to the endpoint, dials it and follows the dial until
+// it is answered or fails.  The caller, if any, is the originator of the new
+// channel, and may be bridged with it once it answers.  A dial which is not
+// answered is not an error: its outcome is given by the Status of the result.
+// When the context is done, the dial is cancelled and the channel hung up, with
+// the reason of the CancelDial cause of the context if any.  As with Originate,
+// a client sharing its queue group must enable Options.OriginateClaims, or the
+// other members of the group also take the dialed channel.
+// nolint: gocyclo
+func (a *ARIClient) Dial(ctx context.Context, caller *channel.ChannelHandle, endpoint string, opts *DialOptions) (*DialResult, error) {
+	if opts == nil {
+		opts = &DialOptions{}
+	}
+
+	app := opts.App
+	if app == "" {
+		app = a.Application
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDialTimeout
+	}
+
+	referenceKey := key.NewKey(key.ChannelKey, "", key.WithApp(app), key.WithNode(opts.Node))
+	callerID := ""
+	if caller != nil {
+		referenceKey = key.NewKey(key.ChannelKey, "", key.WithApp(app), key.WithNode(caller.Key().GetNode()))
+		callerID = caller.ID()
+	}
+
+	id := rid.New(rid.Channel)
+	k := referenceKey.New(key.ChannelKey, id)
+
+	// Receive the events of the channel, and claim its StasisStart
+	p, err := a.trackOriginate(app, referenceKey.GetNode(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan *arievent.StasisEvent, 16)
+	done := make(chan struct{})
+	defer close(done)
+
+	sub := a.subscribeKey(k, func(e *arievent.StasisEvent) {
+		select {
+		case events <- e:
+		case <-done:
+		}
+	}, arievent.Dial, arievent.ChannelStateChange, arievent.ChannelDestroyed)
+	defer sub.Cancel()
+
+	res := &DialResult{Started: time.Now()}
+
+	ch := &ichannel{c: a}
+
+	res.Handle, err = ch.Create(referenceKey, requests.ChannelCreateRequest{
+		Endpoint:   endpoint,
+		App:        app,
+		AppArgs:    opts.AppArgs,
+		ChannelID:  id,
+		Originator: callerID,
+		Formats:    opts.Formats,
+	})
+	if err != nil {
+		a.untrackOriginate(p)
+		return nil, eris.Wrapf(err, "failed to create channel to %s", endpoint)
+	}
+
+	if err := res.Handle.Dial(callerID, timeout); err != nil {
+		a.untrackOriginate(p)
+		a.hangupDialed(res.Handle, arievent.HangupNormal)
+
+		return nil, eris.Wrapf(err, "failed to dial %s", endpoint)
+	}
+
+	timer := time.NewTimer(timeout + dialGrace)
+	defer timer.Stop()
+
+	end := func(status arievent.DialStatus) {
+		res.Status = status
+		res.Duration = time.Since(res.Started)
+
+		if status == arievent.DialAnswer {
+			res.Answered = res.Duration
+			return
+		}
+
+		a.untrackOriginate(p)
+	}
+
+	for res.Status == "" {
+		select {
+		case <-ctx.Done():
+			reason := arievent.HangupNormal
+
+			var cancel *CancelDial
+			if errors.As(context.Cause(ctx), &cancel) {
+				reason = cancel.Reason
+			}
+
+			a.hangupDialed(res.Handle, reason)
+			end(arievent.DialCancel)
+		case <-timer.C:
+			a.hangupDialed(res.Handle, arievent.HangupNoAnswer)
+			end(arievent.DialNoAnswer)
+		case e := <-events:
+			switch e.GetType() {
+			case arievent.Dial:
+				if e.Peer == nil || e.Peer.ID != id {
+					continue
+				}
+
+				switch {
+				case e.DialStatus == arievent.DialRinging && res.Ringing == 0:
+					res.Ringing = time.Since(res.Started)
+				case e.DialStatus.Final():
+					end(e.DialStatus)
+				}
+			case arievent.ChannelStateChange:
+				switch {
+				case e.Channel.State.IsRinging():
+					if res.Ringing == 0 {
+						res.Ringing = time.Since(res.Started)
+					}
+				case e.Channel.State.IsUp():
+					end(arievent.DialAnswer)
+				}
+			case arievent.ChannelDestroyed:
+				res.Cause, res.CauseText = e.Cause, e.CauseText
+				end(e.Cause.DialStatus())
+			}
+		}
+	}
+
+	logs.TLogger.Debug().Msgf("dial of %s ended with %s after %s", endpoint, res.Status, res.Duration)
+
+	if res.Status != arievent.DialAnswer || !opts.Bridge || caller == nil {
+		return res, nil
+	}
+
+	res.Bridge, err = a.Bridge().Create(referenceKey.New(key.BridgeKey, rid.New(rid.Bridge)), "mixing", "")
+	if err != nil {
+		return res, eris.Wrap(err, "failed to create bridge for the dialed channel")
+	}
+
+	for _, member := range []string{callerID, id} {
+		if err := res.Bridge.AddChannel(member); err != nil {
+			return res, eris.Wrapf(err, "failed to add channel %s to bridge", member)
+		}
+	}
+
+	return res, nil
+}
+
+// hangupDialed hangs up a channel whose dial is abandoned
+func (a *ARIClient) hangupDialed(h *channel.ChannelHandle, reason arievent.HangupReason) {
+	if err := a.Channel().Hangup(h.Key(), reason); err != nil {
+		logs.TLogger.Debug().Msgf("failed to hang up dialed channel %s: %s", h.ID(), err)
+	}
+}
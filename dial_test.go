@@ -0,0 +1,231 @@
+package ari
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+)
+
+// hangups returns the reasons of the hangup requests sent, in order
+func (b *fakeBus) hangups() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var reasons []string
+	for _, r := range b.requests {
+		if r.Kind == "ChannelHangup" {
+			reasons = append(reasons, r.ChannelHangup.Reason)
+		}
+	}
+
+	return reasons
+}
+
+// dialEvent returns a Dial event for the dialed channel
+func dialEvent(id string, status arievent.DialStatus) *arievent.StasisEvent {
+	return &arievent.StasisEvent{Type: arievent.Dial, Node: "ast1", Peer: &arievent.ChannelData{ID: id}, DialStatus: status}
+}
+
+// stateEvent returns a ChannelStateChange event for the channel
+func stateEvent(id string, state arievent.ChannelState) *arievent.StasisEvent {
+	return &arievent.StasisEvent{Type: arievent.ChannelStateChange, Node: "ast1", Channel: arievent.ChannelData{ID: id, State: state}}
+}
+
+func TestDial(t *testing.T) {
+	defer func(grace time.Duration) { dialGrace = grace }(dialGrace)
+	dialGrace = 10 * time.Millisecond
+
+	tests := []struct {
+		name string
+
+		// events are published for the channel once it is dialed
+		events    func(id string) []*arievent.StasisEvent
+		createErr error
+		dialErr   error
+		opts      *DialOptions
+		cancel    *CancelDial
+
+		status  arievent.DialStatus
+		cause   arievent.Cause
+		ringing bool
+		sent    []string
+		hangups []string
+		tracked bool
+		wantErr bool
+	}{
+		{
+			name: "answered",
+			events: func(id string) []*arievent.StasisEvent {
+				return []*arievent.StasisEvent{dialEvent(id, arievent.DialRinging), dialEvent(id, arievent.DialAnswer)}
+			},
+			status:  arievent.DialAnswer,
+			ringing: true,
+			sent:    []string{"ChannelCreate", "ChannelDial"},
+			tracked: true,
+		},
+		{
+			name: "answered, from the channel state",
+			events: func(id string) []*arievent.StasisEvent {
+				return []*arievent.StasisEvent{stateEvent(id, arievent.StateRinging), stateEvent(id, arievent.StateUp)}
+			},
+			status:  arievent.DialAnswer,
+			ringing: true,
+			sent:    []string{"ChannelCreate", "ChannelDial"},
+			tracked: true,
+		},
+		{
+			name: "answered and bridged",
+			events: func(id string) []*arievent.StasisEvent {
+				return []*arievent.StasisEvent{dialEvent(id, arievent.DialAnswer)}
+			},
+			opts:    &DialOptions{Bridge: true},
+			status:  arievent.DialAnswer,
+			sent:    []string{"ChannelCreate", "ChannelDial", "BridgeCreate", "BridgeAddChannel", "BridgeAddChannel"},
+			tracked: true,
+		},
+		{
+			name: "busy",
+			events: func(id string) []*arievent.StasisEvent {
+				return []*arievent.StasisEvent{dialEvent(id, arievent.DialBusy)}
+			},
+			status: arievent.DialBusy,
+			sent:   []string{"ChannelCreate", "ChannelDial"},
+		},
+		{
+			name: "dial of another channel ignored",
+			events: func(id string) []*arievent.StasisEvent {
+				return []*arievent.StasisEvent{
+					{Type: arievent.Dial, Node: "ast1", Channel: arievent.ChannelData{ID: id}, Peer: &arievent.ChannelData{ID: "other"}, DialStatus: arievent.DialAnswer},
+					dialEvent(id, arievent.DialCongestion),
+				}
+			},
+			status: arievent.DialCongestion,
+			sent:   []string{"ChannelCreate", "ChannelDial"},
+		},
+		{
+			name: "destroyed",
+			events: func(id string) []*arievent.StasisEvent {
+				return []*arievent.StasisEvent{{Type: arievent.ChannelDestroyed, Node: "ast1", Channel: arievent.ChannelData{ID: id}, Cause: arievent.CauseUserBusy, CauseText: "User busy"}}
+			},
+			status: arievent.DialBusy,
+			cause:  arievent.CauseUserBusy,
+			sent:   []string{"ChannelCreate", "ChannelDial"},
+		},
+		{
+			name:    "no answer",
+			opts:    &DialOptions{Timeout: 10 * time.Millisecond},
+			status:  arievent.DialNoAnswer,
+			sent:    []string{"ChannelCreate", "ChannelDial", "ChannelHangup"},
+			hangups: []string{"no_answer"},
+		},
+		{
+			name:    "cancelled",
+			cancel:  &CancelDial{Reason: arievent.HangupAnsweredElsewhere},
+			status:  arievent.DialCancel,
+			sent:    []string{"ChannelCreate", "ChannelDial", "ChannelHangup"},
+			hangups: []string{"answered_elsewhere"},
+		},
+		{
+			name:      "create failed",
+			createErr: errBus,
+			sent:      []string{"ChannelCreate"},
+			wantErr:   true,
+		},
+		{
+			name:    "dial failed",
+			dialErr: errBus,
+			sent:    []string{"ChannelCreate", "ChannelDial", "ChannelHangup"},
+			hangups: []string{"normal"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+
+			bus.respond = func(_ string, r *requests.Request) (*response.Response, error) {
+				switch r.Kind {
+				case "ChannelCreate":
+					if tt.createErr != nil {
+						return nil, tt.createErr
+					}
+				case "ChannelDial":
+					if tt.dialErr != nil {
+						return nil, tt.dialErr
+					}
+
+					if tt.events != nil {
+						for _, e := range tt.events(r.Key.GetID()) {
+							bus.publish(a.resourceTopic("app", "ast1", r.Key.GetID()), e)
+						}
+					}
+
+					if tt.cancel != nil {
+						cancel(tt.cancel)
+					}
+				}
+
+				return &response.Response{Key: r.Key}, nil
+			}
+
+			caller := channel.NewChannelHandle(key.NewKey(key.ChannelKey, "caller", key.WithApp("app"), key.WithNode("ast1")), a.Channel(), nil)
+
+			res, err := a.Dial(ctx, caller, "PJSIP/100", tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if sent := bus.sent(); !reflect.DeepEqual(sent, tt.sent) {
+				t.Errorf("sent %v, want %v", sent, tt.sent)
+			}
+			if hangups := bus.hangups(); !reflect.DeepEqual(hangups, tt.hangups) {
+				t.Errorf("hangups %v, want %v", hangups, tt.hangups)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if res.Status != tt.status || res.Cause != tt.cause {
+				t.Errorf("dial ended with %s (cause %d), want %s (cause %d)", res.Status, res.Cause, tt.status, tt.cause)
+			}
+			if (res.Ringing > 0) != tt.ringing {
+				t.Errorf("ringing after %s, want ringing %v", res.Ringing, tt.ringing)
+			}
+			if (res.Status == arievent.DialAnswer) != (res.Answered > 0) {
+				t.Errorf("answered after %s with %s", res.Answered, res.Status)
+			}
+			if _, tracked := a._originates.Load(res.Handle.ID()); tracked != tt.tracked {
+				t.Errorf("tracked = %v, want %v", tracked, tt.tracked)
+			}
+			if res.Handle.Key().GetNode() != "ast1" {
+				t.Errorf("dialed channel on node %q, want the node of the caller", res.Handle.Key().GetNode())
+			}
+
+			if tt.opts != nil && tt.opts.Bridge {
+				var members []string
+				for _, r := range bus.requests {
+					if r.Kind == "BridgeAddChannel" {
+						members = append(members, r.BridgeAddChannel.Channel)
+					}
+				}
+
+				if want := []string{"caller", res.Handle.ID()}; res.Bridge == nil || !reflect.DeepEqual(members, want) {
+					t.Errorf("bridged %v, want %v", members, want)
+				}
+			}
+		})
+	}
+}
@@ -8,7 +8,31 @@ import (
 	"github.com/panjf2000/ants/v2"
 )
 
-type listenersCollection []Listener
+// DefaultPriority is the priority assigned to listeners added through AddListener
+const DefaultPriority = 0
+
+// Mode describes how the dispatcher delivers an event to its listeners
+type Mode int
+
+const (
+	// Parallel submits every listener to the workers pool, in priority order.
+	// Listeners run concurrently, so StopPropagation has no effect.
+	Parallel Mode = iota
+
+	// Ordered runs the listeners synchronously on the dispatching goroutine,
+	// highest priority first, and stops as soon as a listener stops the
+	// propagation of the event.  Taps are not listeners: they have seen the
+	// event before any listener could stop it.
+	Ordered
+)
+
+type listenerEntry struct {
+	id       uint64
+	listener Listener
+	priority int
+}
+
+type listenersCollection []listenerEntry
 
 type Dispatcher interface {
 	Dispatch(e arievent.StasisEvent) arievent.StasisEvent
@@ -33,9 +57,24 @@ type EventDispatcher struct {
 	sync.RWMutex
 	listeners   map[arievent.EventType]listenersCollection
 	workersPool *ants.Pool
+	mode        Mode
+
+	// taps receive every dispatched event, before any listener
+	taps   listenersCollection
+	nextID uint64
 }
 
-func NewDispatcher() *EventDispatcher {
+// OptionFunc is a functional argument for configuring an EventDispatcher
+type OptionFunc func(d *EventDispatcher)
+
+// WithMode sets the dispatch mode of the dispatcher
+func WithMode(m Mode) OptionFunc {
+	return func(d *EventDispatcher) {
+		d.mode = m
+	}
+}
+
+func NewDispatcher(opts ...OptionFunc) *EventDispatcher {
 	pool, err := ants.NewPool(1000)
 
 	if err != nil {
@@ -47,6 +86,10 @@ func NewDispatcher() *EventDispatcher {
 		workersPool: pool,
 	}
 
+	for _, optfn := range opts {
+		optfn(d)
+	}
+
 	return d
 }
 
@@ -54,20 +97,106 @@ func (d *EventDispatcher) GetPool() *ants.Pool {
 	return d.workersPool
 }
 
+// Mode returns the dispatch mode of the dispatcher
+func (d *EventDispatcher) Mode() Mode {
+	d.RWMutex.RLock()
+	defer d.RWMutex.RUnlock()
+
+	return d.mode
+}
+
+// SetMode changes the dispatch mode of the dispatcher
+func (d *EventDispatcher) SetMode(m Mode) {
+	d.RWMutex.Lock()
+	defer d.RWMutex.Unlock()
+
+	d.mode = m
+}
+
+// AddListener adds a listener for the given event type with the default priority
 func (d *EventDispatcher) AddListener(e arievent.EventType, l Listener) {
+	d.AddListenerWithPriority(e, l, DefaultPriority)
+}
+
+// AddListenerWithPriority adds a listener for the given event type.  Listeners
+// with a higher priority are called first; listeners sharing a priority are
+// called in the order they were added.
+func (d *EventDispatcher) AddListenerWithPriority(e arievent.EventType, l Listener, priority int) {
+	d.RWMutex.Lock()
+	defer d.RWMutex.Unlock()
+
+	d.insert(e, l, priority)
+}
+
+// insert adds the listener to the collection of the event type, keeping the
+// collection ordered by priority.  The caller must hold the lock.
+func (d *EventDispatcher) insert(e arievent.EventType, l Listener, priority int) uint64 {
+	d.nextID++
+
+	listeners := d.listeners[e]
+
+	i := len(listeners)
+	for i > 0 && listeners[i-1].priority < priority {
+		i--
+	}
+
+	listeners = append(listeners, listenerEntry{})
+	copy(listeners[i+1:], listeners[i:])
+	listeners[i] = listenerEntry{id: d.nextID, listener: l, priority: priority}
+
+	d.listeners[e] = listeners
+
+	return d.nextID
+}
+
+// Subscribe adds the listener, with the given priority, to each of the event
+// types.  Unlike RemoveListener, cancelling the returned Subscription removes
+// exactly this registration, which makes it safe to use with closures.
+func (d *EventDispatcher) Subscribe(l Listener, priority int, types ...arievent.EventType) *Subscription {
 	d.RWMutex.Lock()
 	defer d.RWMutex.Unlock()
 
-	d.listeners[e] = append(d.listeners[e], l)
+	s := &Subscription{d: d, types: types}
+	for _, t := range types {
+		s.ids = append(s.ids, d.insert(t, l, priority))
+	}
+
+	return s
+}
+
+// Tap registers a listener which receives every dispatched event, whatever its
+// type.  Taps are called synchronously on the dispatching goroutine, in the
+// order the events arrive and before any listener, so they must not block.
+// They are meant for observers such as recorders and caches, and stand outside
+// the propagation: an event a listener stops still reaches every tap.  Code
+// which must not see such events, such as a DTMF consumer behind a listener
+// swallowing digits, should subscribe to the event types instead.
+func (d *EventDispatcher) Tap(l Listener) *Subscription {
+	d.RWMutex.Lock()
+	defer d.RWMutex.Unlock()
+
+	d.nextID++
+	d.taps = append(d.taps, listenerEntry{id: d.nextID, listener: l})
+
+	return &Subscription{d: d, ids: []uint64{d.nextID}, tap: true}
+}
+
+// remove drops the registration with the given id from the collection
+func remove(listeners listenersCollection, id uint64) listenersCollection {
+	for i, l := range listeners {
+		if l.id == id {
+			return append(listeners[:i:i], listeners[i+1:]...)
+		}
+	}
+
+	return listeners
 }
 
 func (d *EventDispatcher) ExecuteOnce(e *arievent.StasisEvent, l Listener) Listener {
 	var newListener func(e *arievent.StasisEvent)
 	newListener = func(e *arievent.StasisEvent) {
 		l(e)
-		d.RWMutex.RUnlock() // The dispatcher is locked in the Dispatch method, need to unlock it
 		d.RemoveListener(e.GetType(), newListener)
-		d.RWMutex.RLock()
 	}
 
 	return newListener
@@ -81,7 +210,7 @@ func (d *EventDispatcher) RemoveListener(e arievent.EventType, l Listener) {
 
 	listeners := d.listeners[e]
 	for i, l := range listeners {
-		lp := reflect.ValueOf(l).Pointer()
+		lp := reflect.ValueOf(l.listener).Pointer()
 		if lp == p {
 			d.listeners[e] = append(listeners[:i], listeners[i+1:]...)
 		}
@@ -99,6 +228,8 @@ func (d *EventDispatcher) RemoveAll(e arievent.EventType) {
 }
 
 func (d *EventDispatcher) HasListeners(e arievent.EventType) bool {
+	d.RWMutex.RLock()
+	defer d.RWMutex.RUnlock()
 
 	listeners, ok := d.listeners[e]
 	if ok == false {
@@ -108,14 +239,37 @@ func (d *EventDispatcher) HasListeners(e arievent.EventType) bool {
 	return len(listeners) != 0
 }
 
+// Dispatch delivers the event to the listeners registered for its type,
+// according to the dispatch mode of the dispatcher
 func (d *EventDispatcher) Dispatch(e *arievent.StasisEvent) *arievent.StasisEvent {
 	d.RWMutex.RLock()
-	defer d.RWMutex.RUnlock()
+	mode := d.mode
+	taps := d.taps
+	listeners := make(listenersCollection, len(d.listeners[e.GetType()]))
+	copy(listeners, d.listeners[e.GetType()])
+	d.RWMutex.RUnlock()
+
+	// The listeners are called without holding the lock, so that they are
+	// free to add or remove listeners themselves.
+	for _, t := range taps {
+		t.listener(e)
+	}
 
-	for _, lst := range d.listeners[e.GetType()] {
-		d.workersPool.Submit(func() {
-			lst(e)
-		})
+	switch mode {
+	case Ordered:
+		for _, lst := range listeners {
+			lst.listener(e)
+
+			if e.IsPropagationStopped() {
+				break
+			}
+		}
+	default:
+		for _, lst := range listeners {
+			d.workersPool.Submit(func() {
+				lst.listener(e)
+			})
+		}
 	}
 
 	return e
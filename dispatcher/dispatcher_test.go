@@ -0,0 +1,167 @@
+package dispatcher
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+)
+
+func TestOrderedPriorities(t *testing.T) {
+	tests := []struct {
+		name       string
+		priorities []int
+		stopAt     int // index of the listener stopping the propagation, -1 for none
+		want       []int
+	}{
+		{"default priority keeps insertion order", []int{0, 0, 0}, -1, []int{0, 1, 2}},
+		{"higher priority first", []int{0, 10, 5}, -1, []int{1, 2, 0}},
+		{"negative priority last", []int{-1, 0, 1}, -1, []int{2, 1, 0}},
+		{"ties keep insertion order", []int{5, 1, 5}, -1, []int{0, 2, 1}},
+		{"stop propagation", []int{0, 10, 5}, 2, []int{1, 2}},
+		{"stop at first", []int{0, 10, 5}, 1, []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDispatcher(WithMode(Ordered))
+
+			var got []int
+			for i, p := range tt.priorities {
+				d.AddListenerWithPriority(arievent.StasisStart, func(e *arievent.StasisEvent) {
+					got = append(got, i)
+					if i == tt.stopAt {
+						e.StopPropagation()
+					}
+				}, p)
+			}
+
+			d.Dispatch(&arievent.StasisEvent{Type: arievent.StasisStart})
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got calls %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTapsRunFirst(t *testing.T) {
+	d := NewDispatcher(WithMode(Ordered))
+
+	var got []string
+	d.AddListenerWithPriority(arievent.StasisEnd, func(e *arievent.StasisEvent) {
+		got = append(got, "listener")
+	}, 100)
+	d.Tap(func(e *arievent.StasisEvent) {
+		got = append(got, "tap:"+string(e.GetType()))
+	})
+
+	d.Dispatch(&arievent.StasisEvent{Type: arievent.StasisEnd})
+	d.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelDestroyed})
+
+	want := []string{"tap:StasisEnd", "listener", "tap:ChannelDestroyed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSubscriptionCancel(t *testing.T) {
+	tests := []struct {
+		name   string
+		tap    bool
+		cancel bool
+		want   int
+	}{
+		{"subscription", false, false, 2},
+		{"cancelled subscription", false, true, 0},
+		{"tap", true, false, 2},
+		{"cancelled tap", true, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDispatcher(WithMode(Ordered))
+
+			count := 0
+			l := func(e *arievent.StasisEvent) { count++ }
+
+			var s *Subscription
+			if tt.tap {
+				s = d.Tap(l)
+			} else {
+				s = d.Subscribe(l, DefaultPriority, arievent.StasisStart, arievent.StasisEnd)
+			}
+
+			// A second registration of the same closure is unaffected
+			other := 0
+			d.AddListener(arievent.StasisStart, func(e *arievent.StasisEvent) { other++ })
+
+			if tt.cancel {
+				s.Cancel()
+				s.Cancel()
+			}
+
+			d.Dispatch(&arievent.StasisEvent{Type: arievent.StasisStart})
+			d.Dispatch(&arievent.StasisEvent{Type: arievent.StasisEnd})
+
+			if count != tt.want {
+				t.Errorf("listener called %d times, want %d", count, tt.want)
+			}
+			if other != 1 {
+				t.Errorf("other listener called %d times, want 1", other)
+			}
+		})
+	}
+}
+
+func TestTapsIgnoreStopPropagation(t *testing.T) {
+	d := NewDispatcher(WithMode(Ordered))
+
+	var got []string
+	d.AddListenerWithPriority(arievent.ChannelDtmfReceived, func(e *arievent.StasisEvent) {
+		got = append(got, "swallow")
+		e.StopPropagation()
+	}, 10)
+	d.Subscribe(func(e *arievent.StasisEvent) {
+		got = append(got, "subscription")
+	}, DefaultPriority, arievent.ChannelDtmfReceived)
+	d.Tap(func(e *arievent.StasisEvent) {
+		got = append(got, "tap")
+	})
+
+	d.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelDtmfReceived})
+
+	want := []string{"tap", "swallow"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParallelDeliversAll(t *testing.T) {
+	d := NewDispatcher()
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	for i := 0; i < 3; i++ {
+		d.AddListener(arievent.StasisStart, func(e *arievent.StasisEvent) {
+			wg.Done()
+		})
+	}
+
+	d.Dispatch(&arievent.StasisEvent{Type: arievent.StasisStart})
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listeners not called")
+	}
+}
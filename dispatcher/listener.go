@@ -1,6 +1,42 @@
 package dispatcher
 
-import "github.com/callevo/ari/arievent"
+import (
+	"sync"
+
+	"github.com/callevo/ari/arievent"
+)
 
 // Listener type for defining functions as listeners
 type Listener func(*arievent.StasisEvent)
+
+// Subscription is a listener registration on an EventDispatcher
+type Subscription struct {
+	d     *EventDispatcher
+	types []arievent.EventType
+	ids   []uint64
+	tap   bool
+
+	once sync.Once
+}
+
+// Cancel removes the listener registration from the dispatcher.  It is safe
+// to call Cancel more than once.
+func (s *Subscription) Cancel() {
+	if s == nil {
+		return
+	}
+
+	s.once.Do(func() {
+		s.d.RWMutex.Lock()
+		defer s.d.RWMutex.Unlock()
+
+		if s.tap {
+			s.d.taps = remove(s.d.taps, s.ids[0])
+			return
+		}
+
+		for i, t := range s.types {
+			s.d.listeners[t] = remove(s.d.listeners[t], s.ids[i])
+		}
+	})
+}
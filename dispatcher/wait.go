@@ -0,0 +1,177 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/key"
+)
+
+// hangupEvents are the events which indicate that a channel is gone
+var hangupEvents = []arievent.EventType{
+	arievent.StasisEnd,
+	arievent.ChannelDestroyed,
+	arievent.ChannelHangupRequest,
+}
+
+// WaitReason describes why a wait ended without a matching event
+type WaitReason string
+
+const (
+	// WaitHangup indicates that the channel was hung up or left the application
+	WaitHangup WaitReason = "hangup"
+
+	// WaitTimeout indicates that the deadline of the context expired
+	WaitTimeout WaitReason = "timeout"
+
+	// WaitCancelled indicates that the context was cancelled
+	WaitCancelled WaitReason = "cancelled"
+)
+
+// WaitError is the error returned when a wait ends without a matching event
+type WaitError struct {
+	// Reason is the reason the wait ended
+	Reason WaitReason
+
+	// Event is the event which ended the wait, for a hangup
+	Event *arievent.StasisEvent
+
+	err error
+}
+
+func (e *WaitError) Error() string {
+	if e.Event != nil {
+		return "wait ended by " + string(e.Reason) + " (" + string(e.Event.GetType()) + ")"
+	}
+
+	return "wait ended by " + string(e.Reason)
+}
+
+// Unwrap returns the context error which ended the wait, if any
+func (e *WaitError) Unwrap() error {
+	return e.err
+}
+
+// Timeout reports whether the wait ended because of a timeout
+func (e *WaitError) Timeout() bool {
+	return e.Reason == WaitTimeout
+}
+
+// IsHangup reports whether err is a WaitError caused by a hangup
+func IsHangup(err error) bool {
+	return waitReason(err) == WaitHangup
+}
+
+// IsTimeout reports whether err is a WaitError caused by a timeout
+func IsTimeout(err error) bool {
+	return waitReason(err) == WaitTimeout
+}
+
+// IsCancelled reports whether err is a WaitError caused by a cancellation
+func IsCancelled(err error) bool {
+	return waitReason(err) == WaitCancelled
+}
+
+func waitReason(err error) WaitReason {
+	var werr *WaitError
+	if errors.As(err, &werr) {
+		return werr.Reason
+	}
+
+	return ""
+}
+
+// WaitFor blocks until an event of one of the given types, relating to the
+// entity identified by the key, is dispatched.  It returns that event, or a
+// *WaitError when the context is done first.  For a channel key, the wait also
+// ends with a hangup WaitError when the channel is hung up or leaves the
+// application, unless the hangup event is one of the requested types.  The
+// events are seen in the order they are dispatched, before any listener, in
+// both dispatch modes; as with any tap, this includes the events a listener
+// stops.
+func (d *EventDispatcher) WaitFor(ctx context.Context, k *key.Key, types ...arievent.EventType) (*arievent.StasisEvent, error) {
+	wanted := make(map[arievent.EventType]bool)
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	ends := make(map[arievent.EventType]bool)
+	if k.GetKind() == key.ChannelKey {
+		for _, t := range hangupEvents {
+			ends[t] = true
+		}
+	}
+
+	w := newWaiter()
+
+	// The waiter is a tap rather than a listener: taps see the events in the
+	// order they arrive, whatever the dispatch mode, so a hangup following
+	// the awaited event cannot overtake it.
+	sub := d.Tap(func(e *arievent.StasisEvent) {
+		if (wanted[e.GetType()] || ends[e.GetType()]) && e.Matches(k) {
+			w.deliver(e)
+		}
+	})
+	defer sub.Cancel()
+
+	e, err := w.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !wanted[e.GetType()] {
+		return nil, &WaitError{Reason: WaitHangup, Event: e}
+	}
+
+	return e, nil
+}
+
+// WaitForMatch blocks until an event satisfying the predicate is dispatched,
+// and returns it, or a *WaitError when the context is done first.  The
+// predicate is called on the dispatching goroutine for every event, so it must
+// be cheap and must not block.
+func (d *EventDispatcher) WaitForMatch(ctx context.Context, match func(*arievent.StasisEvent) bool) (*arievent.StasisEvent, error) {
+	w := newWaiter()
+
+	sub := d.Tap(func(e *arievent.StasisEvent) {
+		if match(e) {
+			w.deliver(e)
+		}
+	})
+	defer sub.Cancel()
+
+	return w.wait(ctx)
+}
+
+// waiter holds the first event delivered to it
+type waiter struct {
+	ch   chan *arievent.StasisEvent
+	once sync.Once
+}
+
+func newWaiter() *waiter {
+	return &waiter{
+		ch: make(chan *arievent.StasisEvent, 1),
+	}
+}
+
+func (w *waiter) deliver(e *arievent.StasisEvent) {
+	w.once.Do(func() {
+		w.ch <- e
+	})
+}
+
+func (w *waiter) wait(ctx context.Context) (*arievent.StasisEvent, error) {
+	select {
+	case e := <-w.ch:
+		return e, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &WaitError{Reason: WaitTimeout, err: ctx.Err()}
+		}
+
+		return nil, &WaitError{Reason: WaitCancelled, err: ctx.Err()}
+	}
+}
@@ -0,0 +1,187 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/key"
+)
+
+func channelEvent(typ arievent.EventType, id string) *arievent.StasisEvent {
+	return &arievent.StasisEvent{Type: typ, Node: "ast1", Channel: arievent.ChannelData{ID: id}}
+}
+
+func TestWaitFor(t *testing.T) {
+	ch := key.NewKey(key.ChannelKey, "c1", key.WithNode("ast1"))
+
+	tests := []struct {
+		name   string
+		key    *key.Key
+		types  []arievent.EventType
+		events []*arievent.StasisEvent
+		want   arievent.EventType
+		reason WaitReason
+	}{
+		{
+			name:   "matching event",
+			key:    ch,
+			types:  []arievent.EventType{arievent.ChannelDtmfReceived},
+			events: []*arievent.StasisEvent{channelEvent(arievent.ChannelDtmfReceived, "c1")},
+			want:   arievent.ChannelDtmfReceived,
+		},
+		{
+			name:  "other channel ignored",
+			key:   ch,
+			types: []arievent.EventType{arievent.ChannelDtmfReceived},
+			events: []*arievent.StasisEvent{
+				channelEvent(arievent.ChannelDtmfReceived, "c2"),
+				channelEvent(arievent.ChannelHangupRequest, "c2"),
+				channelEvent(arievent.ChannelDtmfReceived, "c1"),
+			},
+			want: arievent.ChannelDtmfReceived,
+		},
+		{
+			name:  "other node ignored",
+			key:   ch,
+			types: []arievent.EventType{arievent.ChannelDtmfReceived},
+			events: []*arievent.StasisEvent{
+				{Type: arievent.ChannelDtmfReceived, Node: "ast2", Channel: arievent.ChannelData{ID: "c1"}},
+			},
+			reason: WaitTimeout,
+		},
+		{
+			name:  "hangup",
+			key:   ch,
+			types: []arievent.EventType{arievent.ChannelDtmfReceived},
+			events: []*arievent.StasisEvent{
+				channelEvent(arievent.StasisEnd, "c1"),
+				channelEvent(arievent.ChannelDtmfReceived, "c1"),
+			},
+			reason: WaitHangup,
+		},
+		{
+			name:  "matching event before hangup",
+			key:   ch,
+			types: []arievent.EventType{arievent.ChannelDtmfReceived},
+			events: []*arievent.StasisEvent{
+				channelEvent(arievent.ChannelDtmfReceived, "c1"),
+				channelEvent(arievent.StasisEnd, "c1"),
+			},
+			want: arievent.ChannelDtmfReceived,
+		},
+		{
+			name:   "awaited hangup event",
+			key:    ch,
+			types:  []arievent.EventType{arievent.StasisEnd},
+			events: []*arievent.StasisEvent{channelEvent(arievent.StasisEnd, "c1")},
+			want:   arievent.StasisEnd,
+		},
+		{
+			name:  "bridge key ignores channel hangup",
+			key:   key.NewKey(key.BridgeKey, "b1"),
+			types: []arievent.EventType{arievent.BridgeDestroyed},
+			events: []*arievent.StasisEvent{
+				channelEvent(arievent.StasisEnd, "b1"),
+				{Type: arievent.BridgeDestroyed, Bridge: &arievent.BridgeData{ID: "b1"}},
+			},
+			want: arievent.BridgeDestroyed,
+		},
+		{
+			name:   "timeout",
+			key:    ch,
+			types:  []arievent.EventType{arievent.ChannelDtmfReceived},
+			reason: WaitTimeout,
+		},
+	}
+
+	for _, mode := range []Mode{Parallel, Ordered} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				d := NewDispatcher(WithMode(mode))
+
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+
+				go func() {
+					// Give WaitFor the time to subscribe
+					time.Sleep(5 * time.Millisecond)
+
+					for _, e := range tt.events {
+						d.Dispatch(e)
+					}
+				}()
+
+				e, err := d.WaitFor(ctx, tt.key, tt.types...)
+
+				if tt.reason != "" {
+					if waitReason(err) != tt.reason {
+						t.Fatalf("got error %v, want %s", err, tt.reason)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("WaitFor: %s", err)
+				}
+				if e.GetType() != tt.want {
+					t.Errorf("got %s, want %s", e.GetType(), tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestWaitErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		hangup    bool
+		timeout   bool
+		cancelled bool
+	}{
+		{"hangup", &WaitError{Reason: WaitHangup}, true, false, false},
+		{"timeout", &WaitError{Reason: WaitTimeout, err: context.DeadlineExceeded}, false, true, false},
+		{"cancelled", &WaitError{Reason: WaitCancelled, err: context.Canceled}, false, false, true},
+		{"other error", context.Canceled, false, false, false},
+		{"nil", nil, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if IsHangup(tt.err) != tt.hangup {
+				t.Errorf("IsHangup = %v", !tt.hangup)
+			}
+			if IsTimeout(tt.err) != tt.timeout {
+				t.Errorf("IsTimeout = %v", !tt.timeout)
+			}
+			if IsCancelled(tt.err) != tt.cancelled {
+				t.Errorf("IsCancelled = %v", !tt.cancelled)
+			}
+		})
+	}
+}
+
+func TestWaitForMatch(t *testing.T) {
+	d := NewDispatcher()
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		d.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelVarset, Variable: "A"})
+		d.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelVarset, Variable: "B"})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	e, err := d.WaitForMatch(ctx, func(e *arievent.StasisEvent) bool {
+		return e.Variable == "B"
+	})
+	if err != nil {
+		t.Fatalf("WaitForMatch: %s", err)
+	}
+	if e.Variable != "B" {
+		t.Errorf("got variable %s, want B", e.Variable)
+	}
+}
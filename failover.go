@@ -0,0 +1,388 @@
+package ari
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/cluster"
+	"github.com/callevo/ari/health"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+	"github.com/callevo/ari/rid"
+	"github.com/rotisserie/eris"
+)
+
+// ErrNoNodes is returned by OriginateFailover when no node is available
+var ErrNoNodes = eris.New("no node available")
+
+// ErrOriginateUnconfirmed is returned by OriginateFailover when its context
+// ends while it waits to find out whether a failed attempt created a channel.
+// Trying the next node could then place the call twice.
+var ErrOriginateUnconfirmed = eris.New("could not confirm the originate attempt created no channel")
+
+// ErrNoApplication is returned by OriginateFailover for an originate into the
+// dialplan: the events of such a channel do not reach the client, so a failed
+// attempt could not be confirmed
+var ErrNoApplication = eris.New("failover originate requires an application")
+
+// DefaultAttemptTimeout is the default timeout of each originate attempt
+var DefaultAttemptTimeout = 5 * time.Second
+
+// FailoverOptions configures OriginateFailover
+type FailoverOptions struct {
+	// Nodes is the ranked list of nodes to try.  When empty, the cluster
+	// members of the application which satisfy Filters are tried, healthiest
+	// and least loaded first.
+	Nodes []string
+
+	// Filters select the cluster members to try when Nodes is empty
+	Filters []cluster.Filter
+
+	// MaxAge excludes the cluster members not heard of for longer, when Nodes
+	// is empty.  Zero accepts any member.
+	MaxAge time.Duration
+
+	// AttemptTimeout bounds each attempt.  Defaults to DefaultAttemptTimeout.
+	AttemptTimeout time.Duration
+
+	// ConfirmTimeout is how long a failed attempt is watched for the events
+	// of its channel before it is deemed not to have created it, when its
+	// node cannot tell either.  Defaults to twice AttemptTimeout.
+	ConfirmTimeout time.Duration
+}
+
+// OriginateAttempt describes one attempt of OriginateFailover
+type OriginateAttempt struct {
+	Node      string
+	ChannelID string
+
+	Started  time.Time
+	Duration time.Duration
+
+	// Err is the failure of the attempt, nil for the successful attempt
+	Err error
+
+	// Recovered is set when the originate request failed but the channel
+	// turned out to exist on the node
+	Recovered bool
+}
+
+// FailoverResult is the outcome of OriginateFailover
+type FailoverResult struct {
+	// Handle is the originated channel, nil if every attempt failed
+	Handle *channel.ChannelHandle
+
+	// Attempts lists every attempt, in order
+	Attempts []OriginateAttempt
+}
+
+// OriginateFailover originates the channel on the first node which accepts it,
+// trying the nodes in order.  The channel must enter an application, see
+// ErrNoApplication.  Each attempt uses a new channel ID, whose events the
+// client subscribes to before sending the request.  A node which answers the
+// request with an error created no channel, and the next node is tried at
+// once.  A node which does not answer in time may still create the channel:
+// the node is asked for the channel, and when it does not answer either, the
+// next node is only tried once no event of the channel was seen within
+// ConfirmTimeout.  If the channel exists, the attempt is a success after all.
+// A channel which shows up after its attempt was given up is hung up, so that
+// the call is not placed twice.  If the context ends while an attempt is being
+// confirmed, OriginateFailover stops with ErrOriginateUnconfirmed.  The result
+// holds every attempt, also on error.  As
+// with Originate, a client sharing its queue group must enable
+// Options.OriginateClaims, or the other members of the group also take the
+// channel.
+func (a *ARIClient) OriginateFailover(ctx context.Context, o requests.OriginateRequest, opts *FailoverOptions) (*FailoverResult, error) {
+	if opts == nil {
+		opts = &FailoverOptions{}
+	}
+
+	result := &FailoverResult{}
+
+	app := o.App
+	if app == "" {
+		return result, ErrNoApplication
+	}
+
+	timeout := opts.AttemptTimeout
+	if timeout <= 0 {
+		timeout = DefaultAttemptTimeout
+	}
+
+	nodes := opts.Nodes
+	if len(nodes) == 0 {
+		nodes = a.rankNodes(app, opts)
+	}
+
+	if len(nodes) == 0 {
+		return result, ErrNoNodes
+	}
+
+	confirm := opts.ConfirmTimeout
+	if confirm <= 0 {
+		confirm = 2 * timeout
+	}
+
+	ch := &ichannel{c: a}
+
+	for _, node := range nodes {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		o.ChannelID = rid.New(rid.Channel)
+		if o.OtherChannelID != "" {
+			o.OtherChannelID = rid.New(rid.Channel)
+		}
+
+		attempt := OriginateAttempt{
+			Node:      node,
+			ChannelID: o.ChannelID,
+			Started:   time.Now(),
+		}
+
+		referenceKey := key.NewKey(key.ChannelKey, "", key.WithApp(app), key.WithNode(node))
+		k := referenceKey.New(key.ChannelKey, o.ChannelID)
+
+		w, err := a.watchAttempt(k)
+		if err != nil {
+			attempt.Err = err
+			result.Attempts = append(result.Attempts, attempt)
+			return result, err
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		h, p, err := ch.originate(attemptCtx, referenceKey, o)
+		cancel()
+
+		attempt.Duration = time.Since(attempt.Started)
+
+		if err == nil {
+			w.release()
+
+			result.Handle = h
+			result.Attempts = append(result.Attempts, attempt)
+			return result, nil
+		}
+
+		attempt.Err = err
+
+		// A request refused by the circuit breaker never reached the node,
+		// and a node which answered with an error created no channel
+		var answered *response.Error
+		if eris.Is(err, health.ErrOpen) || errors.As(err, &answered) {
+			w.release()
+			if p != nil {
+				a.untrackOriginate(p)
+			}
+
+			result.Attempts = append(result.Attempts, attempt)
+			continue
+		}
+
+		exists, err := a.confirmAttempt(ctx, w, timeout, confirm)
+		if err != nil {
+			w.abandon()
+
+			result.Attempts = append(result.Attempts, attempt)
+			return result, eris.Wrapf(ErrOriginateUnconfirmed, "node %s: %s", node, err)
+		}
+
+		if exists {
+			w.release()
+
+			attempt.Recovered = true
+			result.Attempts = append(result.Attempts, attempt)
+
+			if p != nil {
+				result.Handle = channel.NewOriginatedChannelHandle(k, ch, nil, p.started)
+			} else {
+				result.Handle = channel.NewChannelHandle(k, ch, nil)
+			}
+
+			return result, nil
+		}
+
+		// The claims of the attempt expire with ClaimTTL, so that a channel
+		// showing up late is still kept from the queue group until it is
+		// hung up
+		w.abandon()
+
+		result.Attempts = append(result.Attempts, attempt)
+	}
+
+	return result, eris.Wrap(result.Attempts[len(result.Attempts)-1].Err, "every originate attempt failed")
+}
+
+// confirmAttempt reports whether the channel of an attempt whose node did not
+// answer was created.  The node is asked for the channel first; when it does
+// not answer, the events of the channel are awaited for up to confirm.
+func (a *ARIClient) confirmAttempt(ctx context.Context, w *attemptWatch, timeout, confirm time.Duration) (bool, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	resp, err := a.makeRequestContext(queryCtx, "data", &requests.Request{
+		Kind: "ChannelData",
+		Key:  w.k,
+	})
+	cancel()
+
+	if err == nil && resp != nil {
+		if resp.IsNotFound() {
+			return false, nil
+		}
+
+		if resp.Err() == nil && resp.Data != nil && resp.Data.Channel != nil {
+			return true, nil
+		}
+	}
+
+	logs.TLogger.Debug().Msgf("node %s cannot tell whether channel %s exists, watching its events", w.k.GetNode(), w.k.GetID())
+
+	return w.created(ctx, confirm)
+}
+
+// attemptWatch follows the events of the channel of an originate attempt
+type attemptWatch struct {
+	a *ARIClient
+	k *key.Key
+
+	lease *topicLease
+
+	// events receives the type of the first event of the channel
+	events chan arievent.EventType
+	once   sync.Once
+
+	mu        sync.Mutex
+	abandoned bool
+	hungUp    bool
+}
+
+// watchAttempt subscribes to the events of the channel, before it is
+// originated
+func (a *ARIClient) watchAttempt(k *key.Key) (*attemptWatch, error) {
+	w := &attemptWatch{
+		a:      a,
+		k:      k,
+		events: make(chan arievent.EventType, 1),
+	}
+
+	lease, err := a.acquireTopic(a.resourceTopic(k.GetApp(), k.GetNode(), k.GetID()), w.observe)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to subscribe to the originated channel")
+	}
+
+	w.lease = lease
+
+	return w, nil
+}
+
+// observe records the first event of the channel, and hangs up the channel if
+// its attempt was given up
+func (w *attemptWatch) observe(e *arievent.StasisEvent) {
+	if e.Channel.ID != w.k.GetID() {
+		return
+	}
+
+	w.once.Do(func() {
+		w.events <- e.GetType()
+	})
+
+	if e.GetType() == arievent.ChannelDestroyed || e.GetType() == arievent.StasisEnd {
+		return
+	}
+
+	w.mu.Lock()
+	hangup := w.abandoned && !w.hungUp
+	if hangup {
+		w.hungUp = true
+	}
+	w.mu.Unlock()
+
+	if !hangup {
+		return
+	}
+
+	logs.TLogger.Warn().Msgf("channel %s of an abandoned originate attempt showed up on %s, hanging up", w.k.GetID(), w.k.GetNode())
+
+	go func() {
+		if err := w.a.Channel().Hangup(w.k, arievent.HangupNormal); err != nil {
+			logs.TLogger.Debug().Msgf("failed to hang up channel %s: %s", w.k.GetID(), err)
+		}
+	}()
+}
+
+// created waits until an event of the channel shows up or the timeout
+// expires, and reports whether the channel was created and is still alive.  It
+// fails if the context ends first.
+func (w *attemptWatch) created(ctx context.Context, timeout time.Duration) (bool, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case typ := <-w.events:
+		return typ != arievent.ChannelDestroyed && typ != arievent.StasisEnd, nil
+	case <-timer.C:
+		return false, nil
+	case <-ctx.Done():
+		return false, context.Cause(ctx)
+	}
+}
+
+// abandon gives up the attempt.  The channel is still watched until ClaimTTL,
+// and hung up if it shows up.
+func (w *attemptWatch) abandon() {
+	w.mu.Lock()
+	w.abandoned = true
+	w.mu.Unlock()
+
+	time.AfterFunc(ClaimTTL, w.release)
+}
+
+// release stops watching the channel
+func (w *attemptWatch) release() {
+	w.lease.Release()
+}
+
+// rankNodes returns the cluster members of the application, healthiest and
+// least loaded first
+func (a *ARIClient) rankNodes(app string, opts *FailoverOptions) []string {
+	if a.cluster == nil {
+		return nil
+	}
+
+	maxAge := opts.MaxAge
+	if maxAge == 0 {
+		maxAge = cluster.AnyAge
+	}
+
+	members := a.cluster.Matching("", app, maxAge, opts.Filters...)
+
+	score := func(node string) float64 {
+		if a.health == nil {
+			return 1
+		}
+		return a.health.Score(node)
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		si, sj := score(members[i].ID), score(members[j].ID)
+		if si != sj {
+			return si > sj
+		}
+
+		return members[i].Load() < members[j].Load()
+	})
+
+	nodes := make([]string, 0, len(members))
+	for _, m := range members {
+		nodes = append(nodes, m.ID)
+	}
+
+	return nodes
+}
@@ -0,0 +1,310 @@
+package ari
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/health"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+)
+
+// errNoResponders stands for the failure of a request to a node which went
+// away
+var errNoResponders = errors.New("nats: no responders available for request")
+
+func TestOriginateFailover(t *testing.T) {
+	// node describes how a node answers the originate request: with the
+	// failure err, with the error answer, or with the channel.  event, if
+	// set, is published for the channel before the node answers.  data is
+	// how the node answers when asked for the channel: found, not found, or
+	// not at all.
+	type node struct {
+		err    error
+		answer string
+		event  arievent.EventType
+		data   string
+	}
+
+	tests := []struct {
+		name      string
+		app       string
+		nodes     map[string]node
+		open      string
+		confirm   time.Duration
+		ctx       time.Duration
+		want      string
+		attempts  int
+		recovered bool
+		queries   int
+		wantErr   error
+	}{
+		{
+			name:     "first node",
+			nodes:    map[string]node{"ast1": {}},
+			want:     "ast1",
+			attempts: 1,
+		},
+		{
+			name:      "timeout, channel created",
+			nodes:     map[string]node{"ast1": {err: context.DeadlineExceeded, event: arievent.ChannelStateChange}, "ast2": {}},
+			want:      "ast1",
+			attempts:  1,
+			recovered: true,
+			queries:   1,
+		},
+		{
+			name:      "timeout, channel entered the application",
+			nodes:     map[string]node{"ast1": {err: context.DeadlineExceeded, event: arievent.StasisStart}, "ast2": {}},
+			want:      "ast1",
+			attempts:  1,
+			recovered: true,
+			queries:   1,
+		},
+		{
+			name:      "timeout, channel of another application created",
+			app:       "other",
+			nodes:     map[string]node{"ast1": {err: context.DeadlineExceeded, event: arievent.ChannelCreated}, "ast2": {}},
+			want:      "ast1",
+			attempts:  1,
+			recovered: true,
+			queries:   1,
+		},
+		{
+			name:     "timeout, no channel",
+			nodes:    map[string]node{"ast1": {err: context.DeadlineExceeded}, "ast2": {}},
+			want:     "ast2",
+			attempts: 2,
+			queries:  1,
+		},
+		{
+			name:     "timeout, node reports no channel",
+			nodes:    map[string]node{"ast1": {err: context.DeadlineExceeded, data: "not found"}, "ast2": {}},
+			confirm:  time.Hour,
+			want:     "ast2",
+			attempts: 2,
+			queries:  1,
+		},
+		{
+			name:      "timeout, node reports the channel",
+			nodes:     map[string]node{"ast1": {err: context.DeadlineExceeded, data: "found"}, "ast2": {}},
+			confirm:   time.Hour,
+			want:      "ast1",
+			attempts:  1,
+			recovered: true,
+			queries:   1,
+		},
+		{
+			name:     "error answer",
+			nodes:    map[string]node{"ast1": {answer: "Allocation failed"}, "ast2": {}},
+			confirm:  time.Hour,
+			want:     "ast2",
+			attempts: 2,
+		},
+		{
+			name:     "timeout, channel destroyed",
+			nodes:    map[string]node{"ast1": {err: context.DeadlineExceeded, event: arievent.ChannelDestroyed}, "ast2": {}},
+			want:     "ast2",
+			attempts: 2,
+			queries:  1,
+		},
+		{
+			name:     "dead node",
+			nodes:    map[string]node{"ast1": {err: errNoResponders}, "ast2": {}},
+			want:     "ast2",
+			attempts: 2,
+			queries:  1,
+		},
+		{
+			name:     "breaker open",
+			nodes:    map[string]node{"ast1": {}, "ast2": {}},
+			open:     "ast1",
+			confirm:  time.Hour,
+			want:     "ast2",
+			attempts: 2,
+		},
+		{
+			name:     "every node failed",
+			nodes:    map[string]node{"ast1": {err: errNoResponders}, "ast2": {err: errNoResponders}},
+			attempts: 2,
+			queries:  2,
+			wantErr:  errNoResponders,
+		},
+		{
+			name:     "context ends while confirming",
+			nodes:    map[string]node{"ast1": {err: errNoResponders}, "ast2": {}},
+			confirm:  time.Hour,
+			ctx:      20 * time.Millisecond,
+			attempts: 1,
+			queries:  1,
+			wantErr:  ErrOriginateUnconfirmed,
+		},
+		{
+			name:    "dialplan originate",
+			app:     "-",
+			nodes:   map[string]node{"ast1": {}},
+			wantErr: ErrNoApplication,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+
+			app := tt.app
+			switch app {
+			case "":
+				app = "app"
+			case "-":
+				app = ""
+			}
+
+			bus.respond = func(_ string, r *requests.Request) (*response.Response, error) {
+				n := tt.nodes[r.Key.GetNode()]
+
+				if r.Kind == "ChannelData" {
+					switch n.data {
+					case "found":
+						return &response.Response{Data: &response.EntityData{Channel: &channel.ChannelData{ID: r.Key.GetID()}}}, nil
+					case "not found":
+						return errorResponse("Not found"), nil
+					}
+
+					return nil, errNoResponders
+				}
+
+				id := r.ChannelOriginate.OriginateRequest.ChannelID
+
+				if n.event != "" {
+					bus.publish(a.resourceTopic(app, r.Key.GetNode(), id), &arievent.StasisEvent{Type: n.event, Node: r.Key.GetNode(), Channel: arievent.ChannelData{ID: id}})
+				}
+				if n.err != nil {
+					return nil, n.err
+				}
+				if n.answer != "" {
+					return errorResponse(n.answer), nil
+				}
+
+				return &response.Response{Key: r.Key.New(key.ChannelKey, id)}, nil
+			}
+
+			if tt.open != "" {
+				a.health = health.NewRegistry(&health.Options{FailureThreshold: 1, OpenTimeout: time.Hour})
+				ticket, _ := a.health.Allow(tt.open)
+				a.health.Record(tt.open, ticket, 0, errNoResponders)
+			}
+
+			confirm := tt.confirm
+			if confirm == 0 {
+				confirm = 20 * time.Millisecond
+			}
+
+			ctx := context.Background()
+			if tt.ctx > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctx)
+				defer cancel()
+			}
+
+			res, err := a.OriginateFailover(ctx, requests.OriginateRequest{Endpoint: "PJSIP/100", App: app}, &FailoverOptions{
+				Nodes:          []string{"ast1", "ast2"},
+				ConfirmTimeout: confirm,
+			})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("OriginateFailover: %s", err)
+			}
+
+			if len(res.Attempts) != tt.attempts {
+				t.Fatalf("%d attempts, want %d", len(res.Attempts), tt.attempts)
+			}
+
+			// Only the nodes which did not answer are asked for the channel
+			if queries := bus.count("ChannelData"); queries != tt.queries {
+				t.Errorf("%d channel queries, want %d", queries, tt.queries)
+			}
+
+			if tt.attempts == 0 {
+				if len(bus.sent()) != 0 {
+					t.Errorf("sent %v", bus.sent())
+				}
+				return
+			}
+
+			if tt.want != "" {
+				if res.Handle == nil || res.Handle.Key().GetNode() != tt.want {
+					t.Fatalf("handle = %v, want a channel on %s", res.Handle, tt.want)
+				}
+			}
+
+			if last := res.Attempts[len(res.Attempts)-1]; last.Recovered != tt.recovered {
+				t.Errorf("recovered = %v, want %v", last.Recovered, tt.recovered)
+			}
+		})
+	}
+}
+
+func TestOriginateFailoverHangsUpLateChannel(t *testing.T) {
+	bus := newFakeBus()
+	a := newTestClient(bus)
+
+	bus.respond = func(_ string, r *requests.Request) (*response.Response, error) {
+		switch r.Kind {
+		case "ChannelOriginate":
+			if r.Key.GetNode() == "ast1" {
+				return nil, context.DeadlineExceeded
+			}
+			return &response.Response{Key: r.Key.New(key.ChannelKey, r.ChannelOriginate.OriginateRequest.ChannelID)}, nil
+		}
+
+		return &response.Response{}, nil
+	}
+
+	res, err := a.OriginateFailover(context.Background(), requests.OriginateRequest{Endpoint: "PJSIP/100", App: "app"}, &FailoverOptions{
+		Nodes:          []string{"ast1", "ast2"},
+		ConfirmTimeout: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("OriginateFailover: %s", err)
+	}
+
+	late := res.Attempts[0].ChannelID
+
+	// The abandoned channel is still claimed, so that the queue group does
+	// not take it
+	if !a.isClaimed(late) {
+		t.Error("abandoned channel not claimed")
+	}
+
+	bus.publish(a.resourceTopic("app", "ast1", late), &arievent.StasisEvent{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: late}})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		var hungUp bool
+		bus.mu.Lock()
+		for _, r := range bus.requests {
+			if r.Kind == "ChannelHangup" && r.Key.GetID() == late && r.Key.GetNode() == "ast1" {
+				hungUp = true
+			}
+		}
+		bus.mu.Unlock()
+
+		if hungUp {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("late channel not hung up")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,316 @@
+// Package gather plays a prompt to a channel and collects the DTMF digits
+// entered in response
+package gather
+
+import (
+	"context"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/play"
+	"github.com/rotisserie/eris"
+)
+
+// Channel is the channel the digits are gathered from, such as a
+// channel.ChannelHandle
+type Channel interface {
+	play.Player
+	play.Subscriber
+}
+
+// Reason describes why the gathering of digits ended
+type Reason int
+
+const (
+	// Complete indicates that the maximum number of digits was entered
+	Complete Reason = iota
+
+	// Terminated indicates that a terminator digit was entered
+	Terminated
+
+	// DigitTimeout indicates that no further digit was entered in time,
+	// after at least one digit
+	DigitTimeout
+
+	// Timeout indicates that no digit was entered, in time or before a
+	// terminator, on every attempt
+	Timeout
+
+	// Invalid indicates that the input was rejected on every attempt
+	Invalid
+
+	// Hangup indicates that the channel hung up
+	Hangup
+
+	// Cancelled indicates that the context was cancelled
+	Cancelled
+
+	// Failed indicates that a prompt could not be played
+	Failed
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Complete:
+		return "complete"
+	case Terminated:
+		return "terminated"
+	case DigitTimeout:
+		return "inter-digit timeout"
+	case Timeout:
+		return "timeout"
+	case Invalid:
+		return "invalid"
+	case Hangup:
+		return "hangup"
+	case Cancelled:
+		return "cancelled"
+	case Failed:
+		return "failed"
+	}
+
+	return "unknown"
+}
+
+// Result represents the result of Gather
+type Result struct {
+	// Digits holds the digits entered on the last attempt, without the
+	// terminator
+	Digits string
+
+	// Terminator holds the terminator digit which ended the input, if any
+	Terminator string
+
+	// Reason indicates why the gathering ended
+	Reason Reason
+
+	// Attempts is the number of times the input was asked
+	Attempts int
+
+	// Duration indicates the duration of the gathering
+	Duration time.Duration
+
+	// Error holds any error encountered during the gathering
+	Error error
+}
+
+// Valid reports whether the digits were accepted.  They are on completion, on
+// a terminator and on an inter-digit timeout.
+func (r *Result) Valid() bool {
+	switch r.Reason {
+	case Complete, Terminated, DigitTimeout:
+		return true
+	}
+
+	return false
+}
+
+// Gather plays the prompt configured with the Prompt option to the channel and
+// collects the digits entered in response.  Unless NoBargeIn is set, the first
+// digit stops the prompt.  Missing or rejected input is asked again up to the
+// number of Retries, after playing the RetryPrompt.
+func Gather(ctx context.Context, ch Channel, opts ...OptionFunc) (*Result, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+
+	g := &gathering{
+		options: o,
+		events:  make(chan *arievent.StasisEvent, 16),
+		doneCh:  make(chan struct{}),
+		res:     new(Result),
+	}
+	defer close(g.doneCh)
+
+	started := time.Now()
+
+	// Subscribe before playing the prompt, so that no digit is missed
+	sub := ch.Subscribe(g.deliver,
+		arievent.ChannelDtmfReceived,
+		arievent.ChannelHangupRequest,
+		arievent.ChannelDestroyed,
+		arievent.StasisEnd,
+	)
+	defer sub.Cancel()
+
+	for g.res.Attempts <= o.retries {
+		if g.res.Attempts > 0 && len(o.retryPrompt) > 0 {
+			if !g.prompt(ctx, ch, o.retryPrompt, false) {
+				break
+			}
+		}
+
+		g.res.Attempts++
+
+		if !g.collect(ctx, ch) {
+			break
+		}
+
+		if g.accept() {
+			break
+		}
+
+		logs.TLogger.Debug().Msgf("gathered input %s rejected (%s), attempt %d", o.logged(g.res.Digits), g.res.Reason, g.res.Attempts)
+	}
+
+	g.res.Duration = time.Since(started)
+
+	logs.TLogger.Debug().Msgf("gathered %s: %s", o.logged(g.res.Digits), g.res.Reason)
+
+	return g.res, g.res.Error
+}
+
+type gathering struct {
+	options *Options
+
+	// events receives the DTMF and hangup events of the channel
+	events chan *arievent.StasisEvent
+
+	doneCh chan struct{}
+
+	res *Result
+}
+
+// deliver queues an event for the gathering, unless the gathering is over
+func (g *gathering) deliver(e *arievent.StasisEvent) {
+	select {
+	case g.events <- e:
+	case <-g.doneCh:
+	}
+}
+
+// accept validates the input of the last attempt and reports whether the
+// gathering is over
+func (g *gathering) accept() bool {
+	if !g.res.Valid() {
+		return false
+	}
+
+	o := g.options
+
+	if len(g.res.Digits) < o.minDigits || (o.validate != nil && !o.validate(g.res.Digits)) {
+		g.res.Reason = Invalid
+		return false
+	}
+
+	return true
+}
+
+// drain discards the digits entered before the attempt started
+func (g *gathering) drain() bool {
+	for {
+		select {
+		case e := <-g.events:
+			if e.GetType() != arievent.ChannelDtmfReceived {
+				g.res.Reason = Hangup
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+// prompt plays the media URIs to the channel.  When bargeIn is set, a digit
+// stops the playback and remains queued as input.  It returns false when the
+// gathering must end.
+func (g *gathering) prompt(ctx context.Context, ch Channel, uris []string, bargeIn bool) bool {
+	stopOn := "none"
+	if bargeIn {
+		stopOn = "any"
+	}
+
+	res, err := play.Play(ctx, ch, play.URI(uris...), play.StopOnDTMF(stopOn)).Result()
+
+	switch res.Status {
+	case play.Finished, play.DTMF:
+		return true
+	case play.Hangup:
+		g.res.Reason = Hangup
+	case play.Cancelled:
+		g.res.Reason = Cancelled
+		g.res.Error = ctx.Err()
+	default:
+		g.res.Reason = Failed
+		g.res.Error = eris.Wrap(err, "failed to play prompt")
+		if g.res.Error == nil {
+			g.res.Error = eris.Errorf("failed to play prompt: %s", res.Status)
+		}
+	}
+
+	return false
+}
+
+// collect plays the prompt and collects the digits of one attempt.  It returns
+// false when the gathering must end.
+// nolint: gocyclo
+func (g *gathering) collect(ctx context.Context, ch Channel) bool {
+	o := g.options
+
+	g.res.Digits = ""
+	g.res.Terminator = ""
+
+	if !g.drain() {
+		return false
+	}
+
+	if len(o.prompt) > 0 {
+		if !g.prompt(ctx, ch, o.prompt, !o.noBargeIn) {
+			return false
+		}
+
+		// Without barge-in, the digits entered during the prompt are ignored
+		if o.noBargeIn && !g.drain() {
+			return false
+		}
+	}
+
+	timer := time.NewTimer(o.firstDigitTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			g.res.Reason = Cancelled
+			g.res.Error = ctx.Err()
+
+			return false
+		case <-timer.C:
+			if g.res.Digits == "" {
+				g.res.Reason = Timeout
+			} else {
+				g.res.Reason = DigitTimeout
+			}
+
+			return true
+		case e := <-g.events:
+			if e.GetType() != arievent.ChannelDtmfReceived {
+				g.res.Reason = Hangup
+				return false
+			}
+
+			if o.isTerminator(e.Digit) {
+				g.res.Terminator = e.Digit
+				g.res.Reason = Terminated
+
+				// A terminator alone is no input, unless the empty
+				// input is allowed
+				if g.res.Digits == "" && o.minDigits > 0 {
+					g.res.Reason = Timeout
+				}
+
+				return true
+			}
+
+			g.res.Digits += e.Digit
+
+			if o.maxDigits > 0 && len(g.res.Digits) >= o.maxDigits {
+				g.res.Reason = Complete
+				return true
+			}
+
+			timer.Stop()
+			timer.Reset(o.interDigitTimeout)
+		}
+	}
+}
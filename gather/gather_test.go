@@ -0,0 +1,260 @@
+package gather
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/play"
+)
+
+// fakeChannel plays to channel c1.  The nth playback of a media URI emits the
+// nth scripted list of events of the URI, or the last one.  Channel events
+// carry the digit, if any.
+type fakeChannel struct {
+	d      *dispatcher.EventDispatcher
+	script map[string][][]arievent.StasisEvent
+
+	mu     sync.Mutex
+	played map[string]int
+}
+
+func newFakeChannel(script map[string][][]arievent.StasisEvent) *fakeChannel {
+	return &fakeChannel{
+		d:      dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered)),
+		script: script,
+		played: make(map[string]int),
+	}
+}
+
+func subscribeKey(d *dispatcher.EventDispatcher, k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return d.Subscribe(func(e *arievent.StasisEvent) {
+		if e.Matches(k) {
+			l(e)
+		}
+	}, dispatcher.DefaultPriority, types...)
+}
+
+func (c *fakeChannel) Play(id, uri string) (*play.PlaybackHandle, error) {
+	return play.NewPlaybackHandle(key.NewKey(key.PlaybackKey, id), &fakePlayback{c}, nil), nil
+}
+
+func (c *fakeChannel) StagePlay(id, uri string) (*play.PlaybackHandle, error) {
+	return play.NewPlaybackHandle(key.NewKey(key.PlaybackKey, id), &fakePlayback{c}, func(h *play.PlaybackHandle) error {
+		c.mu.Lock()
+		n := c.played[uri]
+		c.played[uri]++
+		c.mu.Unlock()
+
+		var steps []arievent.StasisEvent
+		if scripts := c.script[uri]; len(scripts) > 0 {
+			steps = scripts[min(n, len(scripts)-1)]
+		}
+
+		go c.emit(id, steps)
+
+		return nil
+	}), nil
+}
+
+func (c *fakeChannel) Subscribe(l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return subscribeKey(c.d, key.NewKey(key.ChannelKey, "c1"), l, types...)
+}
+
+func (c *fakeChannel) plays(uri string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.played[uri]
+}
+
+func (c *fakeChannel) emit(id string, steps []arievent.StasisEvent) {
+	for _, s := range steps {
+		e := s
+		if s.Playback != nil {
+			e.Playback = &arievent.PlaybackData{ID: id, State: s.Playback.State}
+		} else {
+			e.Channel = arievent.ChannelData{ID: "c1"}
+		}
+
+		c.d.Dispatch(&e)
+	}
+}
+
+type fakePlayback struct {
+	c *fakeChannel
+}
+
+func (pb *fakePlayback) Get(k *key.Key) *play.PlaybackHandle {
+	return play.NewPlaybackHandle(k, pb, nil)
+}
+
+func (pb *fakePlayback) Data(k *key.Key) (*play.PlaybackData, error) {
+	return &play.PlaybackData{ID: k.GetID()}, nil
+}
+
+func (pb *fakePlayback) Control(k *key.Key, op string) error { return nil }
+
+func (pb *fakePlayback) Stop(k *key.Key) error { return nil }
+
+func (pb *fakePlayback) Subscribe(k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return subscribeKey(pb.c.d, k, l, types...)
+}
+
+var (
+	started  = arievent.StasisEvent{Type: arievent.PlaybackStarted, Playback: &arievent.PlaybackData{}}
+	finished = arievent.StasisEvent{Type: arievent.PlaybackFinished, Playback: &arievent.PlaybackData{State: "done"}}
+	hangup   = arievent.StasisEvent{Type: arievent.ChannelHangupRequest}
+)
+
+// prompt returns the events of a prompt during which the digits are entered
+func prompt(digits string, tail ...arievent.StasisEvent) []arievent.StasisEvent {
+	steps := []arievent.StasisEvent{started}
+	for _, d := range digits {
+		steps = append(steps, arievent.StasisEvent{Type: arievent.ChannelDtmfReceived, Digit: string(d)})
+	}
+
+	return append(steps, tail...)
+}
+
+func TestGather(t *testing.T) {
+	short := 20 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		prompts  [][]arievent.StasisEvent
+		opts     []OptionFunc
+		cancel   bool
+		digits   string
+		reason   Reason
+		attempts int
+		retried  int
+		valid    bool
+		wantErr  bool
+	}{
+		{
+			name:     "complete",
+			prompts:  [][]arievent.StasisEvent{prompt("123")},
+			opts:     []OptionFunc{MaxDigits(3)},
+			digits:   "123",
+			reason:   Complete,
+			attempts: 1,
+			valid:    true,
+		},
+		{
+			name:     "terminated",
+			prompts:  [][]arievent.StasisEvent{prompt("12#")},
+			digits:   "12",
+			reason:   Terminated,
+			attempts: 1,
+			valid:    true,
+		},
+		{
+			name:     "terminator alone",
+			prompts:  [][]arievent.StasisEvent{prompt("#")},
+			reason:   Timeout,
+			attempts: 1,
+		},
+		{
+			name:     "terminator alone allowed",
+			prompts:  [][]arievent.StasisEvent{prompt("#")},
+			opts:     []OptionFunc{MinDigits(0)},
+			reason:   Terminated,
+			attempts: 1,
+			valid:    true,
+		},
+		{
+			name:     "inter-digit timeout",
+			prompts:  [][]arievent.StasisEvent{prompt("4")},
+			opts:     []OptionFunc{InterDigitTimeout(short)},
+			digits:   "4",
+			reason:   DigitTimeout,
+			attempts: 1,
+			valid:    true,
+		},
+		{
+			name:     "first digit timeout",
+			prompts:  [][]arievent.StasisEvent{prompt("", finished)},
+			opts:     []OptionFunc{FirstDigitTimeout(short), Retries(1)},
+			reason:   Timeout,
+			attempts: 2,
+		},
+		{
+			name:     "valid on retry",
+			prompts:  [][]arievent.StasisEvent{prompt("1#"), prompt("42#")},
+			opts:     []OptionFunc{Retries(2), RetryPrompt("sound:retry"), Validate(func(d string) bool { return d == "42" })},
+			digits:   "42",
+			reason:   Terminated,
+			attempts: 2,
+			retried:  1,
+			valid:    true,
+		},
+		{
+			name:     "too short on every attempt",
+			prompts:  [][]arievent.StasisEvent{prompt("1#")},
+			opts:     []OptionFunc{MinDigits(3), Retries(1)},
+			digits:   "1",
+			reason:   Invalid,
+			attempts: 2,
+		},
+		{
+			name:     "hangup",
+			prompts:  [][]arievent.StasisEvent{prompt("", hangup)},
+			reason:   Hangup,
+			attempts: 1,
+		},
+		{
+			name:     "no barge-in",
+			prompts:  [][]arievent.StasisEvent{prompt("9", finished)},
+			opts:     []OptionFunc{NoBargeIn(), FirstDigitTimeout(short)},
+			reason:   Timeout,
+			attempts: 1,
+		},
+		{
+			name:     "cancelled",
+			prompts:  [][]arievent.StasisEvent{prompt("", finished)},
+			cancel:   true,
+			reason:   Cancelled,
+			attempts: 1,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := newFakeChannel(map[string][][]arievent.StasisEvent{
+				"sound:prompt": tt.prompts,
+				"sound:retry":  {{started, finished}},
+			})
+
+			ctx := context.Background()
+			if tt.cancel {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, short)
+				defer cancel()
+			}
+
+			res, err := Gather(ctx, ch, append([]OptionFunc{Prompt("sound:prompt")}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if res.Digits != tt.digits || res.Reason != tt.reason {
+				t.Errorf("gathered %q (%s), want %q (%s)", res.Digits, res.Reason, tt.digits, tt.reason)
+			}
+			if res.Attempts != tt.attempts {
+				t.Errorf("%d attempts, want %d", res.Attempts, tt.attempts)
+			}
+			if res.Valid() != tt.valid {
+				t.Errorf("valid = %v, want %v", res.Valid(), tt.valid)
+			}
+			if n := ch.plays("sound:retry"); n != tt.retried {
+				t.Errorf("retry prompt played %d times, want %d", n, tt.retried)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package gather
+
+import (
+	"strings"
+	"time"
+)
+
+var (
+	// DefaultFirstDigitTimeout is the default amount of time to wait for the
+	// first digit once the prompt finished
+	DefaultFirstDigitTimeout = 5 * time.Second
+
+	// DefaultInterDigitTimeout is the default amount of time to wait for each
+	// subsequent digit
+	DefaultInterDigitTimeout = 3 * time.Second
+
+	// DefaultTerminators are the default digits which end the input
+	DefaultTerminators = "#"
+)
+
+// Options describes a set of options for Gather
+type Options struct {
+	prompt []string
+
+	retryPrompt []string
+
+	minDigits int
+
+	maxDigits int
+
+	terminators string
+
+	firstDigitTimeout time.Duration
+
+	interDigitTimeout time.Duration
+
+	noBargeIn bool
+
+	validate func(digits string) bool
+
+	retries int
+
+	mask bool
+}
+
+func defaultOptions() *Options {
+	return &Options{
+		minDigits:         1,
+		terminators:       DefaultTerminators,
+		firstDigitTimeout: DefaultFirstDigitTimeout,
+		interDigitTimeout: DefaultInterDigitTimeout,
+	}
+}
+
+// Apply applies a set of options for Gather
+func (o *Options) Apply(opts ...OptionFunc) {
+	for _, f := range opts {
+		f(o)
+	}
+}
+
+// OptionFunc is a function which applies changes to an Options set
+type OptionFunc func(*Options)
+
+// Prompt configures the media URIs played before collecting the digits
+func Prompt(uris ...string) OptionFunc {
+	return func(o *Options) {
+		o.prompt = append(o.prompt, uris...)
+	}
+}
+
+// RetryPrompt configures the media URIs played after an invalid or missing
+// input, before the prompt is played again
+func RetryPrompt(uris ...string) OptionFunc {
+	return func(o *Options) {
+		o.retryPrompt = append(o.retryPrompt, uris...)
+	}
+}
+
+// MinDigits sets the minimum number of digits of a valid input.  Defaults to
+// 1; a terminator entered alone then counts as no input.  A setting of 0
+// accepts the empty input.
+func MinDigits(n int) OptionFunc {
+	return func(o *Options) {
+		o.minDigits = n
+	}
+}
+
+// MaxDigits sets the number of digits after which the input is complete.  A
+// setting of 0 (default) collects digits until a terminator or a timeout.
+func MaxDigits(n int) OptionFunc {
+	return func(o *Options) {
+		o.maxDigits = n
+	}
+}
+
+// Terminators sets the digits which end the input, such as "#" (default) or
+// "#*".  The terminator is not part of the digits.  An empty string disables
+// terminators.
+func Terminators(digits string) OptionFunc {
+	return func(o *Options) {
+		o.terminators = digits
+	}
+}
+
+// FirstDigitTimeout sets the amount of time to wait for the first digit once
+// the prompt finished
+func FirstDigitTimeout(timeout time.Duration) OptionFunc {
+	return func(o *Options) {
+		o.firstDigitTimeout = timeout
+	}
+}
+
+// InterDigitTimeout sets the amount of time to wait for each subsequent digit
+func InterDigitTimeout(timeout time.Duration) OptionFunc {
+	return func(o *Options) {
+		o.interDigitTimeout = timeout
+	}
+}
+
+// NoBargeIn lets the prompt play to the end.  The digits received during the
+// prompt are ignored.  By default, the first digit stops the prompt and counts
+// as input.
+func NoBargeIn() OptionFunc {
+	return func(o *Options) {
+		o.noBargeIn = true
+	}
+}
+
+// Validate configures a function which accepts or rejects the collected
+// digits.  A rejected input is retried, if retries remain.
+func Validate(f func(digits string) bool) OptionFunc {
+	return func(o *Options) {
+		o.validate = f
+	}
+}
+
+// Retries sets the number of times the input is asked again after an invalid
+// or missing input
+func Retries(n int) OptionFunc {
+	return func(o *Options) {
+		o.retries = n
+	}
+}
+
+// Mask hides the collected digits in the logs, for sensitive input such as
+// PINs or card numbers
+func Mask() OptionFunc {
+	return func(o *Options) {
+		o.mask = true
+	}
+}
+
+// isTerminator reports whether the digit ends the input
+func (o *Options) isTerminator(digit string) bool {
+	return digit != "" && o.terminators != "" && strings.Contains(o.terminators, digit)
+}
+
+// logged returns the digits as they may appear in the logs
+func (o *Options) logged(digits string) string {
+	if o.mask {
+		return strings.Repeat("*", len(digits))
+	}
+
+	return digits
+}
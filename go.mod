@@ -5,7 +5,11 @@ go 1.23.1
 require (
 	github.com/lrita/cmap v0.0.0-20231108122212-cb084a67f554
 	github.com/nats-io/nats.go v1.37.0
+	github.com/oklog/ulid v1.3.1
+	github.com/panjf2000/ants/v2 v2.12.1
+	github.com/rotisserie/eris v0.5.4
 	github.com/rs/zerolog v1.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,7 +19,9 @@ require (
 	github.com/nats-io/nkeys v0.4.7 // indirect
 	github.com/nats-io/nuid v1.0.1 // indirect
 	golang.org/x/crypto v0.29.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
 	golang.org/x/sys v0.27.0 // indirect
+	golang.org/x/text v0.20.0 // indirect
 )
 
 replace github.com/callevo/ari => ./ari/
@@ -0,0 +1,285 @@
+// Package health tracks the health of the Asterisk nodes through the outcome
+// of the requests sent to their proxies, and stops sending requests to the
+// nodes which do not answer
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// ErrOpen is returned instead of sending a request to a node whose circuit
+// breaker is open
+var ErrOpen = eris.New("circuit breaker open")
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// Closed lets every request through
+	Closed State = iota
+
+	// Open fails every request immediately
+	Open
+
+	// HalfOpen lets a few probe requests through, to find out whether the
+	// node recovered
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// Options configures the circuit breakers
+type Options struct {
+	// FailureThreshold is the number of consecutive failed requests which
+	// opens the breaker.  Defaults to 5.
+	FailureThreshold int
+
+	// OpenTimeout is how long the breaker stays open before letting probe
+	// requests through.  Defaults to 10 seconds.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of concurrent probe requests let through
+	// by a half-open breaker.  Defaults to 1.
+	HalfOpenRequests int
+}
+
+func (o *Options) withDefaults() *Options {
+	ret := Options{}
+	if o != nil {
+		ret = *o
+	}
+
+	if ret.FailureThreshold <= 0 {
+		ret.FailureThreshold = 5
+	}
+
+	if ret.OpenTimeout <= 0 {
+		ret.OpenTimeout = 10 * time.Second
+	}
+
+	if ret.HalfOpenRequests <= 0 {
+		ret.HalfOpenRequests = 1
+	}
+
+	return &ret
+}
+
+// StateChange describes the transition of the circuit breaker of a node
+type StateChange struct {
+	Node string
+	From State
+	To   State
+	At   time.Time
+
+	// Err is the failure which opened the breaker, if any
+	Err error
+}
+
+// Ticket identifies a request let through by Allow, so that Record only counts
+// its outcome against the state of the breaker which let it through
+type Ticket struct {
+	generation uint64
+	probe      bool
+}
+
+// scoreWeight is the weight of the latest outcome in the health score
+const scoreWeight = 0.2
+
+// Breaker is the circuit breaker of one node
+type Breaker struct {
+	node string
+	opts *Options
+
+	state    State
+	failures int
+	openedAt time.Time
+	probes   int
+
+	// generation changes with every transition, so that the outcome of the
+	// requests let through before the transition is not mistaken for the
+	// outcome of a probe
+	generation uint64
+
+	score   float64
+	latency time.Duration
+
+	notify func(StateChange)
+
+	mu sync.Mutex
+}
+
+func newBreaker(node string, opts *Options, notify func(StateChange)) *Breaker {
+	return &Breaker{
+		node:   node,
+		opts:   opts,
+		score:  1,
+		notify: notify,
+	}
+}
+
+// Allow reports whether a request may be sent to the node.  It returns ErrOpen
+// when the breaker is open, or half-open with all its probes in flight.  Every
+// allowed request must be followed by a call to Record with the returned
+// Ticket.
+func (b *Breaker) Allow() (Ticket, error) {
+	b.mu.Lock()
+
+	var change *StateChange
+
+	if b.state == Open && time.Since(b.openedAt) >= b.opts.OpenTimeout {
+		change = b.transition(HalfOpen, nil)
+	}
+
+	t := Ticket{generation: b.generation}
+
+	var err error
+	switch b.state {
+	case Open:
+		err = ErrOpen
+	case HalfOpen:
+		if b.probes >= b.opts.HalfOpenRequests {
+			err = ErrOpen
+		} else {
+			b.probes++
+			t.probe = true
+		}
+	}
+
+	b.mu.Unlock()
+
+	b.emit(change)
+
+	if err != nil {
+		return Ticket{}, eris.Wrapf(err, "node %s", b.node)
+	}
+
+	return t, nil
+}
+
+// Record records the outcome of a request allowed by Allow.  A request
+// cancelled by its caller says nothing of the node and is not counted.  The
+// outcome of a request let through before the last state change only counts
+// towards the score: in particular, only the probes close or reopen a
+// half-open breaker.
+func (b *Breaker) Record(t Ticket, latency time.Duration, err error) {
+	b.mu.Lock()
+
+	var change *StateChange
+
+	current := t.generation == b.generation
+
+	if current && t.probe && b.probes > 0 {
+		b.probes--
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+	case err == nil:
+		b.score += scoreWeight * (1 - b.score)
+		b.latency += time.Duration(scoreWeight * float64(latency-b.latency))
+
+		if !current {
+			break
+		}
+
+		b.failures = 0
+
+		if b.state == HalfOpen {
+			change = b.transition(Closed, nil)
+		}
+	default:
+		b.score -= scoreWeight * b.score
+
+		if !current {
+			break
+		}
+
+		b.failures++
+
+		if b.state == HalfOpen || (b.state == Closed && b.failures >= b.opts.FailureThreshold) {
+			change = b.transition(Open, err)
+		}
+	}
+
+	b.mu.Unlock()
+
+	b.emit(change)
+}
+
+// transition changes the state of the breaker.  The caller holds the lock.
+func (b *Breaker) transition(to State, err error) *StateChange {
+	change := &StateChange{
+		Node: b.node,
+		From: b.state,
+		To:   to,
+		At:   time.Now(),
+		Err:  err,
+	}
+
+	b.state = to
+	b.probes = 0
+	b.failures = 0
+	b.generation++
+
+	if to == Open {
+		b.openedAt = change.At
+	}
+
+	return change
+}
+
+func (b *Breaker) emit(change *StateChange) {
+	if change != nil && b.notify != nil {
+		b.notify(*change)
+	}
+}
+
+// State returns the state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Score returns the health score of the node, from 0 for a node failing every
+// request to 1 for a healthy node.  It decays with each failed request and
+// recovers with each successful one, and is 0 while the breaker is open.
+func (b *Breaker) Score() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		return 0
+	case HalfOpen:
+		return b.score / 2
+	}
+
+	return b.score
+}
+
+// Latency returns the moving average of the latency of the successful
+// requests
+func (b *Breaker) Latency() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.latency
+}
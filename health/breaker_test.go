@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+var errTimeout = eris.New("timeout")
+
+// step lets a request through the breaker, or records the outcome of the
+// request let through at the given step
+type step struct {
+	allow   bool
+	wantErr bool
+
+	record int
+	err    error
+
+	// wait lets the open breaker become half-open
+	wait bool
+}
+
+func allow() step                  { return step{allow: true} }
+func refused() step                { return step{allow: true, wantErr: true} }
+func record(i int, err error) step { return step{record: i, err: err} }
+func wait() step                   { return step{wait: true} }
+func fail(n int, from int) []step {
+	var steps []step
+	for i := 0; i < n; i++ {
+		steps = append(steps, allow(), record(from+2*i, errTimeout))
+	}
+	return steps
+}
+
+func TestBreaker(t *testing.T) {
+	opts := &Options{FailureThreshold: 2, OpenTimeout: time.Millisecond, HalfOpenRequests: 1}
+
+	tests := []struct {
+		name  string
+		steps []step
+		want  State
+	}{
+		{"healthy", []step{allow(), record(0, nil)}, Closed},
+		{"under threshold", fail(1, 0), Closed},
+		{"threshold", fail(2, 0), Open},
+		{"success resets failures", append(append(fail(1, 0), allow(), record(2, nil)), fail(1, 4)...), Closed},
+		{"cancelled requests ignored", []step{allow(), record(0, context.Canceled), allow(), record(2, eris.Wrap(context.Canceled, "request")), allow(), record(4, errTimeout)}, Closed},
+		{"open refuses", append(fail(2, 0), refused()), Open},
+		{"half-open", append(fail(2, 0), wait(), allow()), HalfOpen},
+		{"probes limited", append(fail(2, 0), wait(), allow(), refused()), HalfOpen},
+		{"probe success closes", append(fail(2, 0), wait(), allow(), record(5, nil)), Closed},
+		{"probe failure reopens", append(fail(2, 0), wait(), allow(), record(5, errTimeout)), Open},
+		{"cancelled probe frees its slot", append(fail(2, 0), wait(), allow(), record(5, context.Canceled), allow()), HalfOpen},
+		{
+			name: "late success of a closed request",
+			steps: []step{
+				allow(),
+				allow(), record(1, errTimeout), allow(), record(3, errTimeout),
+				wait(), allow(),
+				record(0, nil),
+			},
+			want: HalfOpen,
+		},
+		{
+			name: "late failure of a closed request",
+			steps: []step{
+				allow(),
+				allow(), record(1, errTimeout), allow(), record(3, errTimeout),
+				wait(), allow(), record(6, nil),
+				record(0, errTimeout),
+			},
+			want: Closed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker("ast1", opts.withDefaults(), nil)
+
+			tickets := make(map[int]Ticket)
+			for i, s := range tt.steps {
+				switch {
+				case s.wait:
+					time.Sleep(2 * opts.OpenTimeout)
+				case s.allow:
+					ticket, err := b.Allow()
+					if (err != nil) != s.wantErr {
+						t.Fatalf("step %d: Allow error %v, want error %v", i, err, s.wantErr)
+					}
+					tickets[i] = ticket
+				default:
+					ticket, ok := tickets[s.record]
+					if !ok {
+						t.Fatalf("step %d: no request at step %d", i, s.record)
+					}
+					b.Record(ticket, time.Millisecond, s.err)
+				}
+			}
+
+			if got := b.State(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	b := newBreaker("ast1", (&Options{FailureThreshold: 10}).withDefaults(), nil)
+
+	record := func(err error) {
+		ticket, aerr := b.Allow()
+		if aerr != nil {
+			t.Fatalf("Allow: %s", aerr)
+		}
+		b.Record(ticket, 10*time.Millisecond, err)
+	}
+
+	record(errTimeout)
+	low := b.Score()
+	if low >= 1 {
+		t.Fatalf("score %v after a failure", low)
+	}
+
+	record(context.Canceled)
+	if b.Score() != low {
+		t.Errorf("cancelled request changed the score")
+	}
+
+	record(nil)
+	if b.Score() <= low {
+		t.Errorf("score %v did not recover from %v", b.Score(), low)
+	}
+	if b.Latency() <= 0 {
+		t.Errorf("latency %s", b.Latency())
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(&Options{FailureThreshold: 1})
+
+	var changes []StateChange
+	cancel := r.Watch(func(c StateChange) { changes = append(changes, c) })
+	defer cancel()
+
+	if r.Score("ast1") != 1 {
+		t.Error("unknown node not healthy")
+	}
+
+	ticket, err := r.Allow("ast1")
+	if err != nil {
+		t.Fatalf("Allow: %s", err)
+	}
+	r.Record("ast1", ticket, time.Millisecond, errTimeout)
+
+	if r.State("ast1") != Open || r.Score("ast1") != 0 {
+		t.Errorf("state %s, score %v", r.State("ast1"), r.Score("ast1"))
+	}
+
+	if _, err := r.Allow("ast1"); !eris.Is(err, ErrOpen) {
+		t.Errorf("Allow = %v, want ErrOpen", err)
+	}
+
+	if len(changes) != 1 || changes[0].To != Open || changes[0].Node != "ast1" || changes[0].Err != errTimeout {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	if scores := r.Scores(); len(scores) != 1 {
+		t.Errorf("scores = %v", scores)
+	}
+}
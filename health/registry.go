@@ -0,0 +1,125 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// ChangeHandler is called with the state changes of the circuit breakers
+type ChangeHandler func(c StateChange)
+
+// Registry holds the circuit breakers of the nodes
+type Registry struct {
+	opts *Options
+
+	breakers map[string]*Breaker
+
+	watchers map[uint64]ChangeHandler
+	nextID   uint64
+
+	mu sync.RWMutex
+}
+
+// NewRegistry returns an empty registry whose breakers use the given options.
+// nil options select the defaults.
+func NewRegistry(opts *Options) *Registry {
+	return &Registry{
+		opts:     opts.withDefaults(),
+		breakers: make(map[string]*Breaker),
+		watchers: make(map[uint64]ChangeHandler),
+	}
+}
+
+// Breaker returns the circuit breaker of the node, creating it if needed
+func (r *Registry) Breaker(node string) *Breaker {
+	r.mu.RLock()
+	b, ok := r.breakers[node]
+	r.mu.RUnlock()
+
+	if ok {
+		return b
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok = r.breakers[node]; !ok {
+		b = newBreaker(node, r.opts, r.notify)
+		r.breakers[node] = b
+	}
+
+	return b
+}
+
+// Allow reports whether a request may be sent to the node.  See Breaker.Allow.
+func (r *Registry) Allow(node string) (Ticket, error) {
+	return r.Breaker(node).Allow()
+}
+
+// Record records the outcome of a request to the node.  See Breaker.Record.
+func (r *Registry) Record(node string, t Ticket, latency time.Duration, err error) {
+	r.Breaker(node).Record(t, latency, err)
+}
+
+// State returns the state of the circuit breaker of the node
+func (r *Registry) State(node string) State {
+	return r.Breaker(node).State()
+}
+
+// Score returns the health score of the node.  A node never contacted is
+// healthy.  See Breaker.Score.
+func (r *Registry) Score(node string) float64 {
+	r.mu.RLock()
+	b, ok := r.breakers[node]
+	r.mu.RUnlock()
+
+	if !ok {
+		return 1
+	}
+
+	return b.Score()
+}
+
+// Scores returns the health scores of the nodes contacted so far
+func (r *Registry) Scores() map[string]float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ret := make(map[string]float64, len(r.breakers))
+	for node, b := range r.breakers {
+		ret[node] = b.Score()
+	}
+
+	return ret
+}
+
+// Watch registers a handler called for every state change of the circuit
+// breakers.  Handlers are called synchronously by the request which caused the
+// change, so they must not block.  The returned function removes the handler.
+func (r *Registry) Watch(h ChangeHandler) (cancel func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	id := r.nextID
+	r.watchers[id] = h
+
+	return func() {
+		r.mu.Lock()
+		delete(r.watchers, id)
+		r.mu.Unlock()
+	}
+}
+
+func (r *Registry) notify(c StateChange) {
+	r.mu.RLock()
+	watchers := make([]ChangeHandler, 0, len(r.watchers))
+	for _, h := range r.watchers {
+		watchers = append(watchers, h)
+	}
+	r.mu.RUnlock()
+
+	for _, h := range watchers {
+		h(c)
+	}
+}
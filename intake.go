@@ -0,0 +1,230 @@
+package ari
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/messagebus"
+	nats "github.com/nats-io/nats.go"
+	"github.com/rotisserie/eris"
+)
+
+// intake takes the calls entering one Stasis application from its queue group
+type intake struct {
+	app     string
+	handler CallHandler
+	cleanup bool
+
+	subs []*nats.Subscription
+}
+
+// drain drains the subscriptions of the intake
+func (in *intake) drain() error {
+	var errs []error
+	for _, sub := range in.subs {
+		// Draining lets the calls already delivered to this worker be
+		// requeued rather than lost
+		if err := sub.Drain(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	in.subs = nil
+
+	return errors.Join(errs...)
+}
+
+// HandleApplication registers the handler for the channels entering the given
+// Stasis application, so that one client serves several applications over the
+// same connection and dispatcher.  Applications registered before Listen are
+// subscribed when it starts; afterwards, they are subscribed immediately.  The
+// handler is called like the handler of ListenContext.
+func (a *ARIClient) HandleApplication(app string, handler CallHandler) error {
+	if app == "" {
+		return eris.New("application name required")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	in := a.registerApplication(app, handler, true)
+
+	if a.listenCtx == nil {
+		return nil
+	}
+
+	return a.subscribeIntake(in)
+}
+
+// RemoveApplication stops taking the calls entering the given application.  The
+// calls in progress are not affected.
+func (a *ARIClient) RemoveApplication(app string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	in, ok := a.intakes[app]
+	if !ok {
+		return nil
+	}
+
+	delete(a.intakes, app)
+
+	return in.drain()
+}
+
+// ApplicationNames returns the names of the applications the client takes
+// calls for
+func (a *ARIClient) ApplicationNames() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	names := make([]string, 0, len(a.intakes))
+	for app := range a.intakes {
+		names = append(names, app)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// HandlesApplication reports whether the client takes the calls entering the
+// given application
+func (a *ARIClient) HandlesApplication(app string) bool {
+	if app == a.Application {
+		return true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, ok := a.intakes[app]
+	return ok
+}
+
+// registerApplication records the handler of the application.  The caller
+// holds the client lock.
+func (a *ARIClient) registerApplication(app string, handler CallHandler, cleanup bool) *intake {
+	if a.intakes == nil {
+		a.intakes = make(map[string]*intake)
+	}
+
+	in, ok := a.intakes[app]
+	if !ok {
+		in = &intake{app: app}
+		a.intakes[app] = in
+	}
+
+	in.handler = handler
+	in.cleanup = cleanup
+
+	return in
+}
+
+// startIntake subscribes every registered application to its queue group
+func (a *ARIClient) startIntake(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.listenCtx = ctx
+
+	for _, in := range a.intakes {
+		if err := a.subscribeIntake(in); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subscribeIntake subscribes the application to its queue group, unless it is
+// already subscribed or the intake is paused.  The caller holds the client lock.
+func (a *ARIClient) subscribeIntake(in *intake) error {
+	if in.subs != nil {
+		return nil
+	}
+
+	if a.admission != nil {
+		a.admission.mu.Lock()
+		paused := a.admission.paused
+		a.admission.mu.Unlock()
+
+		if paused {
+			return nil
+		}
+	}
+
+	ctx := a.listenCtx
+
+	callback := func(o *arievent.StasisEvent, msg *nats.Msg) {
+		logs.TLogger.Debug().Msgf("O: %+v", o)
+
+		// Calls outside the share of this client are left to the other queue
+		// groups
+		if !a.takes(o) {
+			return
+		}
+
+		// Channels originated by a worker into the application are delivered
+		// to that worker directly
+		if a.isClaimed(o.Channel.ID) {
+			logs.TLogger.Debug().Msgf("channel %s is claimed by its originator", o.Channel.ID)
+			return
+		}
+
+		// A call this worker refused already is passed on to the other
+		// members of the queue group
+		if messagebus.RefusedBy(msg, a.instanceID) {
+			logs.TLogger.Debug().Msgf("channel %s already refused by this worker", o.Channel.ID)
+			a.reject(o, msg)
+			return
+		}
+
+		release, ok := a.admission.admit(o)
+		if !ok {
+			logs.TLogger.Debug().Msgf("channel %s refused by admission control", o.Channel.ID)
+			a.reject(o, msg)
+			return
+		}
+
+		a.mu.Lock()
+		handler, cleanup := in.handler, in.cleanup
+		a.mu.Unlock()
+
+		a.startCall(ctx, o, handler, cleanup, release)
+	}
+
+	nodes := a.nodes
+	if len(nodes) == 0 {
+		nodes = []string{""}
+	}
+
+	for _, node := range nodes {
+		topic := a.subjects.StasisStart(in.app, node)
+
+		logs.TLogger.Debug().Msgf("Queue subscribing to stasisstart events %s", topic)
+		sub, err := a.sbus.QueueSubscribeEvent(topic, a.queueGroup(), callback)
+		if err != nil {
+			in.drain()
+			return eris.Wrapf(err, "failed to subscribe to application %s", in.app)
+		}
+
+		in.subs = append(in.subs, sub)
+	}
+
+	// The calls refused by the other members of the queue group come back on
+	// the requeue subject, which only the group subscribes to
+	topic := a.subjects.Requeue(in.app, a.queueGroup())
+
+	logs.TLogger.Debug().Msgf("Queue subscribing to requeued stasisstart events %s", topic)
+	sub, err := a.sbus.QueueSubscribeEvent(topic, a.queueGroup(), callback)
+	if err != nil {
+		in.drain()
+		return eris.Wrapf(err, "failed to subscribe to application %s", in.app)
+	}
+
+	in.subs = append(in.subs, sub)
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package ari
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+)
+
+func TestHandleApplication(t *testing.T) {
+	bus := newFakeBus()
+	a := newTestClient(bus)
+	a.admission = newAdmission(nil)
+
+	handled := make(chan string, 2)
+	handler := func(name string) CallHandler {
+		return func(_ context.Context, _ *ARIClient, h *channel.ChannelHandle, e *arievent.StasisEvent) {
+			handled <- name + ":" + h.Key().GetApp()
+		}
+	}
+
+	if err := a.HandleApplication("", handler("none")); err == nil {
+		t.Error("application without a name registered")
+	}
+
+	// Registered before listening, subscribed when listening starts
+	if err := a.HandleApplication("sales", handler("sales")); err != nil {
+		t.Fatalf("HandleApplication: %s", err)
+	}
+	if n := len(bus.queueSubs[a.subjects.StasisStart("sales", "")]); n != 0 {
+		t.Fatalf("%d subscriptions before listening, want 0", n)
+	}
+
+	if err := a.startIntake(context.Background()); err != nil {
+		t.Fatalf("startIntake: %s", err)
+	}
+
+	// Registered while listening, subscribed at once
+	if err := a.HandleApplication("support", handler("support")); err != nil {
+		t.Fatalf("HandleApplication: %s", err)
+	}
+
+	tests := []struct {
+		app  string
+		want string
+	}{
+		{"sales", "sales:sales"},
+		{"support", "support:support"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			e := &arievent.StasisEvent{Type: arievent.StasisStart, Application: tt.app, Node: "ast1", Channel: arievent.ChannelData{ID: "c-" + tt.app}}
+			if !bus.deliver(a.subjects.StasisStart(tt.app, ""), e, nil) {
+				t.Fatal("application not subscribed")
+			}
+
+			select {
+			case got := <-handled:
+				if got != tt.want {
+					t.Errorf("handled by %s, want %s", got, tt.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("call not handled")
+			}
+		})
+	}
+}
+
+func TestApplicationNames(t *testing.T) {
+	a := newTestClient(newFakeBus())
+
+	for _, app := range []string{"b", "a"} {
+		if err := a.HandleApplication(app, nil); err != nil {
+			t.Fatalf("HandleApplication: %s", err)
+		}
+	}
+
+	names := a.ApplicationNames()
+	if !sort.StringsAreSorted(names) || len(names) != 2 {
+		t.Errorf("ApplicationNames = %v", names)
+	}
+
+	tests := []struct {
+		app  string
+		want bool
+	}{
+		{"app", true},
+		{"a", true},
+		{"b", true},
+		{"c", false},
+	}
+
+	for _, tt := range tests {
+		if got := a.HandlesApplication(tt.app); got != tt.want {
+			t.Errorf("HandlesApplication(%s) = %v, want %v", tt.app, got, tt.want)
+		}
+	}
+
+	if err := a.RemoveApplication("a"); err != nil {
+		t.Fatalf("RemoveApplication: %s", err)
+	}
+	if a.HandlesApplication("a") {
+		t.Error("removed application still handled")
+	}
+	if err := a.RemoveApplication("unknown"); err != nil {
+		t.Errorf("RemoveApplication of an unknown application: %s", err)
+	}
+}
@@ -1,6 +1,8 @@
 package ari
 
 import (
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
 	"github.com/callevo/ari/key"
 	"github.com/callevo/ari/logs"
 	"github.com/callevo/ari/recordings"
@@ -24,6 +26,12 @@ func (l *iLifeRecording) Get(key *key.Key) *recordings.LiveRecordingHandle {
 }
 
 func (l *iLifeRecording) Data(key *key.Key) (*recordings.LiveRecordingData, error) {
+	if l.c.state != nil {
+		if d, ok := l.c.state.LiveRecording(key, l.c.stateMaxAge); ok {
+			return d, nil
+		}
+	}
+
 	data, err := l.c.dataRequest(&requests.Request{
 		Kind: "RecordingLiveData",
 		Key:  key,
@@ -79,3 +87,7 @@ func (l *iLifeRecording) Scrap(ikey *key.Key) error {
 func (l *iLifeRecording) Stored(ikey *key.Key) *recordings.StoredRecordingHandle {
 	return recordings.NewStoredRecordingHandle(ikey.New(key.StoredRecordingKey, ikey.ID), l.c.StoredRecording(), nil)
 }
+
+func (l *iLifeRecording) Subscribe(ikey *key.Key, listener dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return l.c.subscribeKey(ikey, listener, types...)
+}
@@ -0,0 +1,189 @@
+package media
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/rotisserie/eris"
+)
+
+// DefaultLanguage is the language of the Sayer used for the languages which
+// have none registered
+var DefaultLanguage = "en"
+
+// Sayer composes the sound URIs saying values in one language.  Asterisk
+// selects the sound files of the language of the channel; the Sayer orders
+// them following the grammar of the language.
+type Sayer interface {
+	// Number says an integer
+	Number(n int64) []URI
+
+	// Digits says each digit of the string
+	Digits(digits string) ([]URI, error)
+
+	// Date says the weekday, day, month and year
+	Date(t time.Time) []URI
+
+	// Time says the hour and minutes
+	Time(t time.Time) []URI
+
+	// Money says an amount, in the minor units of the currency
+	Money(amount int64, currency string) ([]URI, error)
+
+	// Duration says the hours, minutes and seconds
+	Duration(d time.Duration) []URI
+}
+
+var (
+	sayers = map[string]Sayer{
+		"en": English{},
+	}
+
+	sayersMu sync.RWMutex
+)
+
+// Register registers the Sayer of a language, such as "fr" or "pt_BR"
+func Register(lang string, s Sayer) {
+	sayersMu.Lock()
+	defer sayersMu.Unlock()
+
+	sayers[normalize(lang)] = s
+}
+
+// For returns the Sayer of the language.  A regional language such as "en_US"
+// falls back to its base language, and an unknown language to
+// DefaultLanguage.
+func For(lang string) Sayer {
+	sayersMu.RLock()
+	defer sayersMu.RUnlock()
+
+	lang = normalize(lang)
+
+	if s, ok := sayers[lang]; ok {
+		return s
+	}
+
+	if base, _, ok := strings.Cut(lang, "_"); ok {
+		if s, ok := sayers[base]; ok {
+			return s
+		}
+	}
+
+	return sayers[normalize(DefaultLanguage)]
+}
+
+// ForChannel returns the Sayer of the language of the channel
+func ForChannel(data *arievent.ChannelData) Sayer {
+	if data == nil {
+		return For("")
+	}
+
+	return For(data.Language)
+}
+
+func normalize(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "-", "_"))
+}
+
+// SayNumber returns the sound URIs saying the number in the language
+func SayNumber(lang string, n int64) []string {
+	return strs(For(lang).Number(n))
+}
+
+// SayDigits returns the sound URIs saying each digit in the language
+func SayDigits(lang string, digits string) ([]string, error) {
+	uris, err := For(lang).Digits(digits)
+	if err != nil {
+		return nil, err
+	}
+
+	return strs(uris), nil
+}
+
+// SayDate returns the sound URIs saying the date in the language
+func SayDate(lang string, t time.Time) []string {
+	return strs(For(lang).Date(t))
+}
+
+// SayTime returns the sound URIs saying the time in the language
+func SayTime(lang string, t time.Time) []string {
+	return strs(For(lang).Time(t))
+}
+
+// SayMoney returns the sound URIs saying the amount, in the minor units of
+// the currency, in the language
+func SayMoney(lang string, amount int64, currency string) ([]string, error) {
+	uris, err := For(lang).Money(amount, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return strs(uris), nil
+}
+
+// SayDuration returns the sound URIs saying the duration in the language
+func SayDuration(lang string, d time.Duration) []string {
+	return strs(For(lang).Duration(d))
+}
+
+func strs(uris []URI) []string {
+	ret := make([]string, len(uris))
+	for i, u := range uris {
+		ret[i] = u.String()
+	}
+
+	return ret
+}
+
+// Currency names the sounds of the units of a currency
+type Currency struct {
+	// Major and Majors are the sounds of one and of several major units,
+	// such as "dollar" and "dollars"
+	Major  string
+	Majors string
+
+	// Minor and Minors are the sounds of one and of several minor units,
+	// such as "cent" and "cents"
+	Minor  string
+	Minors string
+
+	// MinorUnits is the number of minor units in a major unit, such as 100
+	MinorUnits int64
+}
+
+var (
+	currencies = map[string]Currency{
+		"USD": {Major: "dollar", Majors: "dollars", Minor: "cent", Minors: "cents", MinorUnits: 100},
+		"EUR": {Major: "euro", Majors: "euros", Minor: "cent", Minors: "cents", MinorUnits: 100},
+		"GBP": {Major: "pound", Majors: "pounds", Minor: "penny", Minors: "pence", MinorUnits: 100},
+	}
+
+	currenciesMu sync.RWMutex
+)
+
+// RegisterCurrency registers the sounds of a currency, by ISO 4217 code
+func RegisterCurrency(code string, c Currency) {
+	currenciesMu.Lock()
+	defer currenciesMu.Unlock()
+
+	currencies[strings.ToUpper(code)] = c
+}
+
+// LookupCurrency returns the sounds of a currency, by ISO 4217 code
+func LookupCurrency(code string) (Currency, error) {
+	currenciesMu.RLock()
+	defer currenciesMu.RUnlock()
+
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return c, eris.Errorf("unknown currency %q", code)
+	}
+
+	if c.MinorUnits <= 0 {
+		c.MinorUnits = 1
+	}
+
+	return c, nil
+}
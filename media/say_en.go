@@ -0,0 +1,233 @@
+package media
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// English says values with the English sounds of the Asterisk core sounds
+type English struct{}
+
+// digit returns the sound of a number below 20, or of a multiple of ten
+// below 100
+func digit(n int64) URI {
+	return Sound("digits/" + strconv.FormatInt(n, 10))
+}
+
+// Number says an integer, such as "two thousand three hundred forty five"
+func (English) Number(n int64) []URI {
+	if n == 0 {
+		return []URI{digit(0)}
+	}
+
+	if n < 0 {
+		// The negation of the lowest integer only fits unsigned
+		return append([]URI{Sound("digits/minus")}, englishNumber(uint64(-(n+1))+1)...)
+	}
+
+	return englishNumber(uint64(n))
+}
+
+func englishNumber(n uint64) []URI {
+	var ret []URI
+
+	for _, scale := range []struct {
+		value uint64
+		sound string
+	}{
+		{1000000000000000000, "digits/quintillion"},
+		{1000000000000000, "digits/quadrillion"},
+		{1000000000000, "digits/trillion"},
+		{1000000000, "digits/billion"},
+		{1000000, "digits/million"},
+		{1000, "digits/thousand"},
+	} {
+		if n >= scale.value {
+			ret = append(ret, englishNumber(n/scale.value)...)
+			ret = append(ret, Sound(scale.sound))
+			n %= scale.value
+		}
+	}
+
+	if n >= 100 {
+		ret = append(ret, digit(int64(n/100)), Sound("digits/hundred"))
+		n %= 100
+	}
+
+	switch {
+	case n >= 20:
+		ret = append(ret, digit(int64(n-n%10)))
+		if n%10 != 0 {
+			ret = append(ret, digit(int64(n%10)))
+		}
+	case n > 0:
+		ret = append(ret, digit(int64(n)))
+	}
+
+	return ret
+}
+
+// Digits says each digit of the string, including *, #, - and A to D
+func (English) Digits(digits string) ([]URI, error) {
+	ret := make([]URI, 0, len(digits))
+
+	for _, c := range digits {
+		switch {
+		case c >= '0' && c <= '9':
+			ret = append(ret, digit(int64(c-'0')))
+		case c == '*':
+			ret = append(ret, Sound("digits/star"))
+		case c == '#':
+			ret = append(ret, Sound("digits/pound"))
+		case c == '-':
+			ret = append(ret, Sound("digits/minus"))
+		case c >= 'A' && c <= 'D':
+			ret = append(ret, Sound("letters/"+string(c-'A'+'a')))
+		case c >= 'a' && c <= 'd':
+			ret = append(ret, Sound("letters/"+string(c)))
+		default:
+			return nil, eris.Errorf("invalid digit %q in %q", c, digits)
+		}
+	}
+
+	return ret, nil
+}
+
+// Date says the date, such as "Monday, March first, two thousand twenty six"
+func (English) Date(t time.Time) []URI {
+	ret := []URI{
+		Sound("digits/day-" + strconv.Itoa(int(t.Weekday()))),
+		Sound("digits/mon-" + strconv.Itoa(int(t.Month())-1)),
+	}
+
+	ret = append(ret, English{}.ordinal(t.Day())...)
+
+	return append(ret, English{}.Number(int64(t.Year()))...)
+}
+
+// ordinal says the ordinal of n, between 1 and 99.  The sounds hold the
+// ordinals up to the nineteenth and those of the tens; the others are said as
+// the tens followed by the ordinal of the units, such as "twenty first".
+func (English) ordinal(n int) []URI {
+	if n < 20 || n%10 == 0 {
+		return []URI{Sound("digits/h-" + strconv.Itoa(n))}
+	}
+
+	return []URI{
+		Sound("digits/" + strconv.Itoa(n/10*10)),
+		Sound("digits/h-" + strconv.Itoa(n%10)),
+	}
+}
+
+// Time says the time on the 12 hour clock, such as "nine oh five a m"
+func (English) Time(t time.Time) []URI {
+	hour := t.Hour() % 12
+	if hour == 0 {
+		hour = 12
+	}
+
+	ret := English{}.Number(int64(hour))
+
+	switch m := t.Minute(); {
+	case m == 0:
+		ret = append(ret, Sound("digits/oclock"))
+	case m < 10:
+		ret = append(ret, Sound("digits/oh"), digit(int64(m)))
+	default:
+		ret = append(ret, English{}.Number(int64(m))...)
+	}
+
+	if t.Hour() < 12 {
+		return append(ret, Sound("digits/a-m"))
+	}
+
+	return append(ret, Sound("digits/p-m"))
+}
+
+// Money says the amount, such as "twelve dollars and five cents"
+func (English) Money(amount int64, currency string) ([]URI, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []URI
+
+	// The negation of the lowest amount only fits unsigned
+	abs := uint64(amount)
+	if amount < 0 {
+		ret = append(ret, Sound("digits/minus"))
+		abs = uint64(-(amount + 1)) + 1
+	}
+
+	units := uint64(c.MinorUnits)
+	major, minor := abs/units, abs%units
+
+	if major > 0 || minor == 0 {
+		ret = append(ret, English{}.count(major)...)
+		ret = append(ret, Sound(plural(major, c.Major, c.Majors)))
+	}
+
+	if minor > 0 {
+		if major > 0 {
+			ret = append(ret, Sound("and"))
+		}
+
+		ret = append(ret, English{}.count(minor)...)
+		ret = append(ret, Sound(plural(minor, c.Minor, c.Minors)))
+	}
+
+	return ret, nil
+}
+
+// Duration says the duration to the second, such as "one hour twenty
+// minutes"
+func (English) Duration(d time.Duration) []URI {
+	// Negating the seconds rather than d cannot overflow
+	secs := int64(d / time.Second)
+	if secs < 0 {
+		secs = -secs
+	}
+
+	if secs == 0 {
+		return []URI{digit(0), Sound("seconds")}
+	}
+
+	var ret []URI
+
+	for _, unit := range []struct {
+		secs        int64
+		one, plural string
+	}{
+		{3600, "hour", "hours"},
+		{60, "minute", "minutes"},
+		{1, "second", "seconds"},
+	} {
+		if n := secs / unit.secs; n > 0 {
+			ret = append(ret, English{}.Number(n)...)
+			ret = append(ret, Sound(plural(uint64(n), unit.one, unit.plural)))
+			secs %= unit.secs
+		}
+	}
+
+	return ret
+}
+
+// count says a non-negative count
+func (English) count(n uint64) []URI {
+	if n == 0 {
+		return []URI{digit(0)}
+	}
+
+	return englishNumber(n)
+}
+
+func plural(n uint64, one, many string) string {
+	if n == 1 {
+		return one
+	}
+
+	return many
+}
@@ -0,0 +1,181 @@
+package media
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sounds returns the values of the URIs without the digits/ prefix, such as
+// "2 thousand 40 5"
+func sounds(uris []URI) string {
+	ret := make([]string, len(uris))
+	for i, u := range uris {
+		ret[i] = strings.TrimPrefix(u.Value, "digits/")
+	}
+
+	return strings.Join(ret, " ")
+}
+
+func TestEnglishNumber(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{15, "15"},
+		{40, "40"},
+		{105, "1 hundred 5"},
+		{2345, "2 thousand 3 hundred 40 5"},
+		{1000000, "1 million"},
+		{-7, "minus 7"},
+		{math.MaxInt64, "9 quintillion 2 hundred 20 3 quadrillion 3 hundred 70 2 trillion 30 6 billion 8 hundred 50 4 million 7 hundred 70 5 thousand 8 hundred 7"},
+		{math.MinInt64, "minus 9 quintillion 2 hundred 20 3 quadrillion 3 hundred 70 2 trillion 30 6 billion 8 hundred 50 4 million 7 hundred 70 5 thousand 8 hundred 8"},
+	}
+
+	for _, tt := range tests {
+		if got := sounds(English{}.Number(tt.n)); got != tt.want {
+			t.Errorf("Number(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestEnglishDigits(t *testing.T) {
+	tests := []struct {
+		digits  string
+		want    string
+		wantErr bool
+	}{
+		{digits: "1*#-", want: "1 star pound minus"},
+		{digits: "Ab", want: "letters/a letters/b"},
+		{digits: "1x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		uris, err := English{}.Digits(tt.digits)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Digits(%q) error = %v, want error %v", tt.digits, err, tt.wantErr)
+		}
+
+		if got := sounds(uris); got != tt.want {
+			t.Errorf("Digits(%q) = %q, want %q", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestEnglishDateTime(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		date string
+		time string
+	}{
+		{
+			t:    time.Date(2026, time.March, 1, 9, 5, 0, 0, time.UTC),
+			date: "day-0 mon-2 h-1 2 thousand 20 6",
+			time: "9 oh 5 a-m",
+		},
+		{
+			t:    time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC),
+			date: "day-3 mon-11 30 h-1 2 thousand 20 5",
+			time: "12 oclock a-m",
+		},
+		{
+			t:    time.Date(2024, time.July, 4, 13, 45, 0, 0, time.UTC),
+			date: "day-4 mon-6 h-4 2 thousand 20 4",
+			time: "1 40 5 p-m",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := sounds(English{}.Date(tt.t)); got != tt.date {
+			t.Errorf("Date(%s) = %q, want %q", tt.t, got, tt.date)
+		}
+		if got := sounds(English{}.Time(tt.t)); got != tt.time {
+			t.Errorf("Time(%s) = %q, want %q", tt.t, got, tt.time)
+		}
+	}
+}
+
+func TestEnglishOrdinal(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "h-1"},
+		{12, "h-12"},
+		{19, "h-19"},
+		{20, "h-20"},
+		{21, "20 h-1"},
+		{29, "20 h-9"},
+		{30, "h-30"},
+		{31, "30 h-1"},
+	}
+
+	for _, tt := range tests {
+		if got := sounds(English{}.ordinal(tt.n)); got != tt.want {
+			t.Errorf("ordinal(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestEnglishMoney(t *testing.T) {
+	RegisterCurrency("xts", Currency{Major: "unit", Majors: "units"})
+
+	tests := []struct {
+		amount   int64
+		currency string
+		want     string
+		wantErr  bool
+	}{
+		{amount: 1205, currency: "USD", want: "12 dollars and 5 cents"},
+		{amount: 100, currency: "usd", want: "1 dollar"},
+		{amount: 0, currency: "USD", want: "0 dollars"},
+		{amount: 1, currency: "USD", want: "1 cent"},
+		{amount: -250, currency: "EUR", want: "minus 2 euros and 50 cents"},
+		{amount: 101, currency: "GBP", want: "1 pound and 1 penny"},
+		{amount: 3, currency: "XTS", want: "3 units"},
+		{
+			amount:   math.MinInt64,
+			currency: "USD",
+			want:     "minus 90 2 quadrillion 2 hundred 30 3 trillion 7 hundred 20 billion 3 hundred 60 8 million 5 hundred 40 7 thousand 7 hundred 50 8 dollars and 8 cents",
+		},
+		{
+			amount:   math.MinInt64,
+			currency: "XTS",
+			want:     "minus 9 quintillion 2 hundred 20 3 quadrillion 3 hundred 70 2 trillion 30 6 billion 8 hundred 50 4 million 7 hundred 70 5 thousand 8 hundred 8 units",
+		},
+		{amount: 1, currency: "XXX", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		uris, err := English{}.Money(tt.amount, tt.currency)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Money(%d, %s) error = %v, want error %v", tt.amount, tt.currency, err, tt.wantErr)
+		}
+
+		if got := sounds(uris); got != tt.want {
+			t.Errorf("Money(%d, %s) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestEnglishDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0 seconds"},
+		{999 * time.Millisecond, "0 seconds"},
+		{time.Second, "1 second"},
+		{80 * time.Minute, "1 hour 20 minutes"},
+		{-61 * time.Second, "1 minute 1 second"},
+		{math.MinInt64, "2 million 5 hundred 60 2 thousand 40 7 hours 40 7 minutes 16 seconds"},
+	}
+
+	for _, tt := range tests {
+		if got := sounds(English{}.Duration(tt.d)); got != tt.want {
+			t.Errorf("Duration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
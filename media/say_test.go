@@ -0,0 +1,99 @@
+package media
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+)
+
+// shout says everything as a single sound
+type shout struct {
+	English
+}
+
+func (shout) Number(n int64) []URI {
+	return []URI{Sound("shout")}
+}
+
+func TestFor(t *testing.T) {
+	Register("xx-YY", shout{})
+
+	tests := []struct {
+		lang  string
+		shout bool
+	}{
+		{lang: "en"},
+		{lang: "en_US"},
+		{lang: ""},
+		{lang: "fr"},
+		{lang: "xx_yy", shout: true},
+		{lang: "XX-yy", shout: true},
+		{lang: "xx"},
+	}
+
+	for _, tt := range tests {
+		_, shouts := For(tt.lang).(shout)
+		if shouts != tt.shout {
+			t.Errorf("For(%q) = %T", tt.lang, For(tt.lang))
+		}
+	}
+
+	if _, ok := ForChannel(&arievent.ChannelData{Language: "xx_YY"}).(shout); !ok {
+		t.Error("ForChannel ignored the language of the channel")
+	}
+
+	if _, ok := ForChannel(nil).(English); !ok {
+		t.Error("ForChannel(nil) is not the default language")
+	}
+}
+
+func TestSay(t *testing.T) {
+	tests := []struct {
+		name string
+		say  func() ([]string, error)
+		want []string
+	}{
+		{
+			name: "number",
+			say:  func() ([]string, error) { return SayNumber("en", 21), nil },
+			want: []string{"sound:digits/20", "sound:digits/1"},
+		},
+		{
+			name: "digits",
+			say:  func() ([]string, error) { return SayDigits("en", "1#") },
+			want: []string{"sound:digits/1", "sound:digits/pound"},
+		},
+		{
+			name: "time",
+			say: func() ([]string, error) {
+				return SayTime("en", time.Date(2026, time.October, 19, 18, 0, 0, 0, time.UTC)), nil
+			},
+			want: []string{"sound:digits/6", "sound:digits/oclock", "sound:digits/p-m"},
+		},
+		{
+			name: "money",
+			say:  func() ([]string, error) { return SayMoney("en_GB", 200, "GBP") },
+			want: []string{"sound:digits/2", "sound:pounds"},
+		},
+		{
+			name: "duration",
+			say:  func() ([]string, error) { return SayDuration("en", 2*time.Minute), nil },
+			want: []string{"sound:digits/2", "sound:minutes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.say()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("said %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Package media builds and validates the media URIs played by Asterisk, and
+// composes the sound URIs which say numbers, dates, amounts and durations
+package media
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rotisserie/eris"
+)
+
+// Scheme is the scheme of a media URI
+type Scheme string
+
+const (
+	// SoundScheme plays a sound file, such as sound:tt-monkeys
+	SoundScheme Scheme = "sound"
+
+	// DigitsScheme says each digit, such as digits:1234
+	DigitsScheme Scheme = "digits"
+
+	// NumberScheme says a number, such as number:1234
+	NumberScheme Scheme = "number"
+
+	// CharactersScheme spells each character, such as characters:abc
+	CharactersScheme Scheme = "characters"
+
+	// RecordingScheme plays a stored recording, such as recording:greeting
+	RecordingScheme Scheme = "recording"
+
+	// ToneScheme plays a tone, such as tone:ring;tonezone=fr
+	ToneScheme Scheme = "tone"
+)
+
+// validDigits are the characters accepted by the digits scheme
+const validDigits = "0123456789*#ABCDabcd-"
+
+// URI is a media URI, as accepted by the Play operations
+type URI struct {
+	Scheme Scheme
+	Value  string
+}
+
+// String returns the URI in the scheme:value form
+func (u URI) String() string {
+	return string(u.Scheme) + ":" + u.Value
+}
+
+// Validate checks that the value is valid for the scheme
+// nolint: gocyclo
+func (u URI) Validate() error {
+	if u.Value == "" {
+		return eris.Errorf("empty %s media URI", u.Scheme)
+	}
+
+	switch u.Scheme {
+	case SoundScheme, RecordingScheme:
+		if strings.IndexFunc(u.Value, unicode.IsSpace) >= 0 {
+			return eris.Errorf("invalid %s name %q", u.Scheme, u.Value)
+		}
+	case DigitsScheme:
+		for _, c := range u.Value {
+			if !strings.ContainsRune(validDigits, c) {
+				return eris.Errorf("invalid digit %q in %q", c, u.Value)
+			}
+		}
+	case NumberScheme:
+		if _, err := strconv.ParseInt(u.Value, 10, 64); err != nil {
+			return eris.Errorf("invalid number %q", u.Value)
+		}
+	case CharactersScheme:
+		for _, c := range u.Value {
+			if c > unicode.MaxASCII || !unicode.IsPrint(c) {
+				return eris.Errorf("invalid character %q in %q", c, u.Value)
+			}
+		}
+	case ToneScheme:
+		name, zone, ok := strings.Cut(u.Value, ";")
+		if name == "" {
+			return eris.Errorf("invalid tone %q", u.Value)
+		}
+
+		if ok && !strings.HasPrefix(zone, "tonezone=") {
+			return eris.Errorf("invalid tone option %q", zone)
+		}
+	default:
+		return eris.Errorf("unknown media scheme %q", u.Scheme)
+	}
+
+	return nil
+}
+
+// Parse parses and validates a media URI
+func Parse(s string) (URI, error) {
+	scheme, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return URI{}, eris.Errorf("media URI %q has no scheme", s)
+	}
+
+	u := URI{Scheme: Scheme(scheme), Value: value}
+
+	return u, u.Validate()
+}
+
+// Sound returns the URI of a sound file, named without its extension
+func Sound(name string) URI {
+	return URI{Scheme: SoundScheme, Value: name}
+}
+
+// Digits returns the URI saying each digit of the string
+func Digits(digits string) URI {
+	return URI{Scheme: DigitsScheme, Value: digits}
+}
+
+// Number returns the URI saying the number
+func Number(n int64) URI {
+	return URI{Scheme: NumberScheme, Value: strconv.FormatInt(n, 10)}
+}
+
+// Characters returns the URI spelling each character of the string
+func Characters(s string) URI {
+	return URI{Scheme: CharactersScheme, Value: s}
+}
+
+// Recording returns the URI of a stored recording
+func Recording(name string) URI {
+	return URI{Scheme: RecordingScheme, Value: name}
+}
+
+// Tone returns the URI of a tone, such as "ring" or "busy", or of a tone list
+// such as "425/500,0/500".  A zone, such as "us" or "fr", selects the tones of
+// the country rather than those of the channel.
+func Tone(tone string, zone string) URI {
+	u := URI{Scheme: ToneScheme, Value: tone}
+	if zone != "" {
+		u.Value += ";tonezone=" + zone
+	}
+
+	return u
+}
+
+// Strings validates the URIs and returns them in the scheme:value form, as
+// expected by the Play operations and play.URI
+func Strings(uris ...URI) ([]string, error) {
+	ret := make([]string, len(uris))
+
+	for i, u := range uris {
+		if err := u.Validate(); err != nil {
+			return nil, err
+		}
+
+		ret[i] = u.String()
+	}
+
+	return ret, nil
+}
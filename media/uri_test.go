@@ -0,0 +1,65 @@
+package media
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    URI
+		wantErr bool
+	}{
+		{uri: "sound:tt-monkeys", want: Sound("tt-monkeys")},
+		{uri: "digits:12*#A", want: Digits("12*#A")},
+		{uri: "number:-42", want: Number(-42)},
+		{uri: "characters:abc", want: Characters("abc")},
+		{uri: "recording:greeting", want: Recording("greeting")},
+		{uri: "tone:ring;tonezone=fr", want: Tone("ring", "fr")},
+		{uri: "tone:425/500,0/500", want: Tone("425/500,0/500", "")},
+		{uri: "tt-monkeys", wantErr: true},
+		{uri: "sound:", wantErr: true},
+		{uri: "sound:tt monkeys", wantErr: true},
+		{uri: "digits:12x", wantErr: true},
+		{uri: "number:1.5", wantErr: true},
+		{uri: "characters:é", wantErr: true},
+		{uri: "tone:;tonezone=fr", wantErr: true},
+		{uri: "tone:ring;zone=fr", wantErr: true},
+		{uri: "video:clip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		u, err := Parse(tt.uri)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, want error %v", tt.uri, err, tt.wantErr)
+			continue
+		}
+
+		if tt.wantErr {
+			continue
+		}
+
+		if !reflect.DeepEqual(u, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.uri, u, tt.want)
+		}
+		if u.String() != tt.uri {
+			t.Errorf("%+v formatted as %q, want %q", u, u.String(), tt.uri)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	got, err := Strings(Sound("hello"), Number(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"sound:hello", "number:7"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Strings = %q, want %q", got, want)
+	}
+
+	if _, err := Strings(Sound("hello"), Digits("")); err == nil {
+		t.Error("Strings accepted an empty URI")
+	}
+}
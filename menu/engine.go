@@ -0,0 +1,681 @@
+package menu
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/callevo/ari"
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/arioptions"
+	"github.com/callevo/ari/bridge"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/gather"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/play"
+	"github.com/callevo/ari/recordings"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/rid"
+	"github.com/rotisserie/eris"
+)
+
+var (
+	// DefaultMaxSteps is the default number of nodes a run may go through
+	// before it is considered to be looping
+	DefaultMaxSteps = 1000
+
+	// DefaultTransferTimeout is the default amount of time to wait for the
+	// endpoint of a transfer to answer
+	DefaultTransferTimeout = 30 * time.Second
+
+	// DigitsVar is the variable set by the gather nodes without var
+	DigitsVar = "digits"
+
+	// RecordingVar is the variable set by the record nodes without var
+	RecordingVar = "recording"
+)
+
+// ErrLoop is returned when a run goes through more nodes than allowed
+var ErrLoop = eris.New("menu exceeded the maximum number of steps")
+
+// ErrNoBridge is returned by the transfers to an endpoint when the engine has
+// no bridge.Bridge to join the calls
+var ErrNoBridge = eris.New("no bridge available for the transfer")
+
+// Status describes how a run of a menu ended
+type Status int
+
+const (
+	// Running indicates that the run has not ended yet
+	Running Status = iota
+
+	// Completed indicates that the run reached a node without next
+	Completed
+
+	// Transferred indicates that the channel was transferred
+	Transferred
+
+	// Disconnected indicates that a hangup node hung up the channel
+	Disconnected
+
+	// Hangup indicates that the channel hung up
+	Hangup
+
+	// NoInput indicates that a gather or record node without onFailure got no
+	// valid input
+	NoInput
+
+	// Cancelled indicates that the context was cancelled
+	Cancelled
+
+	// Failed indicates that a node failed
+	Failed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Completed:
+		return "completed"
+	case Transferred:
+		return "transferred"
+	case Disconnected:
+		return "disconnected"
+	case Hangup:
+		return "hangup"
+	case NoInput:
+		return "no input"
+	case Cancelled:
+		return "cancelled"
+	case Failed:
+		return "failed"
+	}
+
+	return "unknown"
+}
+
+// Result represents the result of a run of a menu
+type Result struct {
+	// Status indicates how the run ended
+	Status Status
+
+	// Menu and Node are the last node run
+	Menu string
+	Node string
+
+	// Steps is the number of nodes run
+	Steps int
+
+	// Vars are the menu variables at the end of the run
+	Vars map[string]string
+
+	// Peer and Bridge are the called channel and the bridge of a transfer to
+	// an endpoint
+	Peer   *channel.ChannelHandle
+	Bridge *bridge.BridgeHandle
+
+	// Duration indicates the duration of the run
+	Duration time.Duration
+
+	// Error holds any error encountered during the run
+	Error error
+}
+
+// Engine runs the menus of a Document on channels.  An Engine may run any
+// number of channels concurrently.
+type Engine struct {
+	doc *Document
+
+	bridge bridge.Bridge
+
+	vars map[string]string
+
+	maxSteps int
+}
+
+// OptionFunc is a function which applies changes to an Engine
+type OptionFunc func(*Engine)
+
+// WithBridge sets the bridge.Bridge, such as the one of the client, used by
+// the transfers to an endpoint
+func WithBridge(b bridge.Bridge) OptionFunc {
+	return func(e *Engine) {
+		e.bridge = b
+	}
+}
+
+// Vars sets the initial menu variables of each run
+func Vars(vars map[string]string) OptionFunc {
+	return func(e *Engine) {
+		for k, v := range vars {
+			e.vars[k] = v
+		}
+	}
+}
+
+// MaxSteps sets the number of nodes a run may go through.  Defaults to
+// DefaultMaxSteps.
+func MaxSteps(n int) OptionFunc {
+	return func(e *Engine) {
+		e.maxSteps = n
+	}
+}
+
+// New returns an Engine running the validated Document
+func New(doc *Document, opts ...OptionFunc) (*Engine, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		doc:      doc,
+		vars:     make(map[string]string),
+		maxSteps: DefaultMaxSteps,
+	}
+
+	for _, f := range opts {
+		f(e)
+	}
+
+	return e, nil
+}
+
+// Handler returns an ari.CallHandler running the menu on each channel.  The
+// transfers to an endpoint use the bridges of the client, unless WithBridge
+// was given.
+func (e *Engine) Handler() ari.CallHandler {
+	return func(ctx context.Context, a *ari.ARIClient, h *channel.ChannelHandle, _ *arievent.StasisEvent) {
+		b := e.bridge
+		if b == nil {
+			b = a.Bridge()
+		}
+
+		if _, err := e.run(ctx, h, b); err != nil {
+			logs.TLogger.Warn().Msgf("menu failed on channel %s: %s", h.ID(), err)
+		}
+	}
+}
+
+// Run runs the start menu of the Document on the channel, until the menu ends
+func (e *Engine) Run(ctx context.Context, ch *channel.ChannelHandle) (*Result, error) {
+	return e.run(ctx, ch, e.bridge)
+}
+
+func (e *Engine) run(ctx context.Context, ch *channel.ChannelHandle, b bridge.Bridge) (*Result, error) {
+	r := &run{
+		e:      e,
+		ch:     ch,
+		bridge: b,
+		res: &Result{
+			Vars: make(map[string]string, len(e.vars)),
+		},
+	}
+
+	for k, v := range e.vars {
+		r.res.Vars[k] = v
+	}
+
+	started := time.Now()
+
+	r.run(ctx)
+
+	r.res.Duration = time.Since(started)
+
+	logs.TLogger.Debug().Msgf("menu on channel %s ended at %s/%s: %s", ch.ID(), r.res.Menu, r.res.Node, r.res.Status)
+
+	return r.res, r.res.Error
+}
+
+type run struct {
+	e *Engine
+
+	ch *channel.ChannelHandle
+
+	bridge bridge.Bridge
+
+	res *Result
+
+	// jump is the menu to continue with, set by the menu nodes
+	jump string
+}
+
+func (r *run) run(ctx context.Context) {
+	name := r.e.doc.Start
+	m := r.e.doc.Menus[name]
+	id := m.Start
+
+	for {
+		if r.res.Steps >= r.e.maxSteps {
+			r.fail(ErrLoop)
+			return
+		}
+
+		if err := ctx.Err(); err != nil {
+			r.res.Status = Cancelled
+			r.res.Error = err
+
+			return
+		}
+
+		r.res.Menu, r.res.Node = name, id
+		r.res.Steps++
+
+		logs.TLogger.Debug().Msgf("menu on channel %s at %s/%s", r.ch.ID(), name, id)
+
+		next := r.exec(ctx, m.Nodes[id])
+		if r.res.Status != Running {
+			return
+		}
+
+		if r.jump != "" {
+			name, r.jump = r.jump, ""
+			m = r.e.doc.Menus[name]
+			next = m.Start
+		}
+
+		if next == "" {
+			r.res.Status = Completed
+			return
+		}
+
+		id = next
+	}
+}
+
+func (r *run) fail(err error) {
+	r.res.Status = Failed
+	r.res.Error = err
+}
+
+// expand replaces the ${name} references to menu variables
+func (r *run) expand(s string) string {
+	return os.Expand(s, func(name string) string {
+		return r.res.Vars[name]
+	})
+}
+
+func (r *run) expandAll(list []string) []string {
+	ret := make([]string, len(list))
+	for i, s := range list {
+		ret[i] = r.expand(s)
+	}
+
+	return ret
+}
+
+// exec runs the node and returns the name of the next node.  It sets the
+// status of the result when the run must end.
+func (r *run) exec(ctx context.Context, n *Node) string {
+	switch n.Type {
+	case PlayNode:
+		return r.play(ctx, n)
+	case GatherNode:
+		return r.gather(ctx, n)
+	case BranchNode:
+		return r.branch(n)
+	case SetNode:
+		value := r.expand(n.Value)
+		r.res.Vars[n.Var] = value
+
+		if err := r.ch.SetVariable(n.Var, value); err != nil {
+			r.fail(eris.Wrapf(err, "failed to set variable %s", n.Var))
+		}
+
+		return n.Next
+	case MenuNode:
+		r.jump = n.Menu
+		return ""
+	case TransferNode:
+		r.transfer(ctx, n.Transfer)
+		return ""
+	case RecordNode:
+		return r.record(ctx, n)
+	case HangupNode:
+		if err := r.ch.Hangup(); err != nil {
+			r.fail(eris.Wrap(err, "failed to hang up"))
+			return ""
+		}
+
+		r.res.Status = Disconnected
+	}
+
+	return ""
+}
+
+func (r *run) play(ctx context.Context, n *Node) string {
+	stopOn := n.StopOn
+	if stopOn == "" {
+		stopOn = "none"
+	}
+
+	res, err := play.Play(ctx, r.ch, play.URI(r.expandAll(n.Prompt)...), play.StopOnDTMF(stopOn)).Result()
+
+	switch res.Status {
+	case play.Finished, play.DTMF:
+		return n.Next
+	case play.Hangup:
+		r.res.Status = Hangup
+	case play.Cancelled:
+		r.res.Status = Cancelled
+		r.res.Error = ctx.Err()
+	default:
+		if err == nil {
+			err = eris.Errorf("prompt %s", res.Status)
+		}
+
+		r.fail(eris.Wrap(err, "failed to play prompt"))
+	}
+
+	return ""
+}
+
+// nolint: gocyclo
+func (r *run) gather(ctx context.Context, n *Node) string {
+	opts := []gather.OptionFunc{gather.Prompt(r.expandAll(n.Prompt)...)}
+
+	var pattern *regexp.Regexp
+
+	if spec := n.Gather; spec != nil {
+		opts = append(opts,
+			gather.MaxDigits(spec.MaxDigits),
+			gather.Retries(spec.Retries),
+			gather.RetryPrompt(r.expandAll(spec.RetryPrompt)...),
+		)
+
+		if spec.MinDigits > 0 {
+			opts = append(opts, gather.MinDigits(spec.MinDigits))
+		}
+
+		if spec.Terminators != nil {
+			opts = append(opts, gather.Terminators(*spec.Terminators))
+		}
+
+		if spec.FirstDigitTimeout > 0 {
+			opts = append(opts, gather.FirstDigitTimeout(time.Duration(spec.FirstDigitTimeout)))
+		}
+
+		if spec.InterDigitTimeout > 0 {
+			opts = append(opts, gather.InterDigitTimeout(time.Duration(spec.InterDigitTimeout)))
+		}
+
+		if spec.NoBargeIn {
+			opts = append(opts, gather.NoBargeIn())
+		}
+
+		if spec.Mask {
+			opts = append(opts, gather.Mask())
+		}
+
+		if spec.Pattern != "" {
+			var err error
+			if pattern, err = compilePattern(spec.Pattern); err != nil {
+				r.fail(eris.Wrap(err, "invalid pattern"))
+				return ""
+			}
+		}
+	}
+
+	// Without default, only the digits of a case are valid
+	opts = append(opts, gather.Validate(func(digits string) bool {
+		if pattern != nil && !pattern.MatchString(digits) {
+			return false
+		}
+
+		if len(n.Cases) > 0 && n.Default == "" {
+			_, ok := n.Cases[digits]
+			return ok
+		}
+
+		return true
+	}))
+
+	res, err := gather.Gather(ctx, r.ch, opts...)
+
+	switch res.Reason {
+	case gather.Complete, gather.Terminated, gather.DigitTimeout:
+		name := n.Var
+		if name == "" {
+			name = DigitsVar
+		}
+
+		r.res.Vars[name] = res.Digits
+
+		if target, ok := n.Cases[res.Digits]; ok {
+			return target
+		}
+
+		if n.Default != "" {
+			return n.Default
+		}
+
+		return n.Next
+	case gather.Timeout, gather.Invalid:
+		if n.OnFailure != "" {
+			return n.OnFailure
+		}
+
+		r.res.Status = NoInput
+	case gather.Hangup:
+		r.res.Status = Hangup
+	case gather.Cancelled:
+		r.res.Status = Cancelled
+		r.res.Error = err
+	default:
+		r.fail(err)
+	}
+
+	return ""
+}
+
+func (r *run) branch(n *Node) string {
+	value, ok := r.res.Vars[n.Var]
+	if !ok {
+		var err error
+
+		value, err = r.ch.GetVariable(n.Var)
+		if err != nil {
+			r.fail(eris.Wrapf(err, "failed to get variable %s", n.Var))
+			return ""
+		}
+	}
+
+	if target, ok := n.Cases[value]; ok {
+		return target
+	}
+
+	if n.Default != "" {
+		return n.Default
+	}
+
+	return n.Next
+}
+
+func (r *run) transfer(ctx context.Context, t *TransferSpec) {
+	if t.Endpoint == "" {
+		priority := t.Priority
+		if priority == 0 {
+			priority = 1
+		}
+
+		if err := r.ch.Continue(r.expand(t.Context), r.expand(t.Extension), priority); err != nil {
+			r.fail(eris.Wrap(err, "failed to continue in dialplan"))
+			return
+		}
+
+		r.res.Status = Transferred
+
+		return
+	}
+
+	if r.bridge == nil {
+		r.fail(ErrNoBridge)
+		return
+	}
+
+	timeout := time.Duration(t.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultTransferTimeout
+	}
+
+	peer, err := r.ch.Originate(requests.OriginateRequest{
+		Endpoint:  r.expand(t.Endpoint),
+		App:       r.ch.Key().GetApp(),
+		CallerID:  r.expand(t.CallerID),
+		Timeout:   int(timeout / time.Second),
+		ChannelID: rid.New(rid.Channel),
+	})
+	if err != nil {
+		r.fail(eris.Wrap(err, "failed to originate transfer"))
+		return
+	}
+
+	r.res.Peer = peer
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := peer.WaitStasis(waitCtx); err != nil {
+		if err := peer.Hangup(); err != nil {
+			logs.TLogger.Debug().Msgf("failed to hang up transfer peer %s: %s", peer.ID(), err)
+		}
+
+		r.res.Status = NoInput
+		r.res.Error = eris.Wrapf(err, "transfer to %s not answered", t.Endpoint)
+
+		return
+	}
+
+	br, err := r.bridge.Create(r.ch.Key().New(key.BridgeKey, rid.New(rid.Bridge)), "mixing", "")
+	if err != nil {
+		r.fail(eris.Wrap(err, "failed to create transfer bridge"))
+		return
+	}
+
+	r.res.Bridge = br
+
+	for _, id := range []string{r.ch.ID(), peer.ID()} {
+		if err := br.AddChannel(id); err != nil {
+			r.fail(eris.Wrapf(err, "failed to add channel %s to transfer bridge", id))
+			return
+		}
+	}
+
+	r.res.Status = Transferred
+}
+
+// nolint: gocyclo
+func (r *run) record(ctx context.Context, n *Node) string {
+	spec := n.Record
+	if spec == nil {
+		spec = &RecordSpec{}
+	}
+
+	if len(n.Prompt) > 0 {
+		if r.play(ctx, &Node{Prompt: n.Prompt, Next: "record"}) == "" {
+			return ""
+		}
+	}
+
+	name := r.expand(spec.Name)
+	if name == "" {
+		name = rid.New(rid.Recording)
+	}
+
+	opts := &arioptions.RecordingOptions{
+		Format:      spec.Format,
+		MaxDuration: time.Duration(spec.MaxDuration),
+		MaxSilence:  time.Duration(spec.MaxSilence),
+		Exists:      "overwrite",
+		Beep:        spec.Beep,
+		Terminate:   spec.TerminateOn,
+	}
+
+	if opts.Format == "" {
+		opts.Format = "wav"
+	}
+
+	if opts.Terminate == "" {
+		opts.Terminate = "#"
+	}
+
+	h, err := r.ch.StageRecord(name, opts)
+	if err != nil {
+		r.fail(eris.Wrap(err, "failed to stage recording"))
+		return ""
+	}
+
+	events := make(chan *arievent.StasisEvent, 8)
+	done := make(chan struct{})
+	defer close(done)
+
+	deliver := func(e *arievent.StasisEvent) {
+		select {
+		case events <- e:
+		case <-done:
+		}
+	}
+
+	// Subscribe before starting the recording, so that no event is missed
+	sub := h.Subscribe(deliver, arievent.RecordingStarted, arievent.RecordingFinished, arievent.RecordingFailed)
+	defer sub.Cancel()
+
+	hangup := r.ch.Subscribe(deliver, arievent.ChannelHangupRequest, arievent.ChannelDestroyed, arievent.StasisEnd)
+	defer hangup.Cancel()
+
+	if err := h.Exec(); err != nil {
+		r.fail(eris.Wrap(err, "failed to start recording"))
+		return ""
+	}
+
+	timer := time.NewTimer(recordings.RecordingStartTimeout)
+	defer timer.Stop()
+
+	failed := func(err error) string {
+		if n.OnFailure != "" {
+			logs.TLogger.Debug().Msgf("recording %s failed: %s", name, err)
+			return n.OnFailure
+		}
+
+		r.fail(err)
+
+		return ""
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := h.Stop(); err != nil {
+				logs.TLogger.Debug().Msgf("failed to stop recording %s: %s", name, err)
+			}
+
+			r.res.Status = Cancelled
+			r.res.Error = ctx.Err()
+
+			return ""
+		case <-timer.C:
+			return failed(eris.Errorf("timeout waiting for recording %s to start", name))
+		case e := <-events:
+			switch e.GetType() {
+			case arievent.RecordingStarted:
+				timer.Stop()
+			case arievent.RecordingFailed:
+				return failed(eris.Errorf("recording %s failed", name))
+			case arievent.RecordingFinished:
+				varName := n.Var
+				if varName == "" {
+					varName = RecordingVar
+				}
+
+				r.res.Vars[varName] = name
+
+				return n.Next
+			default:
+				r.res.Status = Hangup
+				return ""
+			}
+		}
+	}
+}
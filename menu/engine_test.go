@@ -0,0 +1,465 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/bridge"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/play"
+	"github.com/callevo/ari/requests"
+	"github.com/rotisserie/eris"
+)
+
+// fakeChannel stands for the channels of application app.  A playback of a
+// media URI emits the scripted events of the URI through a dispatcher; channel
+// events are those of channel c1.  The originated channels enter the
+// application when answer is set.
+type fakeChannel struct {
+	channel.Channel
+
+	d      *dispatcher.EventDispatcher
+	script map[string][]arievent.StasisEvent
+	answer bool
+
+	mu         sync.Mutex
+	vars       map[string]string
+	originated []requests.OriginateRequest
+	continued  string
+	hangups    int
+}
+
+func newFakeChannel(script map[string][]arievent.StasisEvent, answer bool) *fakeChannel {
+	return &fakeChannel{
+		d:      dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered)),
+		script: script,
+		answer: answer,
+		vars:   make(map[string]string),
+	}
+}
+
+func subscribeKey(d *dispatcher.EventDispatcher, k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return d.Subscribe(func(e *arievent.StasisEvent) {
+		if e.Matches(k) {
+			l(e)
+		}
+	}, dispatcher.DefaultPriority, types...)
+}
+
+func (c *fakeChannel) StagePlay(k *key.Key, id, uri string) (*play.PlaybackHandle, error) {
+	return play.NewPlaybackHandle(key.NewKey(key.PlaybackKey, id), &fakePlayback{c}, func(h *play.PlaybackHandle) error {
+		go c.emit(id, c.script[uri])
+		return nil
+	}), nil
+}
+
+func (c *fakeChannel) Subscribe(k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return subscribeKey(c.d, k, l, types...)
+}
+
+func (c *fakeChannel) emit(id string, steps []arievent.StasisEvent) {
+	for _, s := range steps {
+		e := s
+		if s.Playback != nil {
+			e.Playback = &arievent.PlaybackData{ID: id, State: s.Playback.State}
+		} else {
+			e.Channel = arievent.ChannelData{ID: "c1"}
+		}
+
+		c.d.Dispatch(&e)
+	}
+}
+
+func (c *fakeChannel) SetVariable(k *key.Key, name, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.vars[name] = value
+
+	return nil
+}
+
+func (c *fakeChannel) GetVariable(k *key.Key, name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.vars[name]; ok {
+		return v, nil
+	}
+
+	return "", eris.Errorf("no variable %s", name)
+}
+
+func (c *fakeChannel) Continue(k *key.Key, context, extension string, priority int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.continued = fmt.Sprintf("%s,%s,%d", context, extension, priority)
+
+	return nil
+}
+
+func (c *fakeChannel) Hangup(k *key.Key, reason channel.HangupReason) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hangups++
+
+	return nil
+}
+
+func (c *fakeChannel) Originate(k *key.Key, req requests.OriginateRequest) (*channel.ChannelHandle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.originated = append(c.originated, req)
+
+	stasis := make(chan struct{})
+	if c.answer && req.App != "" {
+		close(stasis)
+	}
+
+	return channel.NewOriginatedChannelHandle(k.New(key.ChannelKey, req.ChannelID), c, nil, stasis), nil
+}
+
+type fakePlayback struct {
+	c *fakeChannel
+}
+
+func (pb *fakePlayback) Get(k *key.Key) *play.PlaybackHandle {
+	return play.NewPlaybackHandle(k, pb, nil)
+}
+
+func (pb *fakePlayback) Data(k *key.Key) (*play.PlaybackData, error) {
+	return &play.PlaybackData{ID: k.GetID()}, nil
+}
+
+func (pb *fakePlayback) Control(k *key.Key, op string) error { return nil }
+
+func (pb *fakePlayback) Stop(k *key.Key) error { return nil }
+
+func (pb *fakePlayback) Subscribe(k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return subscribeKey(pb.c.d, k, l, types...)
+}
+
+// fakeBridge records the channels added to its bridges
+type fakeBridge struct {
+	bridge.Bridge
+
+	mu    sync.Mutex
+	added []string
+}
+
+func (b *fakeBridge) Create(k *key.Key, btype, name string) (*bridge.BridgeHandle, error) {
+	return bridge.NewBridgeHandle(k, b, nil), nil
+}
+
+func (b *fakeBridge) AddChannel(k *key.Key, channelID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.added = append(b.added, channelID)
+
+	return nil
+}
+
+var (
+	started  = arievent.StasisEvent{Type: arievent.PlaybackStarted, Playback: &arievent.PlaybackData{}}
+	finished = arievent.StasisEvent{Type: arievent.PlaybackFinished, Playback: &arievent.PlaybackData{State: "done"}}
+	hangup   = arievent.StasisEvent{Type: arievent.ChannelHangupRequest}
+)
+
+// prompt returns the events of a prompt during which the digits are entered
+func prompt(digits string, tail ...arievent.StasisEvent) []arievent.StasisEvent {
+	steps := []arievent.StasisEvent{started}
+	for _, d := range digits {
+		steps = append(steps, arievent.StasisEvent{Type: arievent.ChannelDtmfReceived, Digit: string(d)})
+	}
+
+	return append(steps, tail...)
+}
+
+func TestEngineRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		opts      []OptionFunc
+		script    map[string][]arievent.StasisEvent
+		answer    bool
+		status    Status
+		node      string
+		vars      map[string]string
+		hangups   int
+		continued string
+		bridged   int
+		wantErr   bool
+	}{
+		{
+			name: "play",
+			doc: `
+start: main
+menus:
+  main:
+    start: hello
+    nodes:
+      hello: {type: play, prompt: [sound:hello]}
+`,
+			script: map[string][]arievent.StasisEvent{"sound:hello": prompt("", finished)},
+			status: Completed,
+			node:   "hello",
+		},
+		{
+			name: "gather case",
+			doc: `
+start: main
+menus:
+  main:
+    start: ask
+    nodes:
+      ask: {type: gather, prompt: [sound:menu], gather: {maxDigits: 1}, cases: {"1": one}, default: other}
+      one: {type: set, var: choice, value: "one ${digits}"}
+      other: {type: hangup}
+`,
+			script: map[string][]arievent.StasisEvent{"sound:menu": prompt("1")},
+			status: Completed,
+			node:   "one",
+			vars:   map[string]string{"digits": "1", "choice": "one 1"},
+		},
+		{
+			name: "pattern matches the whole input",
+			doc: `
+start: main
+menus:
+  main:
+    start: ask
+    nodes:
+      ask: {type: gather, prompt: [sound:pin], var: pin, gather: {pattern: "1[0-9]"}, onFailure: bye}
+      bye: {type: hangup}
+`,
+			script:  map[string][]arievent.StasisEvent{"sound:pin": prompt("123#")},
+			status:  Disconnected,
+			node:    "bye",
+			hangups: 1,
+		},
+		{
+			name: "pattern matched",
+			doc: `
+start: main
+menus:
+  main:
+    start: ask
+    nodes:
+      ask: {type: gather, prompt: [sound:pin], var: pin, gather: {pattern: "1[0-9]"}, onFailure: bye}
+      bye: {type: hangup}
+`,
+			script: map[string][]arievent.StasisEvent{"sound:pin": prompt("12#")},
+			status: Completed,
+			node:   "ask",
+			vars:   map[string]string{"pin": "12"},
+		},
+		{
+			name: "terminator alone",
+			doc: `
+start: main
+menus:
+  main:
+    start: ask
+    nodes:
+      ask: {type: gather, prompt: [sound:menu]}
+`,
+			script: map[string][]arievent.StasisEvent{"sound:menu": prompt("#")},
+			status: NoInput,
+			node:   "ask",
+		},
+		{
+			name: "hangup during prompt",
+			doc: `
+start: main
+menus:
+  main:
+    start: hello
+    nodes:
+      hello: {type: play, prompt: [sound:hello]}
+`,
+			script: map[string][]arievent.StasisEvent{"sound:hello": prompt("", hangup)},
+			status: Hangup,
+			node:   "hello",
+		},
+		{
+			name: "branch and menu jump",
+			doc: `
+start: main
+menus:
+  main:
+    start: lang
+    nodes:
+      lang: {type: set, var: lang, value: fr, next: route}
+      route: {type: branch, var: lang, cases: {fr: french}, default: bye}
+      french: {type: menu, menu: fr}
+      bye: {type: hangup}
+  fr:
+    start: bye
+    nodes:
+      bye: {type: hangup}
+`,
+			status:  Disconnected,
+			node:    "bye",
+			vars:    map[string]string{"lang": "fr"},
+			hangups: 1,
+		},
+		{
+			name: "loop",
+			doc: `
+start: main
+menus:
+  main:
+    start: a
+    nodes:
+      a: {type: set, var: x, value: a, next: b}
+      b: {type: set, var: x, value: b, next: a}
+`,
+			opts:    []OptionFunc{MaxSteps(5)},
+			status:  Failed,
+			node:    "a",
+			wantErr: true,
+		},
+		{
+			name: "transfer to an extension",
+			doc: `
+start: main
+menus:
+  main:
+    start: out
+    nodes:
+      out: {type: transfer, transfer: {context: sales, extension: "${ext}"}}
+`,
+			opts:      []OptionFunc{Vars(map[string]string{"ext": "200"})},
+			status:    Transferred,
+			node:      "out",
+			continued: "sales,200,1",
+		},
+		{
+			name: "transfer to an endpoint",
+			doc: `
+start: main
+menus:
+  main:
+    start: out
+    nodes:
+      out: {type: transfer, transfer: {endpoint: PJSIP/100}}
+`,
+			answer:  true,
+			status:  Transferred,
+			node:    "out",
+			bridged: 2,
+		},
+		{
+			name: "transfer not answered",
+			doc: `
+start: main
+menus:
+  main:
+    start: out
+    nodes:
+      out: {type: transfer, transfer: {endpoint: PJSIP/100, timeout: 20ms}}
+`,
+			status:  NoInput,
+			node:    "out",
+			hangups: 1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseYAML([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := newFakeChannel(tt.script, tt.answer)
+			b := &fakeBridge{}
+
+			e, err := New(doc, append([]OptionFunc{WithBridge(b)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ch := channel.NewChannelHandle(key.NewKey(key.ChannelKey, "c1", key.WithApp("app"), key.WithNode("n1")), c, nil)
+
+			res, err := e.Run(context.Background(), ch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if res.Status != tt.status || res.Node != tt.node {
+				t.Errorf("ended at %s with %s, want %s with %s", res.Node, res.Status, tt.node, tt.status)
+			}
+			for k, v := range tt.vars {
+				if res.Vars[k] != v {
+					t.Errorf("variable %s = %q, want %q", k, res.Vars[k], v)
+				}
+			}
+			if c.hangups != tt.hangups {
+				t.Errorf("%d hangups, want %d", c.hangups, tt.hangups)
+			}
+			if c.continued != tt.continued {
+				t.Errorf("continued at %q, want %q", c.continued, tt.continued)
+			}
+			if len(b.added) != tt.bridged {
+				t.Errorf("bridged %v, want %d channels", b.added, tt.bridged)
+			}
+		})
+	}
+}
+
+func TestEngineTransferApp(t *testing.T) {
+	doc, err := ParseYAML([]byte(`
+start: main
+menus:
+  main:
+    start: out
+    nodes:
+      out: {type: transfer, transfer: {endpoint: PJSIP/100, callerId: "${caller}"}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newFakeChannel(nil, true)
+	b := &fakeBridge{}
+
+	e, err := New(doc, WithBridge(b), Vars(map[string]string{"caller": "<100>"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := channel.NewChannelHandle(key.NewKey(key.ChannelKey, "c1", key.WithApp("app"), key.WithNode("n1")), c, nil)
+
+	res, err := e.Run(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.originated) != 1 {
+		t.Fatalf("%d originates, want 1", len(c.originated))
+	}
+
+	req := c.originated[0]
+	if req.App != "app" || req.Endpoint != "PJSIP/100" || req.CallerID != "<100>" || req.ChannelID == "" {
+		t.Errorf("originated %+v", req)
+	}
+
+	if res.Peer == nil || res.Peer.ID() != req.ChannelID {
+		t.Errorf("peer %v, want %s", res.Peer, req.ChannelID)
+	}
+
+	if len(b.added) != 2 || b.added[0] != "c1" || b.added[1] != req.ChannelID {
+		t.Errorf("bridged %v", b.added)
+	}
+}
@@ -0,0 +1,459 @@
+// Package menu loads IVR menus described in JSON or YAML, and runs them on
+// live channels
+package menu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"gopkg.in/yaml.v3"
+)
+
+// NodeType is the type of a menu Node
+type NodeType string
+
+const (
+	// PlayNode plays the prompt, then goes to the next node
+	PlayNode NodeType = "play"
+
+	// GatherNode plays the prompt and collects digits, then branches on the
+	// digits or goes to the next node
+	GatherNode NodeType = "gather"
+
+	// BranchNode goes to the node matching the value of a variable
+	BranchNode NodeType = "branch"
+
+	// SetNode sets a variable, then goes to the next node
+	SetNode NodeType = "set"
+
+	// MenuNode jumps to the start of another menu
+	MenuNode NodeType = "menu"
+
+	// TransferNode continues the channel in the dialplan, or originates a call to
+	// an endpoint and bridges the channel with it.  It ends the menu.
+	TransferNode NodeType = "transfer"
+
+	// RecordNode plays the prompt and records the channel, then goes to the next
+	// node
+	RecordNode NodeType = "record"
+
+	// HangupNode hangs up the channel.  It ends the menu.
+	HangupNode NodeType = "hangup"
+)
+
+// Document is a set of menus, as loaded from a file
+type Document struct {
+	// Start is the name of the menu run first
+	Start string `json:"start" yaml:"start"`
+
+	// Menus are the menus by name
+	Menus map[string]*Menu `json:"menus" yaml:"menus"`
+}
+
+// Menu is a tree of nodes
+type Menu struct {
+	// Start is the name of the node run first
+	Start string `json:"start" yaml:"start"`
+
+	// Nodes are the nodes of the menu by name
+	Nodes map[string]*Node `json:"nodes" yaml:"nodes"`
+}
+
+// Node is one step of a menu.  Values may refer to the menu variables as
+// ${name}.
+type Node struct {
+	Type NodeType `json:"type" yaml:"type"`
+
+	// Prompt are the media URIs played by the play, gather and record nodes
+	Prompt []string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+
+	// Next is the node run after this one.  A node without next ends the
+	// menu.
+	Next string `json:"next,omitempty" yaml:"next,omitempty"`
+
+	// Var is the variable set by the set, gather and record nodes, or tested
+	// by the branch nodes
+	Var string `json:"var,omitempty" yaml:"var,omitempty"`
+
+	// Value is the value given to Var by the set nodes
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+
+	// Cases map the values of Var, or the gathered digits, to the nodes to
+	// branch to
+	Cases map[string]string `json:"cases,omitempty" yaml:"cases,omitempty"`
+
+	// Default is the node branched to when no case matches
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// OnFailure is the node run when a gather collects no valid input or a
+	// record fails.  Without it, the menu ends.
+	OnFailure string `json:"onFailure,omitempty" yaml:"onFailure,omitempty"`
+
+	// StopOn are the digits which stop the prompt of a play node
+	StopOn string `json:"stopOn,omitempty" yaml:"stopOn,omitempty"`
+
+	// Menu is the menu jumped to by the menu nodes
+	Menu string `json:"menu,omitempty" yaml:"menu,omitempty"`
+
+	Gather *GatherSpec `json:"gather,omitempty" yaml:"gather,omitempty"`
+
+	Transfer *TransferSpec `json:"transfer,omitempty" yaml:"transfer,omitempty"`
+
+	Record *RecordSpec `json:"record,omitempty" yaml:"record,omitempty"`
+}
+
+// GatherSpec configures the digit collection of a gather node
+type GatherSpec struct {
+	// MinDigits is the minimum number of digits of a valid input.  Defaults
+	// to 1: a terminator entered alone is no input.
+	MinDigits int `json:"minDigits,omitempty" yaml:"minDigits,omitempty"`
+	MaxDigits int `json:"maxDigits,omitempty" yaml:"maxDigits,omitempty"`
+
+	// Terminators are the digits ending the input.  Defaults to "#".
+	Terminators *string `json:"terminators,omitempty" yaml:"terminators,omitempty"`
+
+	FirstDigitTimeout Duration `json:"firstDigitTimeout,omitempty" yaml:"firstDigitTimeout,omitempty"`
+	InterDigitTimeout Duration `json:"interDigitTimeout,omitempty" yaml:"interDigitTimeout,omitempty"`
+
+	Retries     int      `json:"retries,omitempty" yaml:"retries,omitempty"`
+	RetryPrompt []string `json:"retryPrompt,omitempty" yaml:"retryPrompt,omitempty"`
+
+	// Pattern is a regular expression the whole input must match
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	NoBargeIn bool `json:"noBargeIn,omitempty" yaml:"noBargeIn,omitempty"`
+
+	// Mask hides the digits in the logs
+	Mask bool `json:"mask,omitempty" yaml:"mask,omitempty"`
+}
+
+// TransferSpec configures the destination of a transfer node.  Either the
+// dialplan location or the endpoint is set.
+type TransferSpec struct {
+	Context   string `json:"context,omitempty" yaml:"context,omitempty"`
+	Extension string `json:"extension,omitempty" yaml:"extension,omitempty"`
+	Priority  int    `json:"priority,omitempty" yaml:"priority,omitempty"`
+
+	// Endpoint is called and bridged with the channel, such as PJSIP/100
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	CallerID string   `json:"callerId,omitempty" yaml:"callerId,omitempty"`
+	Timeout  Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// RecordSpec configures a record node
+type RecordSpec struct {
+	// Name is the name of the stored recording.  Defaults to a new ID.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Format defaults to wav
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+
+	MaxDuration Duration `json:"maxDuration,omitempty" yaml:"maxDuration,omitempty"`
+	MaxSilence  Duration `json:"maxSilence,omitempty" yaml:"maxSilence,omitempty"`
+
+	Beep bool `json:"beep,omitempty" yaml:"beep,omitempty"`
+
+	// TerminateOn is the DTMF which ends the recording.  Defaults to "#".
+	TerminateOn string `json:"terminateOn,omitempty" yaml:"terminateOn,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "5s" or "1m30s"
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return eris.Wrap(err, "duration must be a string such as \"5s\"")
+	}
+
+	return d.parse(s)
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return eris.Wrap(err, "duration must be a string such as \"5s\"")
+	}
+
+	return d.parse(s)
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) parse(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return eris.Wrapf(err, "invalid duration %q", s)
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// ParseJSON loads and validates a Document written in JSON
+func ParseJSON(data []byte) (*Document, error) {
+	doc := new(Document)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, eris.Wrap(err, "failed to parse menu")
+	}
+
+	return doc, doc.Validate()
+}
+
+// ParseYAML loads and validates a Document written in YAML
+func ParseYAML(data []byte) (*Document, error) {
+	doc := new(Document)
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, eris.Wrap(err, "failed to parse menu")
+	}
+
+	return doc, doc.Validate()
+}
+
+// LoadFile loads and validates a Document from a file, written in YAML when
+// its extension is .yaml or .yml and in JSON otherwise
+func LoadFile(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to read menu")
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAML(data)
+	}
+
+	return ParseJSON(data)
+}
+
+// ValidationError lists the problems found in a Document
+type ValidationError struct {
+	Problems []string
+}
+
+func (err *ValidationError) Error() string {
+	return "invalid menu: " + strings.Join(err.Problems, "; ")
+}
+
+// Validate checks that every node is complete, that every reference points to
+// an existing node or menu, and that every node and menu can be reached.  It
+// returns a *ValidationError listing the problems found.
+func (doc *Document) Validate() error {
+	v := &validator{doc: doc}
+	v.validate()
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: v.problems}
+}
+
+type validator struct {
+	doc *Document
+
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) validate() {
+	if len(v.doc.Menus) == 0 {
+		v.addf("no menu")
+		return
+	}
+
+	if _, ok := v.doc.Menus[v.doc.Start]; !ok {
+		v.addf("start menu %q does not exist", v.doc.Start)
+	}
+
+	for _, name := range sortedKeys(v.doc.Menus) {
+		m := v.doc.Menus[name]
+		if m == nil {
+			v.addf("menu %s: empty", name)
+			continue
+		}
+
+		if _, ok := m.Nodes[m.Start]; !ok {
+			v.addf("menu %s: start node %q does not exist", name, m.Start)
+		}
+
+		for _, id := range sortedKeys(m.Nodes) {
+			v.validateNode(name, m, id)
+		}
+	}
+
+	v.reachability()
+}
+
+// nolint: gocyclo
+func (v *validator) validateNode(menuName string, m *Menu, id string) {
+	n := m.Nodes[id]
+	if n == nil {
+		v.addf("menu %s, node %s: empty", menuName, id)
+		return
+	}
+
+	ref := func(field, target string) {
+		if target == "" {
+			return
+		}
+		if _, ok := m.Nodes[target]; !ok {
+			v.addf("menu %s, node %s: %s node %q does not exist", menuName, id, field, target)
+		}
+	}
+
+	ref("next", n.Next)
+	ref("default", n.Default)
+	ref("onFailure", n.OnFailure)
+
+	for _, value := range sortedKeys(n.Cases) {
+		ref("case "+value, n.Cases[value])
+	}
+
+	switch n.Type {
+	case PlayNode, GatherNode, RecordNode:
+		if n.Type != RecordNode && len(n.Prompt) == 0 {
+			v.addf("menu %s, node %s: missing prompt", menuName, id)
+		}
+
+		for _, uri := range n.Prompt {
+			if uri == "" {
+				v.addf("menu %s, node %s: empty prompt", menuName, id)
+			}
+		}
+
+		if n.Gather != nil && n.Gather.MaxDigits > 0 && n.Gather.MinDigits > n.Gather.MaxDigits {
+			v.addf("menu %s, node %s: minDigits above maxDigits", menuName, id)
+		}
+
+		if n.Gather != nil && n.Gather.Pattern != "" {
+			if _, err := compilePattern(n.Gather.Pattern); err != nil {
+				v.addf("menu %s, node %s: invalid pattern: %s", menuName, id, err)
+			}
+		}
+	case BranchNode:
+		if n.Var == "" {
+			v.addf("menu %s, node %s: missing var", menuName, id)
+		}
+
+		if len(n.Cases) == 0 && n.Default == "" {
+			v.addf("menu %s, node %s: missing cases", menuName, id)
+		}
+	case SetNode:
+		if n.Var == "" {
+			v.addf("menu %s, node %s: missing var", menuName, id)
+		}
+	case MenuNode:
+		if _, ok := v.doc.Menus[n.Menu]; !ok {
+			v.addf("menu %s, node %s: menu %q does not exist", menuName, id, n.Menu)
+		}
+	case TransferNode:
+		t := n.Transfer
+		if t == nil || (t.Endpoint == "" && t.Extension == "") {
+			v.addf("menu %s, node %s: missing transfer extension or endpoint", menuName, id)
+		} else if t.Endpoint != "" && t.Extension != "" {
+			v.addf("menu %s, node %s: transfer to both an extension and an endpoint", menuName, id)
+		}
+	case HangupNode:
+	default:
+		v.addf("menu %s, node %s: unknown type %q", menuName, id, n.Type)
+	}
+}
+
+// reachability reports the menus and nodes which cannot be reached from the
+// start of the document
+func (v *validator) reachability() {
+	menus := make(map[string]bool)
+	nodes := make(map[string]map[string]bool)
+
+	var visitMenu func(name string)
+	visitMenu = func(name string) {
+		m := v.doc.Menus[name]
+		if m == nil || menus[name] {
+			return
+		}
+
+		menus[name] = true
+		nodes[name] = make(map[string]bool)
+
+		stack := []string{m.Start}
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			n := m.Nodes[id]
+			if n == nil || nodes[name][id] {
+				continue
+			}
+
+			nodes[name][id] = true
+
+			stack = append(stack, n.Next, n.Default, n.OnFailure)
+			for _, target := range n.Cases {
+				stack = append(stack, target)
+			}
+
+			if n.Type == MenuNode {
+				visitMenu(n.Menu)
+			}
+		}
+	}
+
+	visitMenu(v.doc.Start)
+
+	for _, name := range sortedKeys(v.doc.Menus) {
+		if v.doc.Menus[name] == nil {
+			continue
+		}
+
+		if !menus[name] {
+			v.addf("menu %s: unreachable", name)
+			continue
+		}
+
+		for _, id := range sortedKeys(v.doc.Menus[name].Nodes) {
+			if !nodes[name][id] {
+				v.addf("menu %s, node %s: unreachable", name, id)
+			}
+		}
+	}
+}
+
+// compilePattern compiles the pattern of a gather node, anchored so that it
+// matches the whole input
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
@@ -0,0 +1,225 @@
+package menu
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		problems []string
+	}{
+		{
+			name: "valid",
+			doc: `
+start: main
+menus:
+  main:
+    start: ask
+    nodes:
+      ask: {type: gather, prompt: [sound:menu], gather: {pattern: "[0-9]+"}, cases: {"1": bye}}
+      bye: {type: hangup}
+`,
+		},
+		{
+			name:     "no menu",
+			doc:      `start: main`,
+			problems: []string{"no menu"},
+		},
+		{
+			name: "missing start",
+			doc: `
+start: other
+menus:
+  main:
+    start: nowhere
+    nodes:
+      bye: {type: hangup}
+`,
+			problems: []string{
+				`start menu "other" does not exist`,
+				`menu main: start node "nowhere" does not exist`,
+				"menu main: unreachable",
+			},
+		},
+		{
+			name: "dangling reference",
+			doc: `
+start: main
+menus:
+  main:
+    start: hello
+    nodes:
+      hello: {type: play, prompt: [sound:hello], next: nowhere}
+`,
+			problems: []string{`menu main, node hello: next node "nowhere" does not exist`},
+		},
+		{
+			name: "invalid gather",
+			doc: `
+start: main
+menus:
+  main:
+    start: ask
+    nodes:
+      ask: {type: gather, gather: {minDigits: 3, maxDigits: 2, pattern: "("}}
+`,
+			problems: []string{
+				"menu main, node ask: missing prompt",
+				"menu main, node ask: minDigits above maxDigits",
+				"menu main, node ask: invalid pattern",
+			},
+		},
+		{
+			name: "invalid transfer",
+			doc: `
+start: main
+menus:
+  main:
+    start: a
+    nodes:
+      a: {type: transfer, next: b}
+      b: {type: transfer, transfer: {extension: "100", endpoint: PJSIP/100}}
+`,
+			problems: []string{
+				"menu main, node a: missing transfer extension or endpoint",
+				"menu main, node b: transfer to both an extension and an endpoint",
+			},
+		},
+		{
+			name: "unreachable",
+			doc: `
+start: main
+menus:
+  main:
+    start: a
+    nodes:
+      a: {type: hangup}
+      b: {type: hangup}
+  other:
+    start: c
+    nodes:
+      c: {type: hangup}
+`,
+			problems: []string{
+				"menu main, node b: unreachable",
+				"menu other: unreachable",
+			},
+		},
+		{
+			name: "unknown type",
+			doc: `
+start: main
+menus:
+  main:
+    start: a
+    nodes:
+      a: {type: dance}
+`,
+			problems: []string{`menu main, node a: unknown type "dance"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAML([]byte(tt.doc))
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("error = %v, want a *ValidationError", err)
+			}
+
+			if len(verr.Problems) != len(tt.problems) {
+				t.Fatalf("problems %q, want %q", verr.Problems, tt.problems)
+			}
+			for i, p := range tt.problems {
+				if !strings.HasPrefix(verr.Problems[i], p) {
+					t.Errorf("problem %q, want %q", verr.Problems[i], p)
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{"1[0-9]", "12", true},
+		{"1[0-9]", "123", false},
+		{"1[0-9]", "012", false},
+		{"1|22", "22", true},
+		{"1|22", "122", false},
+		{"[0-9]{4}", "1234", true},
+	}
+
+	for _, tt := range tests {
+		re, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if match := re.MatchString(tt.input); match != tt.match {
+			t.Errorf("%q matches %q: %v, want %v", tt.pattern, tt.input, match, tt.match)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		file    string
+		data    string
+		timeout time.Duration
+	}{
+		{
+			file: "menu.json",
+			data: `{"start": "main", "menus": {"main": {"start": "ask", "nodes": {
+				"ask": {"type": "gather", "prompt": ["sound:menu"], "gather": {"firstDigitTimeout": "2s"}}}}}}`,
+			timeout: 2 * time.Second,
+		},
+		{
+			file: "menu.yml",
+			data: `
+start: main
+menus:
+  main:
+    start: ask
+    nodes:
+      ask: {type: gather, prompt: [sound:menu], gather: {firstDigitTimeout: 1m30s}}
+`,
+			timeout: 90 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			doc, err := LoadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d := time.Duration(doc.Menus["main"].Nodes["ask"].Gather.FirstDigitTimeout); d != tt.timeout {
+				t.Errorf("first digit timeout %s, want %s", d, tt.timeout)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	arievent "github.com/callevo/ari/arievent"
@@ -181,13 +182,27 @@ func (n *NatsBus) SubscribeAnnounce(topic string, callback AnnounceHandler) (*na
 	})
 }
 
-// ListenQueue is the queue group to use for distributing arieventStart events to Listeners.
+// ListenQueue is the default queue group to use for distributing StasisStart
+// events to Listeners.
 var ListenQueue = "AsteriskARIProxyDistributionQueue"
 
 func (n *NatsBus) SubscribeEvent(topic string, callback EventHandler) (*nats.Subscription, error) {
-	logs.TLogger.Debug().Msgf("Subscribing to %s", topic)
+	return n.QueueSubscribeEvent(topic, ListenQueue, func(o *arievent.StasisEvent, _ *nats.Msg) {
+		if callback != nil {
+			callback(o)
+		}
+	})
+}
+
+// MsgEventHandler handles Events along with the message which carried them
+type MsgEventHandler func(o *arievent.StasisEvent, msg *nats.Msg)
 
-	return n.conn.QueueSubscribe(topic, ListenQueue, func(msg *nats.Msg) {
+// QueueSubscribeEvent subscribes to the events of the topic as a member of the
+// given queue group
+func (n *NatsBus) QueueSubscribeEvent(topic, queue string, callback MsgEventHandler) (*nats.Subscription, error) {
+	logs.TLogger.Debug().Msgf("Subscribing to %s in queue %s", topic, queue)
+
+	return n.conn.QueueSubscribe(topic, queue, func(msg *nats.Msg) {
 
 		evt := arievent.StasisEvent{}
 
@@ -199,11 +214,97 @@ func (n *NatsBus) SubscribeEvent(topic string, callback EventHandler) (*nats.Sub
 		}
 
 		if callback != nil {
-			callback(&evt)
+			callback(&evt, msg)
 		}
 	})
 }
 
+// RequeueHeader counts how many times an event was put back in its queue group
+const RequeueHeader = "Ari-Requeue-Count"
+
+// RefusedByHeader lists the instances which refused a requeued event
+const RefusedByHeader = "Ari-Refused-By"
+
+// PassHeader counts how many times a requeued event was passed on by an
+// instance which had refused it already
+const PassHeader = "Ari-Requeue-Passes"
+
+// Requeue publishes the event message again on the requeue subject of its
+// queue group, so that another member of the group may pick it up.  The
+// subject is only subscribed by the queue group, so that the plain
+// subscribers of the original subject do not see the event twice.  It
+// returns the number of times the message has now been requeued.
+func (n *NatsBus) Requeue(msg *nats.Msg, topic, instance string) (int, error) {
+	out, count := RequeueMsg(msg, topic, instance)
+
+	return count, n.conn.PublishMsg(out)
+}
+
+// RequeueMsg returns the copy of the event message to publish on the requeue
+// subject, along with its requeue count.  The refusing instance is added to
+// the RefusedByHeader and the requeue count is incremented, unless the
+// instance refused the message already: it is then only passed on, which
+// increments the PassHeader instead.  The reply subject is kept.
+func RequeueMsg(msg *nats.Msg, topic, instance string) (*nats.Msg, int) {
+	out := nats.NewMsg(topic)
+	out.Reply = msg.Reply
+	out.Data = msg.Data
+	for k, v := range msg.Header {
+		out.Header[k] = append([]string(nil), v...)
+	}
+
+	count := RequeueCount(msg)
+
+	if RefusedBy(msg, instance) {
+		out.Header.Set(PassHeader, strconv.Itoa(PassCount(msg)+1))
+		return out, count
+	}
+
+	count++
+	out.Header.Set(RequeueHeader, strconv.Itoa(count))
+	if instance != "" {
+		out.Header.Add(RefusedByHeader, instance)
+	}
+
+	return out, count
+}
+
+// RefusedBy reports whether the instance already refused the event message
+func RefusedBy(msg *nats.Msg, instance string) bool {
+	if msg == nil || msg.Header == nil || instance == "" {
+		return false
+	}
+
+	for _, v := range msg.Header.Values(RefusedByHeader) {
+		if v == instance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequeueCount returns the number of times the event message was requeued
+func RequeueCount(msg *nats.Msg) int {
+	if msg == nil || msg.Header == nil {
+		return 0
+	}
+
+	count, _ := strconv.Atoi(msg.Header.Get(RequeueHeader))
+	return count
+}
+
+// PassCount returns the number of times the requeued event message was passed
+// on by an instance which had refused it already
+func PassCount(msg *nats.Msg) int {
+	if msg == nil || msg.Header == nil {
+		return 0
+	}
+
+	count, _ := strconv.Atoi(msg.Header.Get(PassHeader))
+	return count
+}
+
 func (n *NatsBus) DynSubscription(topic string, callback EventHandler) (*nats.Subscription, error) {
 	logs.TLogger.Debug().Msgf("Subscribing to %s", topic+".>")
 
@@ -222,6 +323,15 @@ func (n *NatsBus) DynSubscription(topic string, callback EventHandler) (*nats.Su
 }
 
 func (n *NatsBus) Request(topic string, r *requests.Request) (*response.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return n.RequestContext(ctx, topic, r)
+}
+
+// RequestContext sends the request and waits for the response until the
+// context is done
+func (n *NatsBus) RequestContext(ctx context.Context, topic string, r *requests.Request) (*response.Response, error) {
 
 	if n.conn == nil {
 		return nil, fmt.Errorf("nil connection")
@@ -234,7 +344,7 @@ func (n *NatsBus) Request(topic string, r *requests.Request) (*response.Response
 		return nil, err
 	}
 
-	msg, err := n.conn.Request(topic, b, 3*time.Second)
+	msg, err := n.conn.RequestWithContext(ctx, topic, b)
 	if err != nil {
 		logs.TLogger.Debug().Msgf("err %s", err)
 
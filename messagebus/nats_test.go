@@ -0,0 +1,97 @@
+package messagebus
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+func TestRequeueHeaders(t *testing.T) {
+	msg := func(count string, refusedBy ...string) *nats.Msg {
+		m := nats.NewMsg("ari.requeue.app.q")
+		if count != "" {
+			m.Header.Set(RequeueHeader, count)
+		}
+		for _, r := range refusedBy {
+			m.Header.Add(RefusedByHeader, r)
+		}
+
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		msg      *nats.Msg
+		instance string
+		count    int
+		refused  bool
+	}{
+		{"nil message", nil, "w1", 0, false},
+		{"no header", &nats.Msg{}, "w1", 0, false},
+		{"fresh", msg(""), "w1", 0, false},
+		{"requeued", msg("2", "w2", "w3"), "w1", 2, false},
+		{"refused", msg("2", "w2", "w1"), "w1", 2, true},
+		{"no instance", msg("1", "w1"), "", 1, false},
+		{"invalid count", msg("x"), "w1", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequeueCount(tt.msg); got != tt.count {
+				t.Errorf("RequeueCount = %d, want %d", got, tt.count)
+			}
+			if got := RefusedBy(tt.msg, tt.instance); got != tt.refused {
+				t.Errorf("RefusedBy = %v, want %v", got, tt.refused)
+			}
+		})
+	}
+}
+
+func TestRequeueMsg(t *testing.T) {
+	tests := []struct {
+		name      string
+		refusedBy []string
+		passes    string
+		count     int
+		wantPass  int
+		wantBy    []string
+	}{
+		{"first refusal", nil, "", 1, 0, []string{"w1"}},
+		{"new refusal", []string{"w2"}, "", 2, 0, []string{"w2", "w1"}},
+		{"refused already", []string{"w1", "w2"}, "", 2, 1, []string{"w1", "w2"}},
+		{"passed on again", []string{"w1"}, "3", 1, 4, []string{"w1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := nats.NewMsg("ari.app.ast1.c1.stasisstart.x")
+			in.Reply = "reply.to"
+			if len(tt.refusedBy) > 0 {
+				in.Header.Set(RequeueHeader, strconv.Itoa(len(tt.refusedBy)))
+			}
+			for _, r := range tt.refusedBy {
+				in.Header.Add(RefusedByHeader, r)
+			}
+			if tt.passes != "" {
+				in.Header.Set(PassHeader, tt.passes)
+			}
+
+			out, count := RequeueMsg(in, "ari.requeue.app.q", "w1")
+
+			if count != tt.count || RequeueCount(out) != tt.count {
+				t.Errorf("count %d, header %d, want %d", count, RequeueCount(out), tt.count)
+			}
+			if PassCount(out) != tt.wantPass {
+				t.Errorf("passes %d, want %d", PassCount(out), tt.wantPass)
+			}
+			if by := out.Header.Values(RefusedByHeader); !reflect.DeepEqual(by, tt.wantBy) {
+				t.Errorf("refused by %v, want %v", by, tt.wantBy)
+			}
+			if out.Subject != "ari.requeue.app.q" || out.Reply != "reply.to" {
+				t.Errorf("published on %s replying to %s", out.Subject, out.Reply)
+			}
+		})
+	}
+}
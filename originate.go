@@ -0,0 +1,215 @@
+package ari
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/logs"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rotisserie/eris"
+)
+
+// ClaimBucket is the key-value bucket in which workers claim the channels they
+// originate into their own Stasis application
+var ClaimBucket = "AriOriginateClaims"
+
+// ClaimTTL is the time after which an unreleased originate claim expires
+var ClaimTTL = 5 * time.Minute
+
+// pendingOriginate tracks the channels originated by this client into its own
+// Stasis application, until they leave the application
+type pendingOriginate struct {
+	ids []string
+
+	mu sync.Mutex
+
+	// leases are the subscriptions to the tracked channels, by ID
+	leases map[string]*topicLease
+
+	// entered holds the IDs of the channels which entered the application
+	entered map[string]bool
+
+	// released holds the IDs of the channels no longer tracked
+	released map[string]bool
+
+	started chan struct{}
+	once    sync.Once
+}
+
+func (p *pendingOriginate) start(id string) {
+	p.mu.Lock()
+	p.entered[id] = true
+	p.mu.Unlock()
+
+	p.once.Do(func() {
+		close(p.started)
+	})
+}
+
+// claimKey returns the key-value key of the claim on the channel ID
+func claimKey(id string) string {
+	return "originate." + base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+// setupClaims opens the key-value bucket used by the originate claim protocol
+func (a *ARIClient) setupClaims(ctx context.Context, opts *Options) error {
+	if !opts.OriginateClaims {
+		return nil
+	}
+
+	kv, err := a.sbus.JetStream().CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket: ClaimBucket,
+		TTL:    ClaimTTL,
+	})
+	if err != nil {
+		return eris.Wrap(err, "failed to open the originate claims bucket")
+	}
+
+	a.claims = kv
+
+	return nil
+}
+
+// trackOriginate registers the channels which are about to be originated into
+// the given application.  The client subscribes to the events of each channel,
+// so that it receives their StasisStart directly, and claims each of them so
+// that the other members of the queue group leave them alone.  A claim holds
+// until the channel leaves the application, since its events may still reach
+// the other members, or until ClaimTTL if it never enters it.  Without claims,
+// only this client knows the channels, and the other members of its queue
+// group take them as well.
+func (a *ARIClient) trackOriginate(app, node string, ids ...string) (*pendingOriginate, error) {
+	if node == "" {
+		node = "*"
+	}
+
+	p := &pendingOriginate{
+		ids:      ids,
+		leases:   make(map[string]*topicLease),
+		entered:  make(map[string]bool),
+		released: make(map[string]bool),
+		started:  make(chan struct{}),
+	}
+
+	a.mu.Lock()
+	_, listening := a.intakes[app]
+	a.mu.Unlock()
+
+	if a.claims == nil && listening {
+		a.claimsWarning.Do(func() {
+			logs.TLogger.Warn().Msgf("originating into %s without OriginateClaims: the other members of queue group %s also take the channel", app, a.queueGroup())
+		})
+	}
+
+	for _, id := range ids {
+		a._originates.Store(id, p)
+
+		if a.claims != nil {
+			if _, err := a.claims.Put(context.Background(), claimKey(id), []byte(a.instanceID)); err != nil {
+				a.untrackOriginate(p)
+				return nil, eris.Wrap(err, "failed to claim originated channel")
+			}
+		}
+
+		lease, err := a.acquireTopic(a.resourceTopic(app, node, id), func(o *arievent.StasisEvent) {
+			if o.Channel.ID != id {
+				return
+			}
+
+			switch o.GetType() {
+			case arievent.StasisStart:
+				p.start(id)
+			case arievent.StasisEnd:
+				a.releaseOriginate(p, id)
+			}
+		})
+		if err != nil {
+			a.untrackOriginate(p)
+			return nil, eris.Wrap(err, "failed to subscribe to originated channel")
+		}
+
+		p.mu.Lock()
+		if p.released[id] {
+			p.mu.Unlock()
+			lease.Release()
+			continue
+		}
+		p.leases[id] = lease
+		p.mu.Unlock()
+	}
+
+	// A channel which never answers never enters the application; do not
+	// track it forever
+	time.AfterFunc(ClaimTTL, func() {
+		for _, id := range ids {
+			p.mu.Lock()
+			entered := p.entered[id]
+			p.mu.Unlock()
+
+			if !entered {
+				a.releaseOriginate(p, id)
+			}
+		}
+	})
+
+	return p, nil
+}
+
+// releaseOriginate forgets the originated channel, removes its claim and
+// drops its subscription
+func (a *ARIClient) releaseOriginate(p *pendingOriginate, id string) {
+	p.mu.Lock()
+	if p.released[id] {
+		p.mu.Unlock()
+		return
+	}
+	p.released[id] = true
+
+	lease := p.leases[id]
+	delete(p.leases, id)
+	p.mu.Unlock()
+
+	a._originates.Delete(id)
+
+	if a.claims != nil {
+		if err := a.claims.Delete(context.Background(), claimKey(id)); err != nil {
+			logs.TLogger.Debug().Msgf("failed to release claim on %s: %s", id, err)
+		}
+	}
+
+	lease.Release()
+}
+
+// untrackOriginate forgets every channel of the originate
+func (a *ARIClient) untrackOriginate(p *pendingOriginate) {
+	for _, id := range p.ids {
+		a.releaseOriginate(p, id)
+	}
+}
+
+// isClaimed reports whether the StasisStart of the channel belongs to the
+// worker which originated it, rather than to the queue group
+func (a *ARIClient) isClaimed(id string) bool {
+	if _, ok := a._originates.Load(id); ok {
+		return true
+	}
+
+	if a.claims == nil {
+		return false
+	}
+
+	_, err := a.claims.Get(context.Background(), claimKey(id))
+	if err == nil {
+		return true
+	}
+
+	if !errors.Is(err, jetstream.ErrKeyNotFound) {
+		logs.TLogger.Debug().Msgf("failed to look up claim on %s: %s", id, err)
+	}
+
+	return false
+}
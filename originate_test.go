@@ -0,0 +1,114 @@
+package ari
+
+import (
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+)
+
+func TestTrackOriginate(t *testing.T) {
+	stasis := func(typ arievent.EventType, id string) *arievent.StasisEvent {
+		return &arievent.StasisEvent{Type: typ, Node: "ast1", Channel: arievent.ChannelData{ID: id}}
+	}
+
+	tests := []struct {
+		name    string
+		events  []*arievent.StasisEvent
+		started bool
+		claimed map[string]bool
+	}{
+		{
+			name:    "pending",
+			claimed: map[string]bool{"c1": true, "c2": true},
+		},
+		{
+			name:    "entered",
+			events:  []*arievent.StasisEvent{stasis(arievent.StasisStart, "c1")},
+			started: true,
+			claimed: map[string]bool{"c1": true, "c2": true},
+		},
+		{
+			name:    "other channel entered",
+			events:  []*arievent.StasisEvent{stasis(arievent.StasisStart, "c2")},
+			started: true,
+			claimed: map[string]bool{"c1": true, "c2": true},
+		},
+		{
+			name:    "left",
+			events:  []*arievent.StasisEvent{stasis(arievent.StasisStart, "c1"), stasis(arievent.StasisEnd, "c1")},
+			started: true,
+			claimed: map[string]bool{"c1": false, "c2": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+
+			p, err := a.trackOriginate("app", "ast1", "c1", "c2")
+			if err != nil {
+				t.Fatalf("trackOriginate: %s", err)
+			}
+			defer a.untrackOriginate(p)
+
+			for _, e := range tt.events {
+				bus.publish(a.resourceTopic("app", "ast1", e.Channel.ID), e)
+			}
+
+			select {
+			case <-p.started:
+				if !tt.started {
+					t.Error("started")
+				}
+			default:
+				if tt.started {
+					t.Error("not started")
+				}
+			}
+
+			for id, want := range tt.claimed {
+				if got := a.isClaimed(id); got != want {
+					t.Errorf("%s claimed = %v, want %v", id, got, want)
+				}
+
+				users := 0
+				if want {
+					users = 1
+				}
+				if n := a.topicUsers(a.resourceTopic("app", "ast1", id)); n != users {
+					t.Errorf("%s has %d subscribers, want %d", id, n, users)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackOriginateExpires(t *testing.T) {
+	defer func(ttl time.Duration) { ClaimTTL = ttl }(ClaimTTL)
+	ClaimTTL = 20 * time.Millisecond
+
+	bus := newFakeBus()
+	a := newTestClient(bus)
+
+	p, err := a.trackOriginate("app", "ast1", "c1", "c2")
+	if err != nil {
+		t.Fatalf("trackOriginate: %s", err)
+	}
+	defer a.untrackOriginate(p)
+
+	bus.publish(a.resourceTopic("app", "ast1", "c1"), &arievent.StasisEvent{Type: arievent.StasisStart, Channel: arievent.ChannelData{ID: "c1"}})
+
+	time.Sleep(5 * ClaimTTL)
+
+	if !a.isClaimed("c1") {
+		t.Error("the channel in the application lost its claim")
+	}
+	if a.isClaimed("c2") {
+		t.Error("the channel which never entered the application is still claimed")
+	}
+	if n := a.topicUsers(a.resourceTopic("app", "ast1", "c2")); n != 0 {
+		t.Errorf("%d subscribers to the expired channel, want 0", n)
+	}
+}
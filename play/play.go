@@ -0,0 +1,439 @@
+package play
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/rid"
+	"github.com/rotisserie/eris"
+)
+
+var (
+	// PlaybackStartTimeout is the amount of time to wait for a playback to
+	// start before declaring the playback to have failed.
+	PlaybackStartTimeout = 1 * time.Second
+
+	// DefaultMaxPlaybackTime is the default maximum amount of time a single
+	// playback may last before the session gives up waiting for it.
+	DefaultMaxPlaybackTime = 10 * time.Minute
+)
+
+// Subscriber is implemented by the Players whose events can be subscribed to,
+// such as channel and bridge handles.  Play watches them for hangups and DTMF.
+type Subscriber interface {
+	Subscribe(l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription
+}
+
+// Status describes how a playback session ended
+type Status int
+
+const (
+	// InProgress indicates that the session has not ended yet
+	InProgress Status = iota
+
+	// Finished indicates that every media URI was played to the end
+	Finished
+
+	// Cancelled indicates that the session was stopped, by Stop or by its
+	// context, before the end
+	Cancelled
+
+	// Failed indicates that a playback could not be started or failed
+	Failed
+
+	// Hangup indicates that the Player hung up or was destroyed during the
+	// playback
+	Hangup
+
+	// Timeout indicates that a playback did not start, or did not finish, in
+	// time
+	Timeout
+
+	// DTMF indicates that the playback was stopped by a DTMF digit
+	DTMF
+)
+
+func (s Status) String() string {
+	switch s {
+	case InProgress:
+		return "in progress"
+	case Finished:
+		return "finished"
+	case Cancelled:
+		return "cancelled"
+	case Failed:
+		return "failed"
+	case Hangup:
+		return "hangup"
+	case Timeout:
+		return "timeout"
+	case DTMF:
+		return "dtmf"
+	}
+
+	return "unknown"
+}
+
+// Options describes a set of options for a playback Session
+type Options struct {
+	uris []string
+
+	stopOn string
+
+	startTimeout time.Duration
+
+	maxPlaybackTime time.Duration
+}
+
+func defaultOptions() *Options {
+	return &Options{
+		startTimeout:    PlaybackStartTimeout,
+		maxPlaybackTime: DefaultMaxPlaybackTime,
+	}
+}
+
+// Apply applies a set of options for the playback Session
+func (o *Options) Apply(opts ...OptionFunc) {
+	for _, f := range opts {
+		f(o)
+	}
+}
+
+// OptionFunc is a function which applies changes to an Options set
+type OptionFunc func(*Options)
+
+// URI adds media URIs to the playback.  The URIs are played one after the
+// other, in the order they were added.
+func URI(uris ...string) OptionFunc {
+	return func(o *Options) {
+		o.uris = append(o.uris, uris...)
+	}
+}
+
+// StopOnDTMF configures the DTMF digits which, if received, stop the playback.
+//
+// Valid values are "none" (default), "any", or the list of digits, such as
+// "#" or "*#0".
+func StopOnDTMF(digits string) OptionFunc {
+	return func(o *Options) {
+		o.stopOn = digits
+	}
+}
+
+// StartTimeout sets the amount of time to wait for each playback to start.
+// Defaults to PlaybackStartTimeout.
+func StartTimeout(timeout time.Duration) OptionFunc {
+	return func(o *Options) {
+		o.startTimeout = timeout
+	}
+}
+
+// MaxPlaybackTime sets the maximum amount of time to wait for each playback to
+// finish once started.  Defaults to DefaultMaxPlaybackTime; 0 disables the
+// limit.
+func MaxPlaybackTime(max time.Duration) OptionFunc {
+	return func(o *Options) {
+		o.maxPlaybackTime = max
+	}
+}
+
+// stopsOn reports whether the digit stops the playback
+func (o *Options) stopsOn(digit string) bool {
+	switch o.stopOn {
+	case "", "none":
+		return false
+	case "any":
+		return true
+	}
+
+	return digit != "" && strings.Contains(o.stopOn, digit)
+}
+
+// Session describes the interface to a playback session
+type Session interface {
+	// Done returns a channel which is closed when the session is complete
+	Done() <-chan struct{}
+
+	// Err waits for the session to complete, then returns any error
+	// encountered during its execution
+	Err() error
+
+	// Key returns the key.Key of the current Playback of this session, if one
+	// exists
+	Key() *key.Key
+
+	// Result waits for the session to complete, then returns the Result
+	Result() (*Result, error)
+
+	// Stop stops the playback session and returns its Result
+	Stop() *Result
+}
+
+// Result represents the result of a playback Session
+type Result struct {
+	// Status indicates how the session ended
+	Status Status
+
+	// Data holds the final data of the last Playback of the session
+	Data *PlaybackData
+
+	// Played is the number of media URIs which were played to the end
+	Played int
+
+	// DTMF holds any DTMF digits which are received during the session
+	DTMF string
+
+	// Duration indicates the duration of the session
+	Duration time.Duration
+
+	// Error holds any error encountered during the session
+	Error error
+}
+
+// Play starts a new playback Session, playing the media URIs configured with
+// the URI option to the Player
+func Play(ctx context.Context, p Player, opts ...OptionFunc) Session {
+	o := defaultOptions()
+	o.Apply(opts...)
+
+	s := &playSession{
+		options: o,
+		doneCh:  make(chan struct{}),
+		stopCh:  make(chan struct{}),
+		events:  make(chan *arievent.StasisEvent, 16),
+		res:     new(Result),
+	}
+
+	go s.play(ctx, p)
+
+	return s
+}
+
+type playSession struct {
+	h *PlaybackHandle
+
+	options *Options
+
+	doneCh chan struct{}
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+
+	// events receives the events of the Player and of its playbacks
+	events chan *arievent.StasisEvent
+
+	res *Result
+
+	mu sync.Mutex
+}
+
+func (s *playSession) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+func (s *playSession) Err() error {
+	<-s.Done()
+	return s.res.Error
+}
+
+func (s *playSession) Key() *key.Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.h == nil {
+		return nil
+	}
+
+	return s.h.Key()
+}
+
+func (s *playSession) Result() (*Result, error) {
+	<-s.Done()
+	return s.res, s.res.Error
+}
+
+func (s *playSession) Stop() *Result {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+
+	<-s.Done()
+
+	return s.res
+}
+
+// deliver queues an event for the session, unless the session is over
+func (s *playSession) deliver(e *arievent.StasisEvent) {
+	select {
+	case s.events <- e:
+	case <-s.doneCh:
+	}
+}
+
+func (s *playSession) play(ctx context.Context, p Player) {
+	defer close(s.doneCh)
+
+	started := time.Now()
+
+	defer func() {
+		s.res.Duration = time.Since(started)
+		logs.TLogger.Debug().Msgf("playback duration %d", s.res.Duration)
+	}()
+
+	if len(s.options.uris) == 0 {
+		s.res.Status = Failed
+		s.res.Error = eris.New("no media URI to play")
+
+		return
+	}
+
+	// Watch the Player for hangups and DTMF, when it can be watched
+	if sub, ok := p.(Subscriber); ok {
+		watch := sub.Subscribe(s.deliver,
+			arievent.ChannelDtmfReceived,
+			arievent.ChannelHangupRequest,
+			arievent.ChannelDestroyed,
+			arievent.StasisEnd,
+			arievent.BridgeDestroyed,
+		)
+		defer watch.Cancel()
+	}
+
+	for _, uri := range s.options.uris {
+		if !s.playURI(ctx, p, uri) {
+			return
+		}
+
+		s.res.Played++
+	}
+
+	s.res.Status = Finished
+}
+
+// playURI plays one media URI and waits for it to finish.  It returns false
+// when the session must end.
+// nolint: gocyclo
+func (s *playSession) playURI(ctx context.Context, p Player, uri string) bool {
+	h, err := p.StagePlay(rid.New(rid.Playback), uri)
+	if err != nil {
+		s.res.Status = Failed
+		s.res.Error = eris.Wrap(err, "failed to stage playback")
+
+		return false
+	}
+
+	s.mu.Lock()
+	s.h = h
+	s.mu.Unlock()
+
+	// Subscribe before starting the playback, so that no event is missed
+	sub := h.Subscribe(s.deliver,
+		arievent.PlaybackStarted,
+		arievent.PlaybackContinuing,
+		arievent.PlaybackFinished,
+	)
+	defer sub.Cancel()
+
+	logs.TLogger.Debug().Msgf("starting playback of %s", uri)
+
+	if err := h.Exec(); err != nil {
+		s.res.Status = Failed
+		s.res.Error = eris.Wrap(err, "failed to start playback")
+
+		return false
+	}
+
+	timer := time.NewTimer(s.options.startTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.stopPlayback(h, Cancelled)
+			s.res.Error = ctx.Err()
+
+			return false
+		case <-s.stopCh:
+			s.stopPlayback(h, Cancelled)
+
+			return false
+		case <-timer.C:
+			logs.TLogger.Debug().Msg("timeout waiting for playback")
+
+			s.stopPlayback(h, Timeout)
+			s.res.Error = timeoutErr{"Timeout waiting for playback of " + uri}
+
+			return false
+		case e := <-s.events:
+			// Ignore the late events of the previous playbacks
+			if e.Playback != nil && e.Playback.ID != h.ID() {
+				continue
+			}
+
+			switch e.GetType() {
+			case arievent.PlaybackStarted:
+				logs.TLogger.Debug().Msg("playback started")
+
+				timer.Stop()
+				if s.options.maxPlaybackTime > 0 {
+					timer.Reset(s.options.maxPlaybackTime)
+				}
+			case arievent.PlaybackContinuing:
+				s.res.Data = e.Playback
+			case arievent.PlaybackFinished:
+				logs.TLogger.Debug().Msg("playback finished")
+
+				s.res.Data = e.Playback
+				if e.Playback != nil && e.Playback.State == "failed" {
+					s.res.Status = Failed
+					s.res.Error = eris.Errorf("playback of %s failed", uri)
+
+					return false
+				}
+
+				return true
+			case arievent.ChannelDtmfReceived:
+				s.res.DTMF += e.Digit
+
+				if s.options.stopsOn(e.Digit) {
+					s.stopPlayback(h, DTMF)
+
+					return false
+				}
+			case arievent.ChannelHangupRequest, arievent.ChannelDestroyed, arievent.StasisEnd, arievent.BridgeDestroyed:
+				logs.TLogger.Debug().Msg("player hung up during playback")
+
+				s.res.Status = Hangup
+
+				return false
+			}
+		}
+	}
+}
+
+// stopPlayback stops the current playback and records how the session ended
+func (s *playSession) stopPlayback(h *PlaybackHandle, status Status) {
+	s.res.Status = status
+
+	if err := h.Stop(); err != nil {
+		logs.TLogger.Debug().Msgf("failed to stop playback: %s", err)
+	}
+}
+
+type timeoutErr struct {
+	msg string
+}
+
+func (err timeoutErr) Error() string {
+	return err.msg
+}
+
+func (err timeoutErr) Timeout() bool {
+	return true
+}
@@ -0,0 +1,244 @@
+package play
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+)
+
+// step is an event a fake player emits once a playback starts.  Channel
+// events carry the digit, if any; playback events the state, if any.
+type step struct {
+	typ   arievent.EventType
+	digit string
+	state string
+}
+
+// fakePlayer plays to channel c1 and emits the scripted events of each media
+// URI through a dispatcher
+type fakePlayer struct {
+	d      *dispatcher.EventDispatcher
+	script map[string][]step
+
+	mu      sync.Mutex
+	played  []string
+	stopped int
+}
+
+func newFakePlayer(script map[string][]step) *fakePlayer {
+	return &fakePlayer{
+		d:      dispatcher.NewDispatcher(dispatcher.WithMode(dispatcher.Ordered)),
+		script: script,
+	}
+}
+
+func subscribeKey(d *dispatcher.EventDispatcher, k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return d.Subscribe(func(e *arievent.StasisEvent) {
+		if e.Matches(k) {
+			l(e)
+		}
+	}, dispatcher.DefaultPriority, types...)
+}
+
+func (p *fakePlayer) Play(id, uri string) (*PlaybackHandle, error) {
+	return NewPlaybackHandle(key.NewKey(key.PlaybackKey, id), &fakePlayback{p}, nil), nil
+}
+
+func (p *fakePlayer) StagePlay(id, uri string) (*PlaybackHandle, error) {
+	return NewPlaybackHandle(key.NewKey(key.PlaybackKey, id), &fakePlayback{p}, func(h *PlaybackHandle) error {
+		p.mu.Lock()
+		p.played = append(p.played, uri)
+		p.mu.Unlock()
+
+		go p.emit(id, p.script[uri])
+
+		return nil
+	}), nil
+}
+
+func (p *fakePlayer) Subscribe(l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return subscribeKey(p.d, key.NewKey(key.ChannelKey, "c1"), l, types...)
+}
+
+func (p *fakePlayer) emit(id string, steps []step) {
+	for _, s := range steps {
+		e := &arievent.StasisEvent{Type: s.typ, Channel: arievent.ChannelData{ID: "c1"}, Digit: s.digit}
+		if s.typ == arievent.PlaybackStarted || s.typ == arievent.PlaybackFinished || s.typ == arievent.PlaybackContinuing {
+			e = &arievent.StasisEvent{Type: s.typ, Playback: &arievent.PlaybackData{ID: id, State: s.state}}
+		}
+
+		p.d.Dispatch(e)
+	}
+}
+
+type fakePlayback struct {
+	p *fakePlayer
+}
+
+func (pb *fakePlayback) Get(k *key.Key) *PlaybackHandle { return NewPlaybackHandle(k, pb, nil) }
+
+func (pb *fakePlayback) Data(k *key.Key) (*PlaybackData, error) {
+	return &PlaybackData{ID: k.GetID()}, nil
+}
+
+func (pb *fakePlayback) Control(k *key.Key, op string) error { return nil }
+
+func (pb *fakePlayback) Stop(k *key.Key) error {
+	pb.p.mu.Lock()
+	pb.p.stopped++
+	pb.p.mu.Unlock()
+
+	return nil
+}
+
+func (pb *fakePlayback) Subscribe(k *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return subscribeKey(pb.p.d, k, l, types...)
+}
+
+func TestPlay(t *testing.T) {
+	started := step{typ: arievent.PlaybackStarted}
+	finished := step{typ: arievent.PlaybackFinished, state: "done"}
+
+	tests := []struct {
+		name    string
+		script  map[string][]step
+		opts    []OptionFunc
+		status  Status
+		played  int
+		dtmf    string
+		stopped bool
+		wantErr bool
+	}{
+		{
+			name:   "finished",
+			script: map[string][]step{"sound:a": {started, finished}, "sound:b": {started, finished}},
+			opts:   []OptionFunc{URI("sound:a", "sound:b")},
+			status: Finished,
+			played: 2,
+		},
+		{
+			name:    "no media",
+			status:  Failed,
+			wantErr: true,
+		},
+		{
+			name:    "playback failed",
+			script:  map[string][]step{"sound:a": {started, {typ: arievent.PlaybackFinished, state: "failed"}}},
+			opts:    []OptionFunc{URI("sound:a", "sound:b")},
+			status:  Failed,
+			wantErr: true,
+		},
+		{
+			name:    "never started",
+			opts:    []OptionFunc{URI("sound:a"), StartTimeout(20 * time.Millisecond)},
+			status:  Timeout,
+			stopped: true,
+			wantErr: true,
+		},
+		{
+			name:    "never finished",
+			script:  map[string][]step{"sound:a": {started}},
+			opts:    []OptionFunc{URI("sound:a"), MaxPlaybackTime(20 * time.Millisecond)},
+			status:  Timeout,
+			stopped: true,
+			wantErr: true,
+		},
+		{
+			name:   "hangup",
+			script: map[string][]step{"sound:a": {started, {typ: arievent.ChannelHangupRequest}}},
+			opts:   []OptionFunc{URI("sound:a", "sound:b")},
+			status: Hangup,
+		},
+		{
+			name:    "stopped by dtmf",
+			script:  map[string][]step{"sound:a": {started, {typ: arievent.ChannelDtmfReceived, digit: "1"}, {typ: arievent.ChannelDtmfReceived, digit: "#"}}},
+			opts:    []OptionFunc{URI("sound:a", "sound:b"), StopOnDTMF("#")},
+			status:  DTMF,
+			dtmf:    "1#",
+			stopped: true,
+		},
+		{
+			name:    "stopped by any dtmf",
+			script:  map[string][]step{"sound:a": {started, {typ: arievent.ChannelDtmfReceived, digit: "7"}}},
+			opts:    []OptionFunc{URI("sound:a"), StopOnDTMF("any")},
+			status:  DTMF,
+			dtmf:    "7",
+			stopped: true,
+		},
+		{
+			name:   "dtmf not stopping",
+			script: map[string][]step{"sound:a": {started, {typ: arievent.ChannelDtmfReceived, digit: "5"}, finished}},
+			opts:   []OptionFunc{URI("sound:a")},
+			status: Finished,
+			played: 1,
+			dtmf:   "5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakePlayer(tt.script)
+
+			res, err := Play(context.Background(), p, tt.opts...).Result()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if res.Status != tt.status {
+				t.Errorf("status = %s, want %s", res.Status, tt.status)
+			}
+			if res.Played != tt.played {
+				t.Errorf("played %d, want %d", res.Played, tt.played)
+			}
+			if res.DTMF != tt.dtmf {
+				t.Errorf("DTMF = %q, want %q", res.DTMF, tt.dtmf)
+			}
+			if stopped := p.stopped > 0; stopped != tt.stopped {
+				t.Errorf("stopped = %v, want %v", stopped, tt.stopped)
+			}
+		})
+	}
+}
+
+func TestPlayStop(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel bool
+	}{
+		{"Stop", false},
+		{"context", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakePlayer(map[string][]step{"sound:a": {{typ: arievent.PlaybackStarted}}})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			s := Play(ctx, p, URI("sound:a"))
+
+			// Wait for the playback to start
+			for s.Key() == nil {
+				time.Sleep(time.Millisecond)
+			}
+
+			var res *Result
+			if tt.cancel {
+				cancel()
+				res, _ = s.Result()
+			} else {
+				res = s.Stop()
+			}
+
+			if res.Status != Cancelled || p.stopped != 1 {
+				t.Errorf("status %s, %d stops", res.Status, p.stopped)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package play
 
-import "github.com/callevo/ari/key"
+import (
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+)
 
 // Playback represents a communication path for interacting
 // with an Asterisk server for playback resources
@@ -22,6 +26,10 @@ type Playback interface {
 
 	// Stop stops the playback
 	Stop(key *key.Key) error
+
+	// Subscribe registers the listener for the events of the given types
+	// relating to the playback, or for all of its events when no type is given
+	Subscribe(key *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription
 }
 
 // A Player is an entity which can play an audio URI
@@ -34,16 +42,7 @@ type Player interface {
 }
 
 // PlaybackData represents the state of a playback
-type PlaybackData struct {
-	// Key is the cluster-unique identifier for this playback
-	Key *key.Key `json:"key"`
-
-	ID        string `json:"id"` // Unique ID for this playback session
-	Language  string `json:"language,omitempty"`
-	MediaURI  string `json:"media_uri"`  // URI for the media which is to be played
-	State     string `json:"state"`      // State of the playback operation
-	TargetURI string `json:"target_uri"` // URI of the channel or bridge on which the media should be played (follows format of 'type':'name')
-}
+type PlaybackData = arievent.PlaybackData
 
 // PlaybackHandle is the handle for performing playback operations
 type PlaybackHandle struct {
@@ -87,6 +86,13 @@ func (ph *PlaybackHandle) Stop() error {
 	return ph.p.Stop(ph.key)
 }
 
+// Subscribe registers the listener for the events of the given types relating
+// to the playback, or for all of its events when no type is given.  Only
+// typed subscriptions honour StopPropagation in ordered mode.
+func (ph *PlaybackHandle) Subscribe(l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return ph.p.Subscribe(ph.key, l, types...)
+}
+
 // Exec executes any staged operations
 func (ph *PlaybackHandle) Exec() (err error) {
 	if !ph.executed {
@@ -1,6 +1,8 @@
 package ari
 
 import (
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
 	"github.com/callevo/ari/key"
 	"github.com/callevo/ari/logs"
 	"github.com/callevo/ari/play"
@@ -25,6 +27,12 @@ func (p *playback) Get(key *key.Key) *play.PlaybackHandle {
 }
 
 func (p *playback) Data(key *key.Key) (*play.PlaybackData, error) {
+	if p.c.state != nil {
+		if d, ok := p.c.state.Playback(key, p.c.stateMaxAge); ok {
+			return d, nil
+		}
+	}
+
 	data, err := p.c.dataRequest(&requests.Request{
 		Kind: "PlaybackData",
 		Key:  key,
@@ -51,3 +59,7 @@ func (p *playback) Stop(key *key.Key) error {
 		Key:  key,
 	})
 }
+
+func (p *playback) Subscribe(ikey *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return p.c.subscribeKey(ikey, l, types...)
+}
@@ -2,8 +2,9 @@ package recordings
 
 import (
 	"sync"
-	"time"
 
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/dispatcher"
 	"github.com/callevo/ari/key"
 )
 
@@ -38,29 +39,14 @@ type LiveRecording interface {
 	// Stored returns the StoredRecording handle for this LiveRecording
 	Stored(key *key.Key) *StoredRecordingHandle
 
-	// Subscribe subscribes to events
-	//Subscribe(key *Key, n ...string) Subscription
+	// Subscribe registers the listener for the events of the given types
+	// relating to the live recording, or for all of its events when no type
+	// is given
+	Subscribe(key *key.Key, l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription
 }
 
 // LiveRecordingData is the data for a live recording
-type LiveRecordingData struct {
-	// Key is the cluster-unique identifier for this live recording
-	Key *key.Key `json:"key"`
-
-	Cause     string        `json:"cause,omitempty"`            // If failed, the cause of the failure
-	Duration  time.Duration `json:"duration,omitempty"`         // Length of recording in seconds
-	Format    string        `json:"format"`                     // Format of recording (wav, gsm, etc)
-	Name      string        `json:"name"`                       // (base) name for the recording
-	Silence   time.Duration `json:"silence_duration,omitempty"` // If silence was detected in the recording, the duration in seconds of that silence (requires that maxSilenceSeconds be non-zero)
-	State     string        `json:"state"`                      // Current state of the recording
-	Talking   time.Duration `json:"talking_duration,omitempty"` // Duration of talking, in seconds, that has been detected in the recording (requires that maxSilenceSeconds be non-zero)
-	TargetURI string        `json:"target_uri"`                 // URI for the channel or bridge which is being recorded (TODO: figure out format for this)
-}
-
-// ID returns the identifier of the live recording
-func (s *LiveRecordingData) ID() string {
-	return s.Name
-}
+type LiveRecordingData = arievent.LiveRecordingData
 
 // NewLiveRecordingHandle creates a new live recording handle
 func NewLiveRecordingHandle(ikey *key.Key, r LiveRecording, exec func(*LiveRecordingHandle) (err error)) *LiveRecordingHandle {
@@ -96,6 +82,12 @@ func (h *LiveRecordingHandle) Data() (*LiveRecordingData, error) {
 	return h.r.Data(h.key)
 }
 
+// Subscribe registers the listener for the events of the given types relating
+// to the live recording, or for all of its events when no type is given
+func (h *LiveRecordingHandle) Subscribe(l dispatcher.Listener, types ...arievent.EventType) *dispatcher.Subscription {
+	return h.r.Subscribe(h.key, l, types...)
+}
+
 // Stop stops and saves the recording
 func (h *LiveRecordingHandle) Stop() error {
 	return h.r.Stop(h.key)
@@ -15,6 +15,8 @@ type Request struct {
 	// Key is the key or key filter on which this request should be processed
 	Key *key.Key `json:"key"`
 
+	ApplicationSubscribe *ApplicationSubscribe `json:"application_subscribe,omitempty"`
+
 	AsteriskConfig         *AsteriskConfig         `json:"asterisk_config,omitempty"`
 	AsteriskLoggingChannel *AsteriskLoggingChannel `json:"asterisk_logging_channel,omitempty"`
 	AsteriskVariableSet    *AsteriskVariableSet    `json:"asterisk_variable_set,omitempty"`
@@ -31,6 +33,8 @@ type Request struct {
 	ChannelContinue      *ChannelContinue      `json:"channel_continue,omitempty"`
 	ChannelDial          *ChannelDial          `json:"channel_dial,omitempty"`
 	ChannelHangup        *ChannelHangup        `json:"channel_hangup,omitempty"`
+	ChannelMove          *ChannelMove          `json:"channel_move,omitempty"`
+	ChannelRedirect      *ChannelRedirect      `json:"channel_redirect,omitempty"`
 	ChannelMOH           *ChannelMOH           `json:"channel_moh,omitempty"`
 	ChannelMute          *ChannelMute          `json:"channel_mute,omitempty"`
 	ChannelOriginate     *ChannelOriginate     `json:"channel_originate,omitempty"`
@@ -40,7 +44,7 @@ type Request struct {
 	ChannelSnoop         *ChannelSnoop         `json:"channel_snoop,omitempty"`
 	ChannelExternalMedia *ChannelExternalMedia `json:"channel_external_media,omitempty"`
 	ChannelVariable      *ChannelVariable      `json:"channel_variable,omitempty"`
-	//ChannelUserevent     *ChannelUserevent     `json:"channel_user_event,omitempty"`
+	ChannelUserevent     *ChannelUserevent     `json:"channel_user_event,omitempty"`
 
 	DeviceStateUpdate *DeviceStateUpdate `json:"device_state_update,omitempty"`
 
@@ -190,6 +194,21 @@ type ChannelHangup struct {
 	Reason string `json:"reason"`
 }
 
+// ChannelMove is the request for moving a channel to another Stasis application
+type ChannelMove struct {
+	// App is the Stasis application to which the channel should be moved
+	App string `json:"app"`
+
+	// AppArgs is the set of (comma-separated) arguments for the application
+	AppArgs string `json:"appArgs,omitempty"`
+}
+
+// ChannelRedirect is the request for redirecting a channel to another endpoint
+type ChannelRedirect struct {
+	// Endpoint is the endpoint to which the channel should be redirected
+	Endpoint string `json:"endpoint"`
+}
+
 // ChannelMOH is the request playing hold on music on a channel
 type ChannelMOH struct {
 	// Music is the music to play
@@ -309,7 +328,12 @@ type AsteriskLoggingChannel struct {
 	Levels string `json:"config"`
 }
 
-// ChannelUserevent - "User-generated event with additional user-defined fields in the object."
-//type ChannelUserevent struct {
-//	UserEvent arioptions.ChannelUserevent
-//}
+// ChannelUserevent is the request for generating a user event on a channel
+type ChannelUserevent struct {
+	// EventName is the name of the user event
+	EventName string `json:"eventname"`
+
+	// Variables are the user-defined fields of the event.  They are encoded as
+	// JSON and reach the subscribers as the userevent object of the event.
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
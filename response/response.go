@@ -3,6 +3,7 @@ package response
 import (
 	"errors"
 
+	"github.com/callevo/ari/application"
 	"github.com/callevo/ari/asterisk"
 	"github.com/callevo/ari/bridge"
 	"github.com/callevo/ari/channel"
@@ -31,6 +32,7 @@ type Response struct {
 }
 
 type EntityData struct {
+	Application     *application.ApplicationData    `json:"application,omitempty"`
 	Channel         *channel.ChannelData            `json:"channel,omitempty"`
 	Asterisk        *asterisk.AsteriskInfo          `json:"asterisk,omitempty"`
 	Bridge          *bridge.BridgeData              `json:"bridge,omitempty"`
@@ -42,13 +44,24 @@ type EntityData struct {
 	Variable string `json:"variable,omitempty"`
 }
 
-// Err returns an error from the Response.  If the response's Error is empty, a nil error is returned.  Otherwise, the error will be filled with the value of response.Error.
+// Error is the error a proxy answered a request with, as opposed to a failure
+// to get an answer
+type Error struct {
+	Message string
+	Code    int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Err returns an error from the Response.  If the response's Error is empty, a nil error is returned.  Otherwise, the error is an *Error filled with the value of response.Error.
 func (e *Response) Err() error {
 	if e == nil {
 		return nil
 	}
 	if e.Error != "" {
-		return errors.New(e.Error)
+		return &Error{Message: e.Error, Code: e.Code}
 	}
 	return nil
 }
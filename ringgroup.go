@@ -0,0 +1,401 @@
+package ari
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/bridge"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/gather"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/logs"
+	"github.com/callevo/ari/rid"
+	"github.com/rotisserie/eris"
+)
+
+// RingStrategy is the order in which a ring group rings its targets
+type RingStrategy int
+
+const (
+	// RingParallel rings every target at the same time.  The first target to
+	// answer, and confirm if required, wins; the others are cancelled.
+	RingParallel RingStrategy = iota
+
+	// RingSequential rings the targets one after the other, each for its own
+	// timeout, until one answers
+	RingSequential
+)
+
+// Ringback is what the caller hears while the targets ring
+type Ringback int
+
+const (
+	// RingbackRing plays a ringing tone to the caller
+	RingbackRing Ringback = iota
+
+	// RingbackMOH plays music on hold to the caller
+	RingbackMOH
+
+	// RingbackNone leaves the caller as is
+	RingbackNone
+)
+
+// DefaultRingGroupTimeout is the default amount of time a ring group rings
+var DefaultRingGroupTimeout = 60 * time.Second
+
+// RingTarget is one endpoint of a ring group
+type RingTarget struct {
+	// Endpoint is the endpoint to dial, such as PJSIP/100
+	Endpoint string
+
+	// Timeout is the amount of time to ring the target.  Defaults to
+	// DefaultDialTimeout.
+	Timeout time.Duration
+}
+
+// RingConfirm asks the answering party to accept the call before it is
+// bridged, such as "press 1 to accept"
+type RingConfirm struct {
+	// Prompt are the media URIs played to the answering party
+	Prompt []string
+
+	// Digit is the digit accepting the call.  Defaults to "1".
+	Digit string
+
+	// Timeout is the amount of time to wait for the digit.  Defaults to
+	// gather.DefaultFirstDigitTimeout.
+	Timeout time.Duration
+}
+
+// RingGroupOptions configures RingGroup
+type RingGroupOptions struct {
+	Strategy RingStrategy
+
+	// Timeout bounds the whole ring group.  Defaults to
+	// DefaultRingGroupTimeout.
+	Timeout time.Duration
+
+	Ringback Ringback
+
+	// MOHClass is the music on hold class of RingbackMOH
+	MOHClass string
+
+	// Confirm, when set, asks the answering party to accept the call
+	Confirm *RingConfirm
+
+	// NoBridge leaves the winner and the caller apart.  By default, they are
+	// joined in a new mixing bridge.
+	NoBridge bool
+}
+
+// RingAttempt describes the ringing of one target
+type RingAttempt struct {
+	Target RingTarget
+
+	// Status is the outcome of the dial
+	Status arievent.DialStatus
+
+	Cause     arievent.Cause
+	CauseText string
+
+	Ringing  time.Duration
+	Duration time.Duration
+
+	// Rejected is set when the target answered but did not confirm
+	Rejected bool
+
+	// Err is the error which prevented the target from ringing
+	Err error
+}
+
+// RingGroupResult is the outcome of RingGroup
+type RingGroupResult struct {
+	// Winner is the index of the target which answered, -1 if none did
+	Winner int
+
+	// Handle is the channel of the winner
+	Handle *channel.ChannelHandle
+
+	// Bridge joins the caller and the winner
+	Bridge *bridge.BridgeHandle
+
+	// Attempts are the attempts of the targets, in the order of the targets
+	Attempts []RingAttempt
+
+	// CallerHangup is set when the caller hung up while the group rang
+	CallerHangup bool
+
+	Duration time.Duration
+}
+
+// Answered reports whether a target answered
+func (r *RingGroupResult) Answered() bool {
+	return r.Winner >= 0
+}
+
+// ringLeg is the outcome of the ringing of one target
+type ringLeg struct {
+	index int
+	res   *DialResult
+	err   error
+
+	// accepted is set when the leg answered and confirmed
+	accepted bool
+}
+
+// RingGroup rings the targets for the caller, in parallel or one after the
+// other, and bridges the first target to answer with the caller.  The
+// targets which lose are hung up as answered elsewhere.  A group which no
+// target answers is not an error; see RingGroupResult.Answered.
+// nolint: gocyclo
+func (a *ARIClient) RingGroup(ctx context.Context, caller *channel.ChannelHandle, targets []RingTarget, opts *RingGroupOptions) (*RingGroupResult, error) {
+	if opts == nil {
+		opts = &RingGroupOptions{}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRingGroupTimeout
+	}
+
+	res := &RingGroupResult{
+		Winner:   -1,
+		Attempts: make([]RingAttempt, len(targets)),
+	}
+
+	for i, t := range targets {
+		res.Attempts[i].Target = t
+	}
+
+	if len(targets) == 0 {
+		return res, eris.New("ring group without target")
+	}
+
+	started := time.Now()
+
+	defer func() {
+		res.Duration = time.Since(started)
+	}()
+
+	groupCtx, cancelGroup := context.WithCancelCause(ctx)
+	defer cancelGroup(nil)
+
+	groupCtx, cancelTimeout := context.WithTimeoutCause(groupCtx, timeout, &CancelDial{Reason: arievent.HangupNoAnswer})
+	defer cancelTimeout()
+
+	// Stop ringing when the caller hangs up
+	var hungUp atomic.Bool
+	watch := caller.Subscribe(func(e *arievent.StasisEvent) {
+		if hungUp.CompareAndSwap(false, true) {
+			cancelGroup(&CancelDial{Reason: arievent.HangupNormal})
+		}
+	}, arievent.ChannelHangupRequest, arievent.ChannelDestroyed, arievent.StasisEnd)
+	defer watch.Cancel()
+
+	a.startRingback(caller, opts)
+
+	var winner *ringLeg
+
+	if opts.Strategy == RingSequential {
+		winner = a.ringSequential(groupCtx, caller, targets, opts, res)
+	} else {
+		winner = a.ringParallel(groupCtx, caller, targets, opts, res)
+	}
+
+	a.stopRingback(caller, opts)
+
+	watch.Cancel()
+
+	res.CallerHangup = hungUp.Load()
+
+	if winner == nil {
+		return res, nil
+	}
+
+	res.Winner = winner.index
+	res.Handle = winner.res.Handle
+
+	if groupCtx.Err() != nil {
+		// The caller hung up, or the group timed out, just as the winner
+		// answered
+		a.hangupDialed(winner.res.Handle, arievent.HangupNormal)
+		res.Winner = -1
+		res.Handle = nil
+
+		return res, nil
+	}
+
+	logs.TLogger.Debug().Msgf("ring group answered by %s", targets[winner.index].Endpoint)
+
+	if opts.NoBridge {
+		return res, nil
+	}
+
+	var err error
+
+	res.Bridge, err = a.Bridge().Create(caller.Key().New(key.BridgeKey, rid.New(rid.Bridge)), "mixing", "")
+	if err != nil {
+		return res, eris.Wrap(err, "failed to create ring group bridge")
+	}
+
+	for _, id := range []string{caller.ID(), res.Handle.ID()} {
+		if err := res.Bridge.AddChannel(id); err != nil {
+			return res, eris.Wrapf(err, "failed to add channel %s to ring group bridge", id)
+		}
+	}
+
+	return res, nil
+}
+
+// ringParallel rings every target and returns the first accepted leg
+func (a *ARIClient) ringParallel(ctx context.Context, caller *channel.ChannelHandle, targets []RingTarget, opts *RingGroupOptions, res *RingGroupResult) *ringLeg {
+	legs := make(chan *ringLeg, len(targets))
+
+	legCtx, cancelLegs := context.WithCancelCause(ctx)
+	defer cancelLegs(nil)
+
+	for i := range targets {
+		go func(i int) {
+			legs <- a.ringTarget(legCtx, caller, i, targets[i], opts)
+		}(i)
+	}
+
+	var winner *ringLeg
+
+	for range targets {
+		leg := <-legs
+		a.recordLeg(res, leg)
+
+		if !leg.accepted {
+			continue
+		}
+
+		if winner != nil {
+			// Another target won first
+			a.hangupDialed(leg.res.Handle, arievent.HangupAnsweredElsewhere)
+			continue
+		}
+
+		winner = leg
+		cancelLegs(&CancelDial{Reason: arievent.HangupAnsweredElsewhere})
+	}
+
+	return winner
+}
+
+// ringSequential rings the targets in order and returns the first accepted
+// leg
+func (a *ARIClient) ringSequential(ctx context.Context, caller *channel.ChannelHandle, targets []RingTarget, opts *RingGroupOptions, res *RingGroupResult) *ringLeg {
+	for i := range targets {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		leg := a.ringTarget(ctx, caller, i, targets[i], opts)
+		a.recordLeg(res, leg)
+
+		if leg.accepted {
+			return leg
+		}
+	}
+
+	return nil
+}
+
+// ringTarget dials one target and, once answered, asks it to confirm
+func (a *ARIClient) ringTarget(ctx context.Context, caller *channel.ChannelHandle, i int, t RingTarget, opts *RingGroupOptions) *ringLeg {
+	leg := &ringLeg{index: i}
+
+	leg.res, leg.err = a.Dial(ctx, caller, t.Endpoint, &DialOptions{
+		Timeout: t.Timeout,
+	})
+	if leg.err != nil || leg.res.Status != arievent.DialAnswer {
+		return leg
+	}
+
+	if opts.Confirm == nil {
+		leg.accepted = true
+		return leg
+	}
+
+	digit := opts.Confirm.Digit
+	if digit == "" {
+		digit = "1"
+	}
+
+	gopts := []gather.OptionFunc{
+		gather.Prompt(opts.Confirm.Prompt...),
+		gather.MaxDigits(1),
+		gather.Validate(func(digits string) bool {
+			return digits == digit
+		}),
+	}
+
+	if opts.Confirm.Timeout > 0 {
+		gopts = append(gopts, gather.FirstDigitTimeout(opts.Confirm.Timeout))
+	}
+
+	g, err := gather.Gather(ctx, leg.res.Handle, gopts...)
+	if err == nil && g.Valid() {
+		leg.accepted = true
+		return leg
+	}
+
+	reason := arievent.HangupNormal
+	if ctx.Err() != nil {
+		reason = arievent.HangupAnsweredElsewhere
+	}
+
+	a.hangupDialed(leg.res.Handle, reason)
+
+	return leg
+}
+
+// recordLeg records the outcome of a leg in the result
+func (a *ARIClient) recordLeg(res *RingGroupResult, leg *ringLeg) {
+	attempt := &res.Attempts[leg.index]
+
+	attempt.Err = leg.err
+	if leg.res == nil {
+		return
+	}
+
+	attempt.Status = leg.res.Status
+	attempt.Cause = leg.res.Cause
+	attempt.CauseText = leg.res.CauseText
+	attempt.Ringing = leg.res.Ringing
+	attempt.Duration = leg.res.Duration
+	attempt.Rejected = leg.res.Status == arievent.DialAnswer && !leg.accepted
+}
+
+func (a *ARIClient) startRingback(caller *channel.ChannelHandle, opts *RingGroupOptions) {
+	var err error
+
+	switch opts.Ringback {
+	case RingbackRing:
+		err = caller.Ring()
+	case RingbackMOH:
+		err = caller.MOH(opts.MOHClass)
+	}
+
+	if err != nil {
+		logs.TLogger.Debug().Msgf("failed to start ringback on %s: %s", caller.ID(), err)
+	}
+}
+
+func (a *ARIClient) stopRingback(caller *channel.ChannelHandle, opts *RingGroupOptions) {
+	var err error
+
+	switch opts.Ringback {
+	case RingbackRing:
+		err = caller.StopRing()
+	case RingbackMOH:
+		err = caller.StopMOH()
+	}
+
+	if err != nil {
+		logs.TLogger.Debug().Msgf("failed to stop ringback on %s: %s", caller.ID(), err)
+	}
+}
@@ -0,0 +1,246 @@
+package ari
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+)
+
+// count returns the number of requests of the kind sent
+func (b *fakeBus) count(kind string) int {
+	n := 0
+	for _, k := range b.sent() {
+		if k == kind {
+			n++
+		}
+	}
+
+	return n
+}
+
+// endpoint returns the endpoint of the channel created with the ID
+func (b *fakeBus) endpoint(id string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, r := range b.requests {
+		if r.Kind == "ChannelCreate" && r.ChannelCreate.ChannelCreateRequest.ChannelID == id {
+			return r.ChannelCreate.ChannelCreateRequest.Endpoint
+		}
+	}
+
+	return ""
+}
+
+func TestRingGroup(t *testing.T) {
+	confirm := &RingConfirm{Timeout: 2 * time.Second}
+
+	// The targets behave as their endpoint says: answer, busy and ring
+	// report the dial status, hangup rings and hangs up the caller, and fail
+	// cannot be created
+	tests := []struct {
+		name    string
+		targets []string
+		opts    *RingGroupOptions
+		digit   string
+
+		winner       int
+		statuses     []arievent.DialStatus
+		failed       int
+		rejected     bool
+		callerHangup bool
+		hangups      []string
+		bridged      bool
+		wantErr      bool
+	}{
+		{
+			name:     "parallel",
+			targets:  []string{"ring", "answer", "busy"},
+			winner:   1,
+			statuses: []arievent.DialStatus{arievent.DialCancel, arievent.DialAnswer, arievent.DialBusy},
+			hangups:  []string{"answered_elsewhere"},
+			bridged:  true,
+		},
+		{
+			name:     "sequential",
+			targets:  []string{"busy", "answer", "ring"},
+			opts:     &RingGroupOptions{Strategy: RingSequential},
+			winner:   1,
+			statuses: []arievent.DialStatus{arievent.DialBusy, arievent.DialAnswer, ""},
+			bridged:  true,
+		},
+		{
+			name:     "no answer",
+			targets:  []string{"busy", "fail"},
+			winner:   -1,
+			statuses: []arievent.DialStatus{arievent.DialBusy, ""},
+			failed:   1,
+		},
+		{
+			name:     "group timeout",
+			targets:  []string{"ring"},
+			opts:     &RingGroupOptions{Timeout: 20 * time.Millisecond},
+			winner:   -1,
+			statuses: []arievent.DialStatus{arievent.DialCancel},
+			hangups:  []string{"no_answer"},
+		},
+		{
+			name:         "caller hangup",
+			targets:      []string{"hangup"},
+			winner:       -1,
+			statuses:     []arievent.DialStatus{arievent.DialCancel},
+			callerHangup: true,
+			hangups:      []string{"normal"},
+		},
+		{
+			name:     "confirmed",
+			targets:  []string{"answer"},
+			opts:     &RingGroupOptions{Confirm: confirm},
+			digit:    "1",
+			winner:   0,
+			statuses: []arievent.DialStatus{arievent.DialAnswer},
+			bridged:  true,
+		},
+		{
+			name:     "not confirmed",
+			targets:  []string{"answer"},
+			opts:     &RingGroupOptions{Confirm: confirm},
+			digit:    "2",
+			winner:   -1,
+			statuses: []arievent.DialStatus{arievent.DialAnswer},
+			rejected: true,
+			hangups:  []string{"normal"},
+		},
+		{
+			name:     "no bridge, music on hold",
+			targets:  []string{"answer"},
+			opts:     &RingGroupOptions{NoBridge: true, Ringback: RingbackMOH},
+			winner:   0,
+			statuses: []arievent.DialStatus{arievent.DialAnswer},
+		},
+		{
+			name:    "no target",
+			winner:  -1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+
+			stop := make(chan struct{})
+			defer close(stop)
+
+			bus.respond = func(_ string, r *requests.Request) (*response.Response, error) {
+				if r.Kind == "ChannelCreate" && r.ChannelCreate.ChannelCreateRequest.Endpoint == "fail" {
+					return nil, errBus
+				}
+
+				if r.Kind != "ChannelDial" {
+					return &response.Response{Key: r.Key}, nil
+				}
+
+				id := r.Key.GetID()
+				topic := a.resourceTopic("app", "ast1", id)
+
+				switch bus.endpoint(id) {
+				case "answer":
+					bus.publish(topic, dialEvent(id, arievent.DialAnswer))
+
+					if tt.digit != "" {
+						// Enter the digit until the confirmation collects it
+						go func() {
+							for {
+								select {
+								case <-stop:
+									return
+								case <-time.After(5 * time.Millisecond):
+									bus.publish(topic, &arievent.StasisEvent{Type: arievent.ChannelDtmfReceived, Node: "ast1", Channel: arievent.ChannelData{ID: id}, Digit: tt.digit})
+								}
+							}
+						}()
+					}
+				case "busy":
+					bus.publish(topic, dialEvent(id, arievent.DialBusy))
+				case "ring":
+					bus.publish(topic, dialEvent(id, arievent.DialRinging))
+				case "hangup":
+					bus.publish(topic, dialEvent(id, arievent.DialRinging))
+					a._dispatcher.Dispatch(&arievent.StasisEvent{Type: arievent.ChannelHangupRequest, Node: "ast1", Channel: arievent.ChannelData{ID: "caller"}})
+				}
+
+				return &response.Response{Key: r.Key}, nil
+			}
+
+			targets := make([]RingTarget, len(tt.targets))
+			for i, endpoint := range tt.targets {
+				targets[i] = RingTarget{Endpoint: endpoint}
+			}
+
+			caller := channel.NewChannelHandle(key.NewKey(key.ChannelKey, "caller", key.WithApp("app"), key.WithNode("ast1")), a.Channel(), nil)
+
+			res, err := a.RingGroup(context.Background(), caller, targets, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if res.Winner != tt.winner || res.Answered() != (tt.winner >= 0) {
+				t.Errorf("winner %d, want %d", res.Winner, tt.winner)
+			}
+			if (res.Handle != nil) != (tt.winner >= 0) {
+				t.Errorf("winner channel %v", res.Handle)
+			}
+			if res.CallerHangup != tt.callerHangup {
+				t.Errorf("caller hangup = %v, want %v", res.CallerHangup, tt.callerHangup)
+			}
+			if (res.Bridge != nil) != tt.bridged || (bus.count("BridgeAddChannel") == 2) != tt.bridged {
+				t.Errorf("bridge %v, want bridged %v", res.Bridge, tt.bridged)
+			}
+
+			var statuses []arievent.DialStatus
+			failed, rejected := 0, false
+			for _, attempt := range res.Attempts {
+				statuses = append(statuses, attempt.Status)
+				if attempt.Err != nil {
+					failed++
+				}
+				rejected = rejected || attempt.Rejected
+			}
+
+			if !reflect.DeepEqual(statuses, tt.statuses) {
+				t.Errorf("statuses %v, want %v", statuses, tt.statuses)
+			}
+			if failed != tt.failed || rejected != tt.rejected {
+				t.Errorf("%d failed, rejected %v, want %d failed, rejected %v", failed, rejected, tt.failed, tt.rejected)
+			}
+
+			hangups := bus.hangups()
+			sort.Strings(hangups)
+			if !reflect.DeepEqual(hangups, tt.hangups) {
+				t.Errorf("hangups %v, want %v", hangups, tt.hangups)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			on, off := "ChannelRing", "ChannelStopRing"
+			if tt.opts != nil && tt.opts.Ringback == RingbackMOH {
+				on, off = "ChannelMOH", "ChannelStopMOH"
+			}
+			if bus.count(on) != 1 || bus.count(off) != 1 {
+				t.Errorf("ringback not started and stopped: %v", bus.sent())
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/callevo/ari/arievent"
+)
+
+// Matcher reports whether a StasisStart satisfies a condition of a route
+type Matcher func(e *arievent.StasisEvent) bool
+
+// Arg matches when the argument at the given index equals the value
+func Arg(index int, value string) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		return index < len(e.Args) && e.Args[index] == value
+	}
+}
+
+// ArgPrefix matches when the argument at the given index starts with the prefix
+func ArgPrefix(index int, prefix string) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		return index < len(e.Args) && strings.HasPrefix(e.Args[index], prefix)
+	}
+}
+
+// Args matches when the arguments start with the given values
+func Args(values ...string) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		if len(e.Args) < len(values) {
+			return false
+		}
+
+		for i, v := range values {
+			if e.Args[i] != v {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Exten matches when the dialed extension matches the Asterisk extension
+// pattern.  It panics if the pattern is invalid.
+func Exten(pattern string) Matcher {
+	re := MustCompilePattern(pattern)
+
+	return func(e *arievent.StasisEvent) bool {
+		return re.MatchString(e.Channel.Dialplan.Exten)
+	}
+}
+
+// Context matches when the channel is in the given dialplan context
+func Context(context string) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		return e.Channel.Dialplan.Context == context
+	}
+}
+
+// CallerNumber matches when the caller number matches the Asterisk extension
+// pattern.  It panics if the pattern is invalid.
+func CallerNumber(pattern string) Matcher {
+	re := MustCompilePattern(pattern)
+
+	return func(e *arievent.StasisEvent) bool {
+		return re.MatchString(e.Channel.Caller.Number)
+	}
+}
+
+// CallerNumberRegexp matches when the caller number matches the regular
+// expression
+func CallerNumberRegexp(re *regexp.Regexp) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		return re.MatchString(e.Channel.Caller.Number)
+	}
+}
+
+// Var matches when the channel variable is set to the value.  Only the
+// variables Asterisk is configured to send with the events are available.
+func Var(name, value string) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		v, ok := e.Channel.ChannelVars[name]
+		return ok && v == value
+	}
+}
+
+// VarRegexp matches when the channel variable is set and matches the regular
+// expression
+func VarRegexp(name string, re *regexp.Regexp) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		v, ok := e.Channel.ChannelVars[name]
+		return ok && re.MatchString(v)
+	}
+}
+
+// All matches when every matcher matches
+func All(matchers ...Matcher) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		for _, m := range matchers {
+			if !m(e) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Any matches when at least one of the matchers matches
+func Any(matchers ...Matcher) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		for _, m := range matchers {
+			if m(e) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not matches when the matcher does not
+func Not(m Matcher) Matcher {
+	return func(e *arievent.StasisEvent) bool {
+		return !m(e)
+	}
+}
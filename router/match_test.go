@@ -0,0 +1,55 @@
+package router
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/callevo/ari/arievent"
+)
+
+func TestMatchers(t *testing.T) {
+	e := &arievent.StasisEvent{
+		Args: []string{"inbound", "sales"},
+		Channel: arievent.ChannelData{
+			Caller:      arievent.CallerInfo{Number: "5551234"},
+			Dialplan:    arievent.DialplanInfo{Context: "from-trunk", Exten: "2001"},
+			ChannelVars: map[string]string{"LANG": "fr"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		want    bool
+	}{
+		{"arg", Arg(1, "sales"), true},
+		{"arg mismatch", Arg(0, "sales"), false},
+		{"arg out of range", Arg(2, "sales"), false},
+		{"arg prefix", ArgPrefix(0, "in"), true},
+		{"args", Args("inbound", "sales"), true},
+		{"args prefix", Args("inbound"), true},
+		{"args too many", Args("inbound", "sales", "x"), false},
+		{"exten", Exten("_2XXX"), true},
+		{"exten mismatch", Exten("_3XXX"), false},
+		{"context", Context("from-trunk"), true},
+		{"caller number", CallerNumber("_555XXXX"), true},
+		{"caller number regexp", CallerNumberRegexp(regexp.MustCompile(`^555`)), true},
+		{"var", Var("LANG", "fr"), true},
+		{"var unset", Var("OTHER", ""), false},
+		{"var regexp", VarRegexp("LANG", regexp.MustCompile(`^(fr|de)$`)), true},
+		{"all", All(Arg(0, "inbound"), Context("from-trunk")), true},
+		{"all mismatch", All(Arg(0, "inbound"), Context("internal")), false},
+		{"all empty", All(), true},
+		{"any", Any(Context("internal"), Exten("2001")), true},
+		{"any empty", Any(), false},
+		{"not", Not(Context("internal")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher(e); got != tt.want {
+				t.Errorf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
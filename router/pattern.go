@@ -0,0 +1,77 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// CompilePattern compiles an Asterisk extension pattern into a regular
+// expression.  A pattern which does not start with "_" matches literally.
+// Otherwise:
+//
+//   - X matches any digit from 0 to 9
+//
+//   - Z matches any digit from 1 to 9
+//
+//   - N matches any digit from 2 to 9
+//
+//   - [15-7] matches any of the listed digits or ranges
+//
+//   - . matches one or more characters
+//
+//   - ! matches zero or more characters
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(pattern, "_") {
+		return regexp.Compile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	p := pattern[1:]
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case 'X', 'x':
+			b.WriteString("[0-9]")
+		case 'Z', 'z':
+			b.WriteString("[1-9]")
+		case 'N', 'n':
+			b.WriteString("[2-9]")
+		case '.':
+			b.WriteString(".+")
+		case '!':
+			b.WriteString(".*")
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, eris.Errorf("unterminated character set in pattern %q", pattern)
+			}
+
+			set := p[i+1 : i+end]
+			if set == "" {
+				return nil, eris.Errorf("empty character set in pattern %q", pattern)
+			}
+
+			b.WriteString("[" + strings.ReplaceAll(regexp.QuoteMeta(set), `\-`, "-") + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// MustCompilePattern is like CompilePattern but panics if the pattern is invalid
+func MustCompilePattern(pattern string) *regexp.Regexp {
+	re, err := CompilePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	return re
+}
@@ -0,0 +1,52 @@
+package router
+
+import "testing"
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"100", "100", true},
+		{"100", "1000", false},
+		{"1.0", "1x0", false},
+		{"_1XX", "123", true},
+		{"_1XX", "12", false},
+		{"_1XX", "1234", false},
+		{"_NXX", "199", false},
+		{"_NXX", "299", true},
+		{"_ZX", "09", false},
+		{"_ZX", "19", true},
+		{"_9.", "9", false},
+		{"_9.", "91234", true},
+		{"_9!", "9", true},
+		{"_[15-7]X", "12", true},
+		{"_[15-7]X", "62", true},
+		{"_[15-7]X", "42", false},
+		{"_+1NXX", "+1234", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.input, func(t *testing.T) {
+			re, err := CompilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("CompilePattern: %s", err)
+			}
+
+			if got := re.MatchString(tt.input); got != tt.want {
+				t.Errorf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	tests := []string{"_1[23", "_1[]"}
+
+	for _, pattern := range tests {
+		if _, err := CompilePattern(pattern); err == nil {
+			t.Errorf("%q compiled", pattern)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package router dispatches the channels entering an application to named
+// handlers, according to their StasisStart
+package router
+
+import (
+	"context"
+	"sync"
+
+	"github.com/callevo/ari"
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/logs"
+)
+
+// Middleware wraps a handler, to run code around it
+type Middleware func(next ari.CallHandler) ari.CallHandler
+
+// Route describes which calls are sent to a named handler
+type Route struct {
+	// Name is the name of the handler which receives the matching calls
+	Name string
+
+	matchers   []Matcher
+	middleware []Middleware
+}
+
+// Use adds middleware which only wraps the handler of this route.  It runs
+// inside the middleware of the Router.
+func (rt *Route) Use(mw ...Middleware) *Route {
+	rt.middleware = append(rt.middleware, mw...)
+	return rt
+}
+
+// Matches reports whether the StasisStart satisfies every matcher of the route
+func (rt *Route) Matches(e *arievent.StasisEvent) bool {
+	for _, m := range rt.matchers {
+		if !m(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Router sends each call to the handler of the first route it matches, or to
+// the default handler
+type Router struct {
+	routes     []*Route
+	handlers   map[string]ari.CallHandler
+	middleware []Middleware
+	fallback   ari.CallHandler
+
+	mu sync.RWMutex
+}
+
+// New returns an empty Router
+func New() *Router {
+	return &Router{
+		handlers: make(map[string]ari.CallHandler),
+	}
+}
+
+// Use adds middleware wrapping every handler of the Router, default included.
+// Middleware runs in the order it was added.
+func (r *Router) Use(mw ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Register registers a named handler, which routes refer to by name
+func (r *Router) Register(name string, h ari.CallHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[name] = h
+}
+
+// Route adds a route sending the calls which satisfy every matcher to the named
+// handler.  Routes are tried in the order they were added.  The handler may be
+// registered later; a call routed to a missing handler goes to the default
+// handler.
+func (r *Router) Route(name string, matchers ...Matcher) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rt := &Route{
+		Name:     name,
+		matchers: matchers,
+	}
+	r.routes = append(r.routes, rt)
+
+	return rt
+}
+
+// Handle registers the named handler and adds a route to it
+func (r *Router) Handle(name string, h ari.CallHandler, matchers ...Matcher) *Route {
+	r.Register(name, h)
+	return r.Route(name, matchers...)
+}
+
+// Default sets the handler for the calls which match no route
+func (r *Router) Default(h ari.CallHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fallback = h
+}
+
+// Match returns the route the StasisStart matches, or nil if it matches none
+func (r *Router) Match(e *arievent.StasisEvent) *Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rt := range r.routes {
+		if rt.Matches(e) {
+			return rt
+		}
+	}
+
+	return nil
+}
+
+// Serve routes the call.  It is an ari.CallHandler, to be given to
+// ARIClient.ListenContext.
+func (r *Router) Serve(ctx context.Context, a *ari.ARIClient, h *channel.ChannelHandle, e *arievent.StasisEvent) {
+	rt := r.Match(e)
+
+	r.mu.RLock()
+	handler := r.fallback
+	var routeMiddleware []Middleware
+	if rt != nil {
+		if rh, ok := r.handlers[rt.Name]; ok {
+			handler = rh
+			routeMiddleware = rt.middleware
+		} else {
+			logs.TLogger.Warn().Msgf("no handler registered for route %s", rt.Name)
+		}
+	}
+	middleware := r.middleware
+	r.mu.RUnlock()
+
+	if handler == nil {
+		logs.TLogger.Warn().Msgf("no route for channel %s", e.Channel.ID)
+		return
+	}
+
+	for i := len(routeMiddleware) - 1; i >= 0; i-- {
+		handler = routeMiddleware[i](handler)
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	handler(ctx, a, h, e)
+}
+
+// StasisHandler returns the Router as an ari.StasisHandler, to be given to
+// ARIClient.Listen.  The routed handlers then receive a background context.
+func (r *Router) StasisHandler() ari.StasisHandler {
+	return func(a *ari.ARIClient, h *channel.ChannelHandle, e *arievent.StasisEvent) {
+		r.Serve(context.Background(), a, h, e)
+	}
+}
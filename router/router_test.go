@@ -0,0 +1,84 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/callevo/ari"
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/channel"
+)
+
+func TestServe(t *testing.T) {
+	var got string
+	handler := func(name string) ari.CallHandler {
+		return func(context.Context, *ari.ARIClient, *channel.ChannelHandle, *arievent.StasisEvent) {
+			got = name
+		}
+	}
+
+	r := New()
+	r.Handle("sales", handler("sales"), Arg(0, "sales"))
+	r.Route("support", Arg(0, "support"))
+	r.Handle("first", handler("first"), Arg(0, "dup"))
+	r.Handle("second", handler("second"), Arg(0, "dup"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		fallback bool
+		want     string
+	}{
+		{"route", []string{"sales"}, false, "sales"},
+		{"first route wins", []string{"dup"}, false, "first"},
+		{"no route", []string{"other"}, true, "default"},
+		{"no route, no default", []string{"other"}, false, ""},
+		{"unregistered handler", []string{"support"}, true, "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.Default(nil)
+			if tt.fallback {
+				r.Default(handler("default"))
+			}
+
+			got = ""
+			r.Serve(context.Background(), nil, nil, &arievent.StasisEvent{Args: tt.args})
+
+			if got != tt.want {
+				t.Errorf("handled by %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var trace []string
+	mw := func(name string) Middleware {
+		return func(next ari.CallHandler) ari.CallHandler {
+			return func(ctx context.Context, a *ari.ARIClient, h *channel.ChannelHandle, e *arievent.StasisEvent) {
+				trace = append(trace, name)
+				next(ctx, a, h, e)
+			}
+		}
+	}
+
+	r := New()
+	r.Use(mw("router1"), mw("router2"))
+	r.Handle("h", func(context.Context, *ari.ARIClient, *channel.ChannelHandle, *arievent.StasisEvent) {
+		trace = append(trace, "handler")
+	}).Use(mw("route"))
+
+	r.Serve(context.Background(), nil, nil, &arievent.StasisEvent{})
+
+	want := []string{"router1", "router2", "route", "handler"}
+	if len(trace) != len(want) {
+		t.Fatalf("trace = %v, want %v", trace, want)
+	}
+	for i := range want {
+		if trace[i] != want[i] {
+			t.Fatalf("trace = %v, want %v", trace, want)
+		}
+	}
+}
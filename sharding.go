@@ -0,0 +1,90 @@
+package ari
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/messagebus"
+)
+
+// Canary splits the incoming calls between the queue group of the client and
+// a canary queue group.  Both groups receive every StasisStart; a call goes to
+// the canary group when the hash of its channel ID falls within Weight, and to
+// the main group otherwise.  Every worker of both pools must be configured with
+// the same Group and Weight.
+type Canary struct {
+	// Group is the queue group of the canary pool
+	Group string
+
+	// Weight is the fraction of the calls, between 0 and 1, which go to the
+	// canary pool
+	Weight float64
+
+	// Member is set on the workers of the canary pool
+	Member bool
+}
+
+// inCanary reports whether the call belongs to the canary pool
+func (c *Canary) inCanary(channelID string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(channelID))
+
+	return float64(h.Sum32())/float64(math.MaxUint32+1) < c.Weight
+}
+
+// StasisStartFilter selects the calls a client takes.  The calls it does not
+// take are left to the other queue groups; a call no group takes stays in the
+// application until it hangs up.  The filter only decides for its own client:
+// nothing keeps two queue groups from taking the same call, and a call is not
+// offered to another member of the group when the member it reached rejects
+// it.  See Options.Filter.
+type StasisStartFilter func(e *arievent.StasisEvent) bool
+
+// ArgPrefixFilter takes the calls whose first argument starts with one of the
+// prefixes
+func ArgPrefixFilter(prefixes ...string) StasisStartFilter {
+	return func(e *arievent.StasisEvent) bool {
+		if len(e.Args) == 0 {
+			return false
+		}
+
+		for _, p := range prefixes {
+			if strings.HasPrefix(e.Args[0], p) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// queueGroup returns the queue group the client joins
+func (a *ARIClient) queueGroup() string {
+	if a.canary != nil && a.canary.Member {
+		return a.canary.Group
+	}
+
+	if a.queue != "" {
+		return a.queue
+	}
+
+	return messagebus.ListenQueue
+}
+
+// takes reports whether the call belongs to the share of the client
+func (a *ARIClient) takes(o *arievent.StasisEvent) bool {
+	if a.canary != nil && a.canary.inCanary(o.Channel.ID) != a.canary.Member {
+		return false
+	}
+
+	return a.filter == nil || a.filter(o)
+}
+
+func (a *ARIClient) setupSharding(opts *Options) {
+	a.queue = opts.QueueGroup
+	a.nodes = opts.Nodes
+	a.filter = opts.Filter
+	a.canary = opts.Canary
+}
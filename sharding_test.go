@@ -0,0 +1,194 @@
+package ari
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/messagebus"
+	nats "github.com/nats-io/nats.go"
+)
+
+func TestQueueGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options
+		want string
+	}{
+		{"default", &Options{}, messagebus.ListenQueue},
+		{"configured", &Options{QueueGroup: "shard1"}, "shard1"},
+		{"canary member", &Options{QueueGroup: "shard1", Canary: &Canary{Group: "canary", Member: true}}, "canary"},
+		{"canary configured", &Options{QueueGroup: "shard1", Canary: &Canary{Group: "canary"}}, "shard1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestClient(newFakeBus())
+			a.setupSharding(tt.opts)
+
+			if got := a.queueGroup(); got != tt.want {
+				t.Errorf("queueGroup = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTakes(t *testing.T) {
+	e := func(arg string) *arievent.StasisEvent {
+		return &arievent.StasisEvent{Args: []string{arg}, Channel: arievent.ChannelData{ID: "c1"}}
+	}
+
+	tests := []struct {
+		name  string
+		opts  *Options
+		event *arievent.StasisEvent
+		want  bool
+	}{
+		{"no filter", &Options{}, e("x"), true},
+		{"filter", &Options{Filter: ArgPrefixFilter("sales", "support")}, e("support-fr"), true},
+		{"filtered out", &Options{Filter: ArgPrefixFilter("sales")}, e("support"), false},
+		{"no arguments", &Options{Filter: ArgPrefixFilter("")}, &arievent.StasisEvent{}, false},
+		{"canary weight 0, main", &Options{Canary: &Canary{Weight: 0}}, e("x"), true},
+		{"canary weight 0, member", &Options{Canary: &Canary{Weight: 0, Member: true}}, e("x"), false},
+		{"canary weight 1, main", &Options{Canary: &Canary{Weight: 1}}, e("x"), false},
+		{"canary weight 1, member", &Options{Canary: &Canary{Weight: 1, Member: true}}, e("x"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestClient(newFakeBus())
+			a.setupSharding(tt.opts)
+
+			if got := a.takes(tt.event); got != tt.want {
+				t.Errorf("takes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanaryWeight(t *testing.T) {
+	c := &Canary{Weight: 0.2}
+
+	n := 10000
+	in := 0
+	for i := 0; i < n; i++ {
+		if c.inCanary(fmt.Sprintf("channel-%d", i)) {
+			in++
+		}
+	}
+
+	if share := float64(in) / float64(n); math.Abs(share-c.Weight) > 0.03 {
+		t.Errorf("canary share %.3f, want about %.2f", share, c.Weight)
+	}
+}
+
+func TestIntakeNodes(t *testing.T) {
+	bus := newFakeBus()
+	a := newTestClient(bus)
+	a.admission = newAdmission(nil)
+	a.setupSharding(&Options{QueueGroup: "shard1", Nodes: []string{"ast1", "ast2"}})
+
+	a.registerApplication("app", nil, true)
+	if err := a.startIntake(context.Background()); err != nil {
+		t.Fatalf("startIntake: %s", err)
+	}
+
+	for _, topic := range []string{a.subjects.StasisStart("app", "ast1"), a.subjects.StasisStart("app", "ast2"), a.subjects.Requeue("app", "shard1")} {
+		subs := bus.queueSubs[topic]
+		if len(subs) != 1 || subs[0].queue != "shard1" {
+			t.Errorf("%s: %d subscriptions", topic, len(subs))
+		}
+	}
+}
+
+// deliverGroups hands the message carrying the event to the first queue
+// subscription of each queue group of the topic, as NATS does
+func (b *fakeBus) deliverGroups(topic string, e *arievent.StasisEvent, msg *nats.Msg) {
+	b.mu.Lock()
+	seen := make(map[string]bool)
+	var subs []*fakeQueueSub
+	for _, s := range b.queueSubs[topic] {
+		if !seen[s.queue] {
+			seen[s.queue] = true
+			subs = append(subs, s)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.callback(e, msg)
+	}
+}
+
+func TestFiltersAcrossQueueGroups(t *testing.T) {
+	// The pools join the application in order; the first member of a queue
+	// group gets its calls
+	type pool struct {
+		group  string
+		filter StasisStartFilter
+	}
+
+	tests := []struct {
+		name  string
+		pools []pool
+		arg   string
+		want  []int
+	}{
+		{
+			name:  "filtered pool beside an unfiltered pool",
+			pools: []pool{{"default", nil}, {"sales", ArgPrefixFilter("sales")}},
+			arg:   "sales-1",
+			want:  []int{1, 1},
+		},
+		{
+			name:  "call outside the filter",
+			pools: []pool{{"default", nil}, {"sales", ArgPrefixFilter("sales")}},
+			arg:   "support",
+			want:  []int{1, 0},
+		},
+		{
+			name:  "disjoint filters",
+			pools: []pool{{"sales", ArgPrefixFilter("sales")}, {"support", ArgPrefixFilter("support")}},
+			arg:   "support",
+			want:  []int{0, 1},
+		},
+		{
+			name:  "filters differing within a group",
+			pools: []pool{{"shared", ArgPrefixFilter("sales")}, {"shared", ArgPrefixFilter("support")}},
+			arg:   "support",
+			want:  []int{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+
+			var clients []*ARIClient
+			for _, p := range tt.pools {
+				a := newTestClient(bus)
+				a.admission = newAdmission(nil)
+				a.setupSharding(&Options{QueueGroup: p.group, Filter: p.filter})
+
+				a.registerApplication("app", nil, true)
+				if err := a.startIntake(context.Background()); err != nil {
+					t.Fatalf("startIntake: %s", err)
+				}
+
+				clients = append(clients, a)
+			}
+
+			e := &arievent.StasisEvent{Type: arievent.StasisStart, Application: "app", Node: "ast1", Args: []string{tt.arg}, Channel: arievent.ChannelData{ID: "c1"}}
+			topic := clients[0].subjects.StasisStart("app", "")
+			bus.deliverGroups(topic, e, nats.NewMsg(topic))
+
+			for i, a := range clients {
+				if got := a.ActiveCalls(); got != tt.want[i] {
+					t.Errorf("pool %d took %d calls, want %d", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package ari
+
+import (
+	"testing"
+
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/requests"
+	"github.com/callevo/ari/response"
+)
+
+func TestStagedOperations(t *testing.T) {
+	chKey := key.NewKey(key.ChannelKey, "c1", key.WithApp("app"), key.WithNode("ast1"))
+	brKey := key.NewKey(key.BridgeKey, "b1", key.WithApp("app"), key.WithNode("ast1"))
+
+	// stage stages the operation and returns the ID of the staged resource
+	// and its Exec
+	tests := []struct {
+		name  string
+		stage func(a *ARIClient) (string, func() error, error)
+		kinds []string
+	}{
+		{
+			name: "channel play",
+			stage: func(a *ARIClient) (string, func() error, error) {
+				h, err := a.Channel().StagePlay(chKey, "", "sound:hello")
+				if err != nil {
+					return "", nil, err
+				}
+				return h.ID(), h.Exec, nil
+			},
+			kinds: []string{"ChannelStagePlay", "ChannelPlay"},
+		},
+		{
+			name: "channel record",
+			stage: func(a *ARIClient) (string, func() error, error) {
+				h, err := a.Channel().StageRecord(chKey, "rec1", nil)
+				if err != nil {
+					return "", nil, err
+				}
+				return h.ID(), h.Exec, nil
+			},
+			kinds: []string{"ChannelStageRecord", "ChannelRecord"},
+		},
+		{
+			name: "bridge play",
+			stage: func(a *ARIClient) (string, func() error, error) {
+				h, err := a.Bridge().StagePlay(brKey, "pb1", "sound:hello")
+				if err != nil {
+					return "", nil, err
+				}
+				return h.ID(), h.Exec, nil
+			},
+			kinds: []string{"BridgeStagePlay", "BridgePlay"},
+		},
+		{
+			name: "bridge record",
+			stage: func(a *ARIClient) (string, func() error, error) {
+				h, err := a.Bridge().StageRecord(brKey, "rec1", nil)
+				if err != nil {
+					return "", nil, err
+				}
+				return h.ID(), h.Exec, nil
+			},
+			kinds: []string{"BridgeStageRecord", "BridgeRecord"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			bus.respond = func(_ string, r *requests.Request) (*response.Response, error) {
+				return &response.Response{Key: r.Key}, nil
+			}
+
+			a := newTestClient(bus)
+
+			id, exec, err := tt.stage(a)
+			if err != nil {
+				t.Fatalf("stage: %s", err)
+			}
+			if id == "" {
+				t.Error("staged resource without an ID")
+			}
+
+			if sent := bus.sent(); len(sent) != 1 || sent[0] != tt.kinds[0] {
+				t.Fatalf("sent %v after staging, want %v", sent, tt.kinds[:1])
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := exec(); err != nil {
+					t.Fatalf("Exec: %s", err)
+				}
+			}
+
+			sent := bus.sent()
+			if len(sent) != 2 || sent[1] != tt.kinds[1] {
+				t.Fatalf("sent %v, want %v", sent, tt.kinds)
+			}
+
+			staged, executed := bus.requests[0], bus.requests[1]
+			if executed.Key.GetID() != staged.Key.GetID() || executed.Key.GetNode() != "ast1" {
+				t.Errorf("executed on %v, staged on %v", executed.Key, staged.Key)
+			}
+
+			if staged.ChannelPlay != nil && executed.ChannelPlay.PlaybackID != staged.ChannelPlay.PlaybackID {
+				t.Errorf("played %s, staged %s", executed.ChannelPlay.PlaybackID, staged.ChannelPlay.PlaybackID)
+			}
+			if staged.BridgePlay != nil && executed.BridgePlay.PlaybackID != id {
+				t.Errorf("played %s, staged %s", executed.BridgePlay.PlaybackID, id)
+			}
+		})
+	}
+}
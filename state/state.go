@@ -0,0 +1,439 @@
+// Package state maintains a local cache of the entity state carried by events
+package state
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/bridge"
+	"github.com/callevo/ari/channel"
+	"github.com/callevo/ari/dispatcher"
+	"github.com/callevo/ari/key"
+	"github.com/callevo/ari/play"
+	"github.com/callevo/ari/recordings"
+)
+
+// EndedTTL is how long the Store remembers the entities which ended, so that
+// the events arriving after their end do not bring them back
+var EndedTTL = time.Minute
+
+// Change describes a modification of the Store
+type Change struct {
+	// Kind is the key kind of the entity which changed
+	Kind string
+
+	// Node is the Asterisk node of the entity which changed
+	Node string
+
+	// ID is the identifier of the entity which changed
+	ID string
+
+	// Removed indicates that the entity was evicted from the Store
+	Removed bool
+
+	// Event is the event which caused the change
+	Event *arievent.StasisEvent
+}
+
+// ChangeHandler is called for every change of the Store
+type ChangeHandler func(c *Change)
+
+// ref identifies an entity in the Store.  As with key.Key, the same ID on two
+// nodes names two entities.
+type ref struct {
+	kind string
+	node string
+	id   string
+}
+
+type entry struct {
+	updated time.Time
+
+	channel   *channel.ChannelData
+	bridge    *bridge.BridgeData
+	playback  *play.PlaybackData
+	recording *recordings.LiveRecordingData
+}
+
+// fresh reports whether the entry was updated no longer than maxAge ago.  A
+// maxAge of zero accepts any age.
+func (e *entry) fresh(maxAge time.Duration) bool {
+	return maxAge == 0 || time.Since(e.updated) <= maxAge
+}
+
+// Store is an in-memory cache of channel, bridge, playback and live recording
+// state, maintained from the events seen by a dispatcher
+type Store struct {
+	entries map[ref]*entry
+
+	// ended holds the entities which ended, with the time they ended
+	ended  map[ref]time.Time
+	pruned time.Time
+
+	watchers map[uint64]ChangeHandler
+	nextID   uint64
+
+	sub *dispatcher.Subscription
+
+	mu sync.RWMutex
+}
+
+// New returns an empty Store
+func New() *Store {
+	return &Store{
+		entries:  make(map[ref]*entry),
+		ended:    make(map[ref]time.Time),
+		watchers: make(map[uint64]ChangeHandler),
+	}
+}
+
+// Attach keeps the Store up to date with the events dispatched by the given
+// dispatcher.  The Store is registered as a tap, so it is updated before any
+// listener sees the event.
+func (s *Store) Attach(d *dispatcher.EventDispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sub.Cancel()
+	s.sub = d.Tap(s.Apply)
+}
+
+// Detach stops following the dispatcher the Store is attached to
+func (s *Store) Detach() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sub.Cancel()
+	s.sub = nil
+}
+
+// Watch registers a handler called for every change of the Store.  Handlers
+// are called synchronously while the event is dispatched, so they must not
+// block.  The returned function removes the handler.
+func (s *Store) Watch(h ChangeHandler) (cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := s.nextID
+	s.watchers[id] = h
+
+	return func() {
+		s.mu.Lock()
+		delete(s.watchers, id)
+		s.mu.Unlock()
+	}
+}
+
+// lookup returns the fresh entry of the given kind identified by the key.  A
+// key without a Node matches the entity on any node, as long as only one node
+// has it.  The caller must hold the lock.
+func (s *Store) lookup(kind string, k *key.Key, maxAge time.Duration) (*entry, bool) {
+	if k == nil {
+		return nil, false
+	}
+
+	var e *entry
+
+	if k.Node != "" {
+		e = s.entries[ref{kind: kind, node: k.Node, id: k.ID}]
+	} else {
+		for r, candidate := range s.entries {
+			if r.kind != kind || r.id != k.ID {
+				continue
+			}
+
+			if e != nil {
+				// Ambiguous without the node
+				return nil, false
+			}
+
+			e = candidate
+		}
+	}
+
+	if e == nil || !e.fresh(maxAge) {
+		return nil, false
+	}
+
+	return e, true
+}
+
+// Channel returns a copy of the cached data of the channel, if it is known and
+// no older than maxAge.  A maxAge of zero accepts any age.
+func (s *Store) Channel(k *key.Key, maxAge time.Duration) (*channel.ChannelData, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.lookup(key.ChannelKey, k, maxAge)
+	if !ok {
+		return nil, false
+	}
+
+	return copyChannel(e.channel), true
+}
+
+// Bridge returns a copy of the cached data of the bridge, if it is known and
+// no older than maxAge.  A maxAge of zero accepts any age.
+func (s *Store) Bridge(k *key.Key, maxAge time.Duration) (*bridge.BridgeData, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.lookup(key.BridgeKey, k, maxAge)
+	if !ok {
+		return nil, false
+	}
+
+	b := *e.bridge
+	b.ChannelIDs = append([]string(nil), e.bridge.ChannelIDs...)
+
+	return &b, true
+}
+
+// Playback returns a copy of the cached data of the playback, if it is known
+// and no older than maxAge.  A maxAge of zero accepts any age.
+func (s *Store) Playback(k *key.Key, maxAge time.Duration) (*play.PlaybackData, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.lookup(key.PlaybackKey, k, maxAge)
+	if !ok {
+		return nil, false
+	}
+
+	p := *e.playback
+
+	return &p, true
+}
+
+// LiveRecording returns a copy of the cached data of the live recording, if it
+// is known and no older than maxAge.  A maxAge of zero accepts any age.
+func (s *Store) LiveRecording(k *key.Key, maxAge time.Duration) (*recordings.LiveRecordingData, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.lookup(key.LiveRecordingKey, k, maxAge)
+	if !ok {
+		return nil, false
+	}
+
+	r := *e.recording
+
+	return &r, true
+}
+
+// Apply updates the Store from the given event
+// nolint: gocyclo
+func (s *Store) Apply(evt *arievent.StasisEvent) {
+	var changes []*Change
+
+	s.mu.Lock()
+
+	now := time.Now()
+	node := evt.GetNode()
+
+	s.prune(now)
+
+	switch evt.GetType() {
+	case arievent.StasisStart:
+		// A channel which left the application may enter it again
+		delete(s.ended, ref{kind: key.ChannelKey, node: node, id: evt.Channel.ID})
+	case arievent.StasisEnd, arievent.ChannelDestroyed:
+		changes = append(changes, s.evictChannel(node, evt.Channel.ID, evt, now)...)
+	case arievent.BridgeDestroyed:
+		if evt.Bridge != nil {
+			changes = append(changes, s.evictBridge(node, evt.Bridge.ID, evt, now)...)
+		}
+	case arievent.PlaybackFinished:
+		if evt.Playback != nil {
+			changes = append(changes, s.evict(ref{kind: key.PlaybackKey, node: node, id: evt.Playback.ID}, evt, now)...)
+		}
+	case arievent.RecordingFinished, arievent.RecordingFailed:
+		if evt.Recording != nil {
+			changes = append(changes, s.evict(ref{kind: key.LiveRecordingKey, node: node, id: evt.Recording.Name}, evt, now)...)
+		}
+	}
+
+	changes = append(changes, s.update(evt, node, now)...)
+
+	watchers := make([]ChangeHandler, 0, len(s.watchers))
+	for _, w := range s.watchers {
+		watchers = append(watchers, w)
+	}
+
+	s.mu.Unlock()
+
+	for _, c := range changes {
+		for _, w := range watchers {
+			w(c)
+		}
+	}
+}
+
+// update stores the entities carried by the event, except those which ended.
+// The caller must hold the lock.
+func (s *Store) update(evt *arievent.StasisEvent, node string, now time.Time) []*Change {
+	var changes []*Change
+
+	set := func(r ref, e *entry) {
+		if _, ok := s.ended[r]; ok {
+			return
+		}
+
+		e.updated = now
+		s.entries[r] = e
+		changes = append(changes, &Change{Kind: r.kind, Node: r.node, ID: r.id, Event: evt})
+	}
+
+	if evt.Channel.ID != "" && !isEnd(evt.GetType()) {
+		c := copyChannel(&evt.Channel)
+		r := ref{kind: key.ChannelKey, node: node, id: c.ID}
+
+		if old, ok := s.entries[r]; ok {
+			// Events only carry the variables configured for them, so keep
+			// what we already know
+			for k, v := range old.channel.ChannelVars {
+				if _, ok := c.ChannelVars[k]; !ok {
+					c.ChannelVars[k] = v
+				}
+			}
+		}
+
+		if evt.GetType() == arievent.ChannelVarset && evt.Variable != "" {
+			c.ChannelVars[evt.Variable] = evt.Value
+		}
+
+		set(r, &entry{channel: c})
+	}
+
+	if evt.Bridge != nil && evt.Bridge.ID != "" && evt.GetType() != arievent.BridgeDestroyed {
+		b := *evt.Bridge
+		b.ChannelIDs = append([]string(nil), evt.Bridge.ChannelIDs...)
+
+		set(ref{kind: key.BridgeKey, node: node, id: b.ID}, &entry{bridge: &b})
+	}
+
+	if evt.Playback != nil && evt.Playback.ID != "" && evt.GetType() != arievent.PlaybackFinished {
+		p := *evt.Playback
+
+		set(ref{kind: key.PlaybackKey, node: node, id: p.ID}, &entry{playback: &p})
+	}
+
+	if evt.Recording != nil && evt.Recording.Name != "" && !isEnd(evt.GetType()) {
+		r := *evt.Recording
+
+		set(ref{kind: key.LiveRecordingKey, node: node, id: r.Name}, &entry{recording: &r})
+	}
+
+	return changes
+}
+
+// isEnd reports whether the event ends the entity it carries
+func isEnd(t arievent.EventType) bool {
+	switch t {
+	case arievent.StasisEnd, arievent.ChannelDestroyed, arievent.BridgeDestroyed,
+		arievent.PlaybackFinished, arievent.RecordingFinished, arievent.RecordingFailed:
+		return true
+	}
+
+	return false
+}
+
+// evict removes the entity and remembers that it ended.  The caller must hold
+// the lock.
+func (s *Store) evict(r ref, evt *arievent.StasisEvent, now time.Time) []*Change {
+	if r.id == "" {
+		return nil
+	}
+
+	s.ended[r] = now
+
+	if _, ok := s.entries[r]; !ok {
+		return nil
+	}
+
+	delete(s.entries, r)
+
+	return []*Change{{Kind: r.kind, Node: r.node, ID: r.id, Removed: true, Event: evt}}
+}
+
+// evictChannel removes the channel, and the playbacks and recordings which
+// target it.  The caller must hold the lock.
+func (s *Store) evictChannel(node, id string, evt *arievent.StasisEvent, now time.Time) []*Change {
+	if id == "" {
+		return nil
+	}
+
+	changes := s.evict(ref{kind: key.ChannelKey, node: node, id: id}, evt, now)
+
+	return append(changes, s.evictTarget(node, "channel:"+id, evt)...)
+}
+
+// evictBridge removes the bridge, and the playbacks and recordings which
+// target it.  The caller must hold the lock.
+func (s *Store) evictBridge(node, id string, evt *arievent.StasisEvent, now time.Time) []*Change {
+	if id == "" {
+		return nil
+	}
+
+	changes := s.evict(ref{kind: key.BridgeKey, node: node, id: id}, evt, now)
+
+	return append(changes, s.evictTarget(node, "bridge:"+id, evt)...)
+}
+
+// evictTarget removes the playbacks and recordings of the node whose target is
+// the given URI.  The caller must hold the lock.
+func (s *Store) evictTarget(node, uri string, evt *arievent.StasisEvent) (changes []*Change) {
+	for r, e := range s.entries {
+		if r.node != node {
+			continue
+		}
+
+		var target string
+
+		switch r.kind {
+		case key.PlaybackKey:
+			target = e.playback.TargetURI
+		case key.LiveRecordingKey:
+			target = e.recording.TargetURI
+		default:
+			continue
+		}
+
+		if strings.EqualFold(target, uri) {
+			delete(s.entries, r)
+			changes = append(changes, &Change{Kind: r.kind, Node: r.node, ID: r.id, Removed: true, Event: evt})
+		}
+	}
+
+	return
+}
+
+// prune forgets the entities which ended more than EndedTTL ago.  The caller
+// must hold the lock.
+func (s *Store) prune(now time.Time) {
+	if now.Sub(s.pruned) < EndedTTL/10 {
+		return
+	}
+
+	s.pruned = now
+
+	for r, at := range s.ended {
+		if now.Sub(at) > EndedTTL {
+			delete(s.ended, r)
+		}
+	}
+}
+
+func copyChannel(c *channel.ChannelData) *channel.ChannelData {
+	n := *c
+
+	n.ChannelVars = make(map[string]string, len(c.ChannelVars))
+	for k, v := range c.ChannelVars {
+		n.ChannelVars[k] = v
+	}
+
+	return &n
+}
@@ -0,0 +1,243 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/key"
+)
+
+func chanKey(node, id string) *key.Key {
+	return key.NewKey(key.ChannelKey, id, key.WithNode(node))
+}
+
+func TestChannelLifecycle(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*arievent.StasisEvent
+		key    *key.Key
+		known  bool
+		state  arievent.ChannelState
+	}{
+		{
+			name: "created",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1", State: arievent.StateRing}},
+			},
+			key:   chanKey("ast1", "c1"),
+			known: true,
+			state: arievent.StateRing,
+		},
+		{
+			name: "updated",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1", State: arievent.StateRing}},
+				{Type: arievent.ChannelStateChange, Node: "ast1", Channel: arievent.ChannelData{ID: "c1", State: arievent.StateUp}},
+			},
+			key:   chanKey("ast1", "c1"),
+			known: true,
+			state: arievent.StateUp,
+		},
+		{
+			name: "other node",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+			},
+			key: chanKey("ast2", "c1"),
+		},
+		{
+			name: "key without node",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1", State: arievent.StateUp}},
+			},
+			key:   key.NewKey(key.ChannelKey, "c1"),
+			known: true,
+			state: arievent.StateUp,
+		},
+		{
+			name: "key without node, ambiguous",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+				{Type: arievent.StasisStart, Node: "ast2", Channel: arievent.ChannelData{ID: "c1"}},
+			},
+			key: key.NewKey(key.ChannelKey, "c1"),
+		},
+		{
+			name: "same ID on two nodes",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1", State: arievent.StateUp}},
+				{Type: arievent.StasisStart, Node: "ast2", Channel: arievent.ChannelData{ID: "c1", State: arievent.StateRing}},
+				{Type: arievent.StasisEnd, Node: "ast2", Channel: arievent.ChannelData{ID: "c1"}},
+			},
+			key:   chanKey("ast1", "c1"),
+			known: true,
+			state: arievent.StateUp,
+		},
+		{
+			name: "ended",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+				{Type: arievent.StasisEnd, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+			},
+			key: chanKey("ast1", "c1"),
+		},
+		{
+			name: "late event after the end",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+				{Type: arievent.ChannelDestroyed, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+				{Type: arievent.ChannelVarset, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}, Variable: "A", Value: "1"},
+			},
+			key: chanKey("ast1", "c1"),
+		},
+		{
+			name: "entering the application again",
+			events: []*arievent.StasisEvent{
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+				{Type: arievent.StasisEnd, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+				{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1", State: arievent.StateUp}},
+			},
+			key:   chanKey("ast1", "c1"),
+			known: true,
+			state: arievent.StateUp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for _, e := range tt.events {
+				s.Apply(e)
+			}
+
+			c, ok := s.Channel(tt.key, 0)
+			if ok != tt.known {
+				t.Fatalf("known = %v, want %v", ok, tt.known)
+			}
+			if ok && c.State != tt.state {
+				t.Errorf("state = %s, want %s", c.State, tt.state)
+			}
+		})
+	}
+}
+
+func TestChannelVars(t *testing.T) {
+	s := New()
+
+	s.Apply(&arievent.StasisEvent{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1", ChannelVars: map[string]string{"A": "1"}}})
+	s.Apply(&arievent.StasisEvent{Type: arievent.ChannelVarset, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}, Variable: "B", Value: "2"})
+
+	c, ok := s.Channel(chanKey("ast1", "c1"), 0)
+	if !ok {
+		t.Fatal("channel not cached")
+	}
+
+	if c.ChannelVars["A"] != "1" || c.ChannelVars["B"] != "2" {
+		t.Errorf("variables = %v", c.ChannelVars)
+	}
+
+	// The returned data is a copy
+	c.ChannelVars["A"] = "changed"
+	if c, _ := s.Channel(chanKey("ast1", "c1"), 0); c.ChannelVars["A"] != "1" {
+		t.Error("cached data altered through a copy")
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	s := New()
+	s.Apply(&arievent.StasisEvent{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}})
+
+	time.Sleep(5 * time.Millisecond)
+
+	tests := []struct {
+		maxAge time.Duration
+		want   bool
+	}{
+		{0, true},
+		{time.Hour, true},
+		{time.Millisecond, false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := s.Channel(chanKey("ast1", "c1"), tt.maxAge); ok != tt.want {
+			t.Errorf("maxAge %s: known = %v, want %v", tt.maxAge, ok, tt.want)
+		}
+	}
+}
+
+func TestPlaybackAndRecordingEviction(t *testing.T) {
+	playback := func(typ arievent.EventType) *arievent.StasisEvent {
+		return &arievent.StasisEvent{Type: typ, Node: "ast1", Playback: &arievent.PlaybackData{ID: "p1", TargetURI: "channel:c1"}}
+	}
+	recording := func(typ arievent.EventType) *arievent.StasisEvent {
+		return &arievent.StasisEvent{Type: typ, Node: "ast1", Recording: &arievent.LiveRecordingData{Name: "r1", TargetURI: "bridge:b1"}}
+	}
+
+	tests := []struct {
+		name      string
+		events    []*arievent.StasisEvent
+		playback  bool
+		recording bool
+	}{
+		{"started", []*arievent.StasisEvent{playback(arievent.PlaybackStarted), recording(arievent.RecordingStarted)}, true, true},
+		{"finished", []*arievent.StasisEvent{
+			playback(arievent.PlaybackStarted), recording(arievent.RecordingStarted),
+			playback(arievent.PlaybackFinished), recording(arievent.RecordingFinished),
+		}, false, false},
+		{"recording failed", []*arievent.StasisEvent{recording(arievent.RecordingStarted), recording(arievent.RecordingFailed)}, false, false},
+		{"target channel ended", []*arievent.StasisEvent{
+			playback(arievent.PlaybackStarted), recording(arievent.RecordingStarted),
+			{Type: arievent.StasisEnd, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}},
+		}, false, true},
+		{"target bridge destroyed", []*arievent.StasisEvent{
+			playback(arievent.PlaybackStarted), recording(arievent.RecordingStarted),
+			{Type: arievent.BridgeDestroyed, Node: "ast1", Bridge: &arievent.BridgeData{ID: "b1"}},
+		}, true, false},
+		{"late playback event", []*arievent.StasisEvent{
+			playback(arievent.PlaybackStarted), playback(arievent.PlaybackFinished), playback(arievent.PlaybackContinuing),
+		}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for _, e := range tt.events {
+				s.Apply(e)
+			}
+
+			if _, ok := s.Playback(key.NewKey(key.PlaybackKey, "p1", key.WithNode("ast1")), 0); ok != tt.playback {
+				t.Errorf("playback known = %v, want %v", ok, tt.playback)
+			}
+			if _, ok := s.LiveRecording(key.NewKey(key.LiveRecordingKey, "r1", key.WithNode("ast1")), 0); ok != tt.recording {
+				t.Errorf("recording known = %v, want %v", ok, tt.recording)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := New()
+
+	var changes []Change
+	cancel := s.Watch(func(c *Change) {
+		changes = append(changes, *c)
+	})
+
+	s.Apply(&arievent.StasisEvent{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}})
+	s.Apply(&arievent.StasisEvent{Type: arievent.StasisEnd, Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}})
+
+	cancel()
+	s.Apply(&arievent.StasisEvent{Type: arievent.StasisStart, Node: "ast1", Channel: arievent.ChannelData{ID: "c2"}})
+
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want 2", len(changes))
+	}
+
+	if changes[0].Removed || changes[0].ID != "c1" || changes[0].Node != "ast1" {
+		t.Errorf("first change = %+v", changes[0])
+	}
+	if !changes[1].Removed || changes[1].Kind != key.ChannelKey {
+		t.Errorf("second change = %+v", changes[1])
+	}
+}
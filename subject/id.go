@@ -0,0 +1,120 @@
+package subject
+
+import (
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+const hexDigits = "0123456789ABCDEF"
+
+// emptyID is the token of the empty ID.  A lone "%" is produced by no other
+// ID, since every escape is followed by two hexadecimal digits.
+const emptyID = "%"
+
+// mustEscape reports whether the byte cannot appear as is in an encoded ID:
+// the escape characters themselves, the NATS wildcards, whitespace, control
+// characters and the bytes beyond ASCII, which may encode Unicode whitespace
+func mustEscape(c byte) bool {
+	switch c {
+	case '%', '#', '*', '>':
+		return true
+	}
+
+	return c <= ' ' || c >= 0x7f
+}
+
+// EncodeID encodes a resource ID into a single, non-empty subject token.  "."
+// becomes "#", as in the original scheme, while "#", "%", "*", ">", whitespace,
+// control characters and non-ASCII bytes are percent-escaped; the empty ID is
+// encoded as "%".  The IDs Asterisk generates are therefore encoded the same
+// way as before, and DecodeID reverses the encoding of any ID.
+func EncodeID(id string) string {
+	if id == "" {
+		return emptyID
+	}
+
+	n := 0
+	for i := 0; i < len(id); i++ {
+		if mustEscape(id[i]) {
+			n++
+		}
+	}
+
+	if n == 0 {
+		return strings.ReplaceAll(id, ".", "#")
+	}
+
+	var b strings.Builder
+	b.Grow(len(id) + 2*n)
+
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c == '.':
+			b.WriteByte('#')
+		case mustEscape(c):
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// DecodeID decodes a subject token produced by EncodeID
+func DecodeID(token string) (string, error) {
+	if token == emptyID {
+		return "", nil
+	}
+
+	if token == "" {
+		return "", eris.New("empty subject token")
+	}
+
+	if !strings.ContainsRune(token, '%') {
+		return strings.ReplaceAll(token, "#", "."), nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(token))
+
+	for i := 0; i < len(token); i++ {
+		switch c := token[i]; c {
+		case '#':
+			b.WriteByte('.')
+		case '%':
+			if i+2 >= len(token) {
+				return "", eris.Errorf("truncated escape in subject token %q", token)
+			}
+
+			hi, ok1 := unhex(token[i+1])
+			lo, ok2 := unhex(token[i+2])
+			if !ok1 || !ok2 {
+				return "", eris.Errorf("invalid escape in subject token %q", token)
+			}
+
+			b.WriteByte(hi<<4 | lo)
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), nil
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,98 @@
+package subject
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode"
+)
+
+func TestEncodeID(t *testing.T) {
+	tests := []struct {
+		id    string
+		token string
+	}{
+		{"1712345678.42", "1712345678#42"},
+		{"plain-id", "plain-id"},
+		{"", "%"},
+		{"a#b", "a%23b"},
+		{"a%b", "a%25b"},
+		{"a*b.c", "a%2Ab#c"},
+		{"a>b", "a%3Eb"},
+		{"a b\tc", "a%20b%09c"},
+		{"é", "%C3%A9"},
+		{" ", "%C2%A0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := EncodeID(tt.id); got != tt.token {
+				t.Errorf("EncodeID = %q, want %q", got, tt.token)
+			}
+
+			got, err := DecodeID(tt.token)
+			if err != nil {
+				t.Fatalf("DecodeID: %s", err)
+			}
+			if got != tt.id {
+				t.Errorf("DecodeID = %q, want %q", got, tt.id)
+			}
+		})
+	}
+}
+
+func TestDecodeIDErrors(t *testing.T) {
+	for _, token := range []string{"", "a%", "a%4", "a%4G", "%%"} {
+		if _, err := DecodeID(token); err == nil {
+			t.Errorf("DecodeID(%q) succeeded", token)
+		}
+	}
+}
+
+// validToken reports whether the token fits in a single subject token
+func validToken(token string) bool {
+	return token != "" && !strings.ContainsAny(token, ".*>") && strings.IndexFunc(token, unicode.IsSpace) < 0
+}
+
+func checkRoundTrip(t *testing.T, id string) {
+	token := EncodeID(id)
+	if !validToken(token) {
+		t.Fatalf("EncodeID(%q) = %q, not a valid token", id, token)
+	}
+
+	got, err := DecodeID(token)
+	if err != nil {
+		t.Fatalf("DecodeID(%q): %s", token, err)
+	}
+	if got != id {
+		t.Fatalf("DecodeID(EncodeID(%q)) = %q", id, got)
+	}
+}
+
+func TestIDRoundTrip(t *testing.T) {
+	f := func(id string) bool {
+		checkRoundTrip(t, id)
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+
+	f2 := func(b []byte) bool {
+		checkRoundTrip(t, string(b))
+		return true
+	}
+
+	if err := quick.Check(f2, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzIDRoundTrip(f *testing.F) {
+	for _, id := range []string{"", "1712345678.42", "a#b%c*d>e", " \t\n", "\x00\xff", "é "} {
+		f.Add(id)
+	}
+
+	f.Fuzz(checkRoundTrip)
+}
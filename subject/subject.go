@@ -0,0 +1,139 @@
+// Package subject builds the NATS subjects used between the ARI clients and
+// the ARI proxies
+package subject
+
+import (
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// Request classes
+const (
+	Get     = "get"
+	Data    = "data"
+	Command = "command"
+	Create  = "create"
+)
+
+// Scheme owns the layout of every subject exchanged with the proxies:
+//
+//	<prefix>.announce.<node>                  proxy announcements
+//	<prefix>.<app>.<class>[.<node>]           get, data, command and create requests
+//	<prefix>.<app>.<node>.<id>.>              events of a resource
+//	<prefix>.<app>.<node>.*.stasisstart.>     channels entering an application
+//	<prefix>.requeue.<app>.<group>            channels refused by a queue group member
+//
+// Resource IDs are encoded with EncodeID, so that any ID fits in one token.
+type Scheme struct {
+	// Prefix is the first token of every subject, the connection name
+	Prefix string
+
+	// LegacyIDs encodes resource IDs by replacing "." with "#" only, for
+	// proxies which do not decode IDs.  Empty IDs and the IDs containing
+	// "#", "%", "*", ">" or whitespace then produce invalid or ambiguous
+	// subjects.
+	LegacyIDs bool
+}
+
+// New returns the subject scheme with the given prefix
+func New(prefix string) *Scheme {
+	return &Scheme{
+		Prefix: prefix,
+	}
+}
+
+// Announce returns the subject of the announcements of the given node, or of
+// every node when node is empty
+func (s *Scheme) Announce(node string) string {
+	if node == "" {
+		node = "*"
+	}
+
+	return s.Prefix + ".announce." + node
+}
+
+// Request returns the subject of the requests of the given class.  The node
+// is optional: without it, any proxy serving the application may answer.
+func (s *Scheme) Request(app, class, node string) string {
+	ret := s.Prefix + "."
+	if app != "" {
+		ret += app + "." + class
+		if node != "" {
+			ret += "." + node
+		}
+	}
+
+	return ret
+}
+
+// Resource returns the subject prefix of the events of the resource.  An empty
+// node stands for every node.
+func (s *Scheme) Resource(app, node, id string) string {
+	if node == "" {
+		node = "*"
+	}
+
+	return s.Prefix + "." + app + "." + node + "." + s.ID(id)
+}
+
+// ResourceEvents returns the subject matching every event of the resource
+func (s *Scheme) ResourceEvents(app, node, id string) string {
+	return s.Resource(app, node, id) + ".>"
+}
+
+// Event returns the subject of an event of the resource, followed by the given
+// tokens, the first of which is usually the lower-case event type
+func (s *Scheme) Event(app, node, id string, tokens ...string) string {
+	ret := s.Resource(app, node, id)
+	for _, t := range tokens {
+		ret += "." + t
+	}
+
+	return ret
+}
+
+// StasisStart returns the subject matching the channels entering the
+// application on the given node, or on any node when node is empty
+func (s *Scheme) StasisStart(app, node string) string {
+	if node == "" {
+		node = "*"
+	}
+
+	return s.Prefix + "." + app + "." + node + ".*.stasisstart.>"
+}
+
+// Requeue returns the subject on which the channels entering the application
+// and refused by a member of the queue group are put back, for the other
+// members of the group only
+func (s *Scheme) Requeue(app, group string) string {
+	return s.Prefix + ".requeue." + app + "." + EncodeID(group)
+}
+
+// ID encodes a resource ID into a subject token
+func (s *Scheme) ID(id string) string {
+	if s.LegacyIDs {
+		return strings.ReplaceAll(id, ".", "#")
+	}
+
+	return EncodeID(id)
+}
+
+// ParseResource splits the subject of a resource event into the application,
+// node, decoded resource ID and the remaining tokens
+func (s *Scheme) ParseResource(subj string) (app, node, id string, rest []string, err error) {
+	// The prefix may span several tokens
+	tail, ok := strings.CutPrefix(subj, s.Prefix+".")
+	tokens := strings.Split(tail, ".")
+	if !ok || len(tokens) < 3 {
+		return "", "", "", nil, eris.Errorf("subject %q is not a resource subject", subj)
+	}
+
+	if s.LegacyIDs {
+		id = strings.ReplaceAll(tokens[2], "#", ".")
+	} else if id, err = DecodeID(tokens[2]); err != nil {
+		return "", "", "", nil, err
+	}
+
+	return tokens[0], tokens[1], id, tokens[3:], nil
+}
@@ -0,0 +1,78 @@
+package subject
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubjects(t *testing.T) {
+	s := New("ari")
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"announce", s.Announce("ast1"), "ari.announce.ast1"},
+		{"announce every node", s.Announce(""), "ari.announce.*"},
+		{"request", s.Request("app", Get, "ast1"), "ari.app.get.ast1"},
+		{"request any node", s.Request("app", Command, ""), "ari.app.command"},
+		{"resource", s.Resource("app", "ast1", "1.2"), "ari.app.ast1.1#2"},
+		{"resource every node", s.Resource("app", "", "c1"), "ari.app.*.c1"},
+		{"resource empty id", s.Resource("app", "ast1", ""), "ari.app.ast1.%"},
+		{"resource events", s.ResourceEvents("app", "ast1", "c1"), "ari.app.ast1.c1.>"},
+		{"event", s.Event("app", "ast1", "c1", "stasisstart", "x"), "ari.app.ast1.c1.stasisstart.x"},
+		{"stasis start", s.StasisStart("app", ""), "ari.app.*.*.stasisstart.>"},
+		{"requeue", s.Requeue("app", "group.1"), "ari.requeue.app.group#1"},
+		{"legacy id", (&Scheme{Prefix: "ari", LegacyIDs: true}).ID("a.b*"), "a#b*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResource(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  *Scheme
+		subject string
+		app     string
+		node    string
+		id      string
+		rest    []string
+		wantErr bool
+	}{
+		{"event", New("ari"), "ari.app.ast1.1#2.stasisstart", "app", "ast1", "1.2", []string{"stasisstart"}, false},
+		{"no rest", New("ari"), "ari.app.ast1.c1", "app", "ast1", "c1", []string{}, false},
+		{"escaped id", New("ari"), "ari.app.ast1.a%2Ab", "app", "ast1", "a*b", []string{}, false},
+		{"empty id", New("ari"), "ari.app.ast1.%.x", "app", "ast1", "", []string{"x"}, false},
+		{"dotted prefix", New("ari.prod"), "ari.prod.app.ast1.c1.x", "app", "ast1", "c1", []string{"x"}, false},
+		{"dotted prefix, other prefix", New("ari.prod"), "ari.app.ast1.c1.x", "", "", "", nil, true},
+		{"prefix of a token", New("ari"), "arix.app.ast1.c1", "", "", "", nil, true},
+		{"other prefix", New("ari"), "other.app.ast1.c1", "", "", "", nil, true},
+		{"too short", New("ari"), "ari.app.ast1", "", "", "", nil, true},
+		{"invalid id", New("ari"), "ari.app.ast1.a%G", "", "", "", nil, true},
+		{"legacy", &Scheme{Prefix: "ari", LegacyIDs: true}, "ari.app.ast1.1#2.x", "app", "ast1", "1.2", []string{"x"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, node, id, rest, err := tt.scheme.ParseResource(tt.subject)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if app != tt.app || node != tt.node || id != tt.id || !reflect.DeepEqual(rest, tt.rest) {
+				t.Errorf("got %q %q %q %q, want %q %q %q %q", app, node, id, rest, tt.app, tt.node, tt.id, tt.rest)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package ari
+
+import (
+	"sync"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/logs"
+	nats "github.com/nats-io/nats.go"
+	"github.com/rotisserie/eris"
+)
+
+// topicSub is the subscription to the events of a resource topic.  It is
+// shared by every user of the topic, such as a call, an originator and an
+// event source subscription, and is drained once the last of them lets go.
+type topicSub struct {
+	sub *nats.Subscription
+
+	// hooks are the callbacks of the users, by lease
+	hooks map[uint64]func(*arievent.StasisEvent)
+}
+
+// topicLease is the share of one user in the subscription to a topic
+type topicLease struct {
+	a     *ARIClient
+	topic string
+	id    uint64
+
+	once sync.Once
+}
+
+// acquireTopic subscribes to the events of the topic, or joins the existing
+// subscription.  Each event is dispatched once, then handed to the hook of
+// every user of the topic, if any.  The subscription stays until every lease
+// is released.
+func (a *ARIClient) acquireTopic(topic string, hook func(*arievent.StasisEvent)) (*topicLease, error) {
+	a.topicsMu.Lock()
+	defer a.topicsMu.Unlock()
+
+	if a.topics == nil {
+		a.topics = make(map[string]*topicSub)
+	}
+
+	ts, ok := a.topics[topic]
+	if !ok {
+		ts = &topicSub{
+			hooks: make(map[uint64]func(*arievent.StasisEvent)),
+		}
+
+		logs.TLogger.Debug().Msgf("subscribing client to %s", topic)
+		sub, err := a.sbus.DynSubscription(topic, func(o *arievent.StasisEvent) {
+			a.topicsMu.Lock()
+			if a.topics[topic] != ts {
+				// Delivered while the subscription drains
+				a.topicsMu.Unlock()
+				return
+			}
+
+			hooks := make([]func(*arievent.StasisEvent), 0, len(ts.hooks))
+			for _, h := range ts.hooks {
+				hooks = append(hooks, h)
+			}
+			a.topicsMu.Unlock()
+
+			a._dispatcher.Dispatch(o)
+
+			for _, h := range hooks {
+				h(o)
+			}
+		})
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to subscribe to %s", topic)
+		}
+
+		ts.sub = sub
+		a.topics[topic] = ts
+	}
+
+	a.nextLease++
+
+	l := &topicLease{
+		a:     a,
+		topic: topic,
+		id:    a.nextLease,
+	}
+
+	if hook == nil {
+		hook = func(*arievent.StasisEvent) {}
+	}
+
+	ts.hooks[l.id] = hook
+
+	return l, nil
+}
+
+// Release gives up the share of the user in the subscription, and drains the
+// subscription if it was the last user.  It is safe to call Release more than
+// once, and on a nil lease.
+func (l *topicLease) Release() {
+	if l == nil {
+		return
+	}
+
+	l.once.Do(func() {
+		a := l.a
+
+		a.topicsMu.Lock()
+		defer a.topicsMu.Unlock()
+
+		ts, ok := a.topics[l.topic]
+		if !ok {
+			return
+		}
+
+		delete(ts.hooks, l.id)
+
+		if len(ts.hooks) > 0 {
+			return
+		}
+
+		logs.TLogger.Debug().Msgf("dropping subscription to %s", l.topic)
+
+		if err := ts.sub.Drain(); err != nil {
+			logs.TLogger.Debug().Msgf("failed to drain subscription to %s: %s", l.topic, err)
+		}
+
+		delete(a.topics, l.topic)
+	})
+}
+
+// topicUsers returns the number of users of the subscription to the topic
+func (a *ARIClient) topicUsers(topic string) int {
+	a.topicsMu.Lock()
+	defer a.topicsMu.Unlock()
+
+	if ts, ok := a.topics[topic]; ok {
+		return len(ts.hooks)
+	}
+
+	return 0
+}
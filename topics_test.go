@@ -0,0 +1,117 @@
+package ari
+
+import (
+	"context"
+	"testing"
+
+	"github.com/callevo/ari/arievent"
+	"github.com/callevo/ari/key"
+)
+
+func TestTopicLeases(t *testing.T) {
+	bus := newFakeBus()
+	a := newTestClient(bus)
+
+	topic := a.resourceTopic("app", "ast1", "c1")
+
+	var first, second int
+	l1, err := a.acquireTopic(topic, func(*arievent.StasisEvent) { first++ })
+	if err != nil {
+		t.Fatalf("acquireTopic: %s", err)
+	}
+	l2, err := a.acquireTopic(topic, func(*arievent.StasisEvent) { second++ })
+	if err != nil {
+		t.Fatalf("acquireTopic: %s", err)
+	}
+
+	if n := bus.subscriptions(topic); n != 1 {
+		t.Fatalf("%d bus subscriptions, want 1", n)
+	}
+
+	dispatched := 0
+	a._dispatcher.AddListener(arievent.ChannelVarset, func(*arievent.StasisEvent) { dispatched++ })
+
+	bus.publish(topic, &arievent.StasisEvent{Type: arievent.ChannelVarset})
+
+	if dispatched != 1 || first != 1 || second != 1 {
+		t.Errorf("dispatched %d, hooks %d and %d, want 1 each", dispatched, first, second)
+	}
+
+	l1.Release()
+	l1.Release()
+
+	if n := a.topicUsers(topic); n != 1 {
+		t.Errorf("%d users after the first release, want 1", n)
+	}
+
+	l2.Release()
+
+	if n := a.topicUsers(topic); n != 0 {
+		t.Errorf("%d users after the last release, want 0", n)
+	}
+}
+
+func TestEventSourceKeepsCallSubscription(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		unsubscribe bool
+		wantUsers   int
+	}{
+		{"subscribed", "channel:c1", false, 2},
+		{"unsubscribed", "channel:c1", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newFakeBus()
+			a := newTestClient(bus)
+
+			start := &arievent.StasisEvent{Type: arievent.StasisStart, Application: "app", Node: "ast1", Channel: arievent.ChannelData{ID: "c1"}}
+			a.startCall(context.Background(), start, nil, false, nil)
+
+			k := key.NewKey(key.ChannelKey, "c1", key.WithApp("app"), key.WithNode("ast1"))
+
+			app := a.Applications()
+			if err := app.Subscribe(k, tt.source); err != nil {
+				t.Fatalf("Subscribe: %s", err)
+			}
+
+			// Subscribing twice shares the subscription
+			if err := app.Subscribe(k, tt.source); err != nil {
+				t.Fatalf("Subscribe: %s", err)
+			}
+
+			if tt.unsubscribe {
+				if err := app.Unsubscribe(k, tt.source); err != nil {
+					t.Fatalf("Unsubscribe: %s", err)
+				}
+			}
+
+			topic := a.resourceTopic("app", "ast1", "c1")
+			if n := a.topicUsers(topic); n != tt.wantUsers {
+				t.Errorf("%d users, want %d", n, tt.wantUsers)
+			}
+			if n := bus.subscriptions(topic); n != 1 {
+				t.Errorf("%d bus subscriptions, want 1", n)
+			}
+		})
+	}
+}
+
+func TestBridgeSourceReleasedOnDestroy(t *testing.T) {
+	bus := newFakeBus()
+	a := newTestClient(bus)
+
+	k := key.NewKey(key.BridgeKey, "b1", key.WithApp("app"), key.WithNode("ast1"))
+	if err := a.Applications().Subscribe(k, "bridge:b1"); err != nil {
+		t.Fatalf("Subscribe: %s", err)
+	}
+
+	topic := a.resourceTopic("app", "ast1", "b1")
+	bus.publish(topic, &arievent.StasisEvent{Type: arievent.BridgeDestroyed, Bridge: &arievent.BridgeData{ID: "b1"}})
+
+	if n := a.topicUsers(topic); n != 0 {
+		t.Errorf("%d users after BridgeDestroyed, want 0", n)
+	}
+}
@@ -262,8 +262,3 @@ type AsteriskLoggingChannel struct {
 	// Levels is the set of logging levels for this logging channel (comma-separated string)
 	Levels string `json:"config"`
 }
-
-// ChannelUserevent - "User-generated event with additional user-defined fields in the object."
-//type ChannelUserevent struct {
-//	UserEvent ChannelUserevent
-//}